@@ -12,6 +12,8 @@ import (
 func main() {
 	var (
 		action = flag.String("action", "up", "Migration action: up, down, status")
+		steps  = flag.Int("steps", 1, "Number of migrations to roll back with -action=down")
+		to     = flag.String("to", "", "Roll back every migration applied after this version with -action=down, 0 for all")
 		help   = flag.Bool("help", false, "Show help")
 	)
 	flag.Parse()
@@ -39,8 +41,14 @@ func main() {
 		log.Println("Migrations completed successfully")
 
 	case "down":
-		log.Println("Rolling back last migration...")
-		if err := migrationManager.Rollback(); err != nil {
+		if *to != "" {
+			log.Printf("Rolling back migrations applied after %s...", *to)
+			err = migrationManager.RollbackTo(*to)
+		} else {
+			log.Printf("Rolling back the last %d migration(s)...", *steps)
+			err = migrationManager.Rollback(*steps)
+		}
+		if err != nil {
 			log.Fatalf("Failed to rollback migration: %v", err)
 		}
 		log.Println("Rollback completed successfully")
@@ -61,10 +69,16 @@ func printHelp() {
 	fmt.Println("Migration Tool")
 	fmt.Println()
 	fmt.Println("Usage:")
-	fmt.Println("  migrate -action=up     # Run all pending migrations")
-	fmt.Println("  migrate -action=down   # Rollback the last migration")
-	fmt.Println("  migrate -action=status # Show migration status")
-	fmt.Println("  migrate -help          # Show this help")
+	fmt.Println("  migrate -action=up                   # Run all pending migrations")
+	fmt.Println("  migrate -action=down                 # Rollback the last migration")
+	fmt.Println("  migrate -action=down -steps=3        # Rollback the last 3 migrations")
+	fmt.Println("  migrate -action=down -to=2610192700  # Rollback every migration after 2610192700")
+	fmt.Println("  migrate -action=status               # Show migration status")
+	fmt.Println("  migrate -help                        # Show this help")
+	fmt.Println()
+	fmt.Println("Migrations can only be rolled back if they have a down script, either in")
+	fmt.Println("a VERSION_name.down.sql file next to VERSION_name.up.sql, or after a")
+	fmt.Println("\"-- +migrate Down\" line in VERSION_name.sql.")
 	fmt.Println()
 	fmt.Println("Environment Variables:")
 	fmt.Println("  DB_HOST     - Database host (default: localhost)")
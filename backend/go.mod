@@ -14,6 +14,7 @@ require (
 	github.com/go-playground/validator/v10 v10.2.0
 	github.com/google/uuid v1.3.1
 	github.com/stretchr/testify v1.7.0
+	golang.org/x/crypto v0.14.0
 )
 
 require (
@@ -27,8 +28,8 @@ require (
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rs/cors v1.7.0 // indirect
 	golang.org/x/net v0.14.0 // indirect
-	golang.org/x/sys v0.11.0 // indirect
-	golang.org/x/text v0.12.0 // indirect
+	golang.org/x/sys v0.13.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20230822172742-b8732ec3820d // indirect
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
 )
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// apiKeyMarker starts every API key, so leaked keys are easy to spot
+const apiKeyMarker = "bgs"
+
+// apiKeyPrefixBytes is the randomness of the public lookup prefix
+const apiKeyPrefixBytes = 6
+
+// NewAPIKey returns a random API key of the form bgs_<prefix>_<secret>,
+// the prefix to look it up by and the hash to store for it
+func NewAPIKey() (key string, prefix string, hash string, err error) {
+	buf := make([]byte, apiKeyPrefixBytes+tokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	prefix = hex.EncodeToString(buf[:apiKeyPrefixBytes])
+	key = apiKeyMarker + "_" + prefix + "_" + base64.RawURLEncoding.EncodeToString(buf[apiKeyPrefixBytes:])
+	return key, prefix, HashToken(key), nil
+}
+
+// APIKeyPrefix returns the lookup prefix of a key, or false if the key is
+// malformed. The secret part may itself contain underscores.
+func APIKeyPrefix(key string) (string, bool) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyMarker || len(parts[1]) != 2*apiKeyPrefixBytes || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
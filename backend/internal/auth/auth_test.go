@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewToken(t *testing.T) {
+	token, hash, err := NewToken()
+	require.NoError(t, err)
+
+	assert.Len(t, token, 43)
+	assert.Equal(t, hash, HashToken(token))
+	assert.NotEqual(t, token, hash)
+
+	other, _, err := NewToken()
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other)
+}
+
+func TestPassword(t *testing.T) {
+	assert.Error(t, ValidatePassword("short"))
+	assert.Error(t, ValidatePassword(strings.Repeat("a", MaxPasswordLength+1)))
+	require.NoError(t, ValidatePassword("correct horse"))
+
+	hash, err := HashPassword("correct horse")
+	require.NoError(t, err)
+	assert.True(t, CheckPassword(hash, "correct horse"))
+	assert.False(t, CheckPassword(hash, "wrong horse"))
+	assert.False(t, CheckPassword("not a hash", "correct horse"))
+	assert.False(t, CheckPassword("", "dummy password"))
+}
+
+func TestSigner(t *testing.T) {
+	signer := NewSigner([]byte("secret"), 15*time.Minute)
+	now := time.Unix(1700000000, 0)
+
+	token, expiresAt, err := signer.Issue(Claims{UserID: 42, Role: "admin"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(15*time.Minute), expiresAt)
+	assert.Len(t, strings.Split(token, "."), 3)
+
+	claims, err := signer.Verify(token, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int32(42), claims.UserID)
+	assert.Equal(t, "admin", claims.Role)
+
+	_, err = signer.Verify(token, expiresAt)
+	assert.ErrorIs(t, err, ErrInvalidAccessToken)
+
+	_, err = NewSigner([]byte("other"), time.Hour).Verify(token, now)
+	assert.ErrorIs(t, err, ErrInvalidAccessToken)
+
+	parts := strings.Split(token, ".")
+	tampered := parts[0] + "." + parts[1] + "x." + parts[2]
+	_, err = signer.Verify(tampered, now)
+	assert.ErrorIs(t, err, ErrInvalidAccessToken)
+
+	_, err = signer.Verify("not a token", now)
+	assert.ErrorIs(t, err, ErrInvalidAccessToken)
+
+	// Derived signers don't accept each other's tokens
+	derived := signer.Derive("two-factor", 5*time.Minute)
+	_, err = derived.Verify(token, now)
+	assert.ErrorIs(t, err, ErrInvalidAccessToken)
+	challenge, _, err := derived.Issue(Claims{UserID: 42}, now)
+	require.NoError(t, err)
+	_, err = signer.Verify(challenge, now)
+	assert.ErrorIs(t, err, ErrInvalidAccessToken)
+
+	// Impersonation tokens name the admin and expire sooner
+	impersonation, expiresAt, err := signer.WithTTL(time.Minute).Issue(Claims{UserID: 7, Role: "user", Actor: &Actor{UserID: 42, ImpersonationID: 3}}, now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Minute), expiresAt)
+	claims, err = signer.Verify(impersonation, now)
+	require.NoError(t, err)
+	assert.Equal(t, &Actor{UserID: 42, ImpersonationID: 3}, claims.Actor)
+	claims, err = signer.Verify(token, now)
+	require.NoError(t, err)
+	assert.Nil(t, claims.Actor)
+	assert.Zero(t, claims.SessionID)
+
+	// Login tokens name their session
+	session, _, err := signer.Issue(Claims{UserID: 42, Role: "admin", SessionID: 9}, now)
+	require.NoError(t, err)
+	claims, err = signer.Verify(session, now)
+	require.NoError(t, err)
+	assert.Equal(t, int32(9), claims.SessionID)
+}
+
+func TestTOTP(t *testing.T) {
+	// RFC 6238 appendix B test vector, truncated to six digits
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	code, err := TOTPCode(secret, TOTPStep(time.Unix(59, 0)))
+	require.NoError(t, err)
+	assert.Equal(t, "287082", code)
+
+	now := time.Unix(1700000000, 0)
+	code, err = TOTPCode(secret, TOTPStep(now))
+	require.NoError(t, err)
+
+	step, ok := ValidateTOTP(secret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, TOTPStep(now), step)
+
+	// One period of clock drift is tolerated, more isn't
+	_, ok = ValidateTOTP(secret, code, now.Add(30*time.Second))
+	assert.True(t, ok)
+	_, ok = ValidateTOTP(secret, code, now.Add(90*time.Second))
+	assert.False(t, ok)
+
+	_, ok = ValidateTOTP(secret, "12345", now)
+	assert.False(t, ok)
+
+	generated, err := NewTOTPSecret()
+	require.NoError(t, err)
+	assert.Len(t, generated, 32)
+
+	uri := TOTPURI("Acme", "jane@example.com", generated)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Acme:jane@example.com?"))
+	assert.Contains(t, uri, "secret="+generated)
+	assert.Contains(t, uri, "issuer=Acme")
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := NewRecoveryCodes(RecoveryCodeCount)
+	require.NoError(t, err)
+	require.Len(t, codes, RecoveryCodeCount)
+	require.Len(t, hashes, RecoveryCodeCount)
+
+	assert.Len(t, codes[0], 19)
+	assert.Equal(t, hashes[0], HashRecoveryCode(codes[0]))
+	assert.Equal(t, hashes[0], HashRecoveryCode(strings.ToUpper(strings.ReplaceAll(codes[0], "-", " "))))
+	assert.NotEqual(t, codes[0], codes[1])
+}
+
+func TestAPIKey(t *testing.T) {
+	key, prefix, hash, err := NewAPIKey()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, "bgs_"+prefix+"_"))
+	assert.Equal(t, hash, HashToken(key))
+
+	parsed, ok := APIKeyPrefix(key)
+	assert.True(t, ok)
+	assert.Equal(t, prefix, parsed)
+
+	other, otherPrefix, _, err := NewAPIKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+	assert.NotEqual(t, prefix, otherPrefix)
+
+	for _, malformed := range []string{"", "bgs", "bgs_" + prefix, "bgs_" + prefix + "_", "xyz_" + prefix + "_secret", "bgs_short_secret"} {
+		_, ok := APIKeyPrefix(malformed)
+		assert.False(t, ok, malformed)
+	}
+}
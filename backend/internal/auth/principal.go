@@ -0,0 +1,50 @@
+package auth
+
+import "context"
+
+// Principal is who a request is made by: a signed in user or an API key
+type Principal struct {
+	UserID    int32  // zero for API keys
+	Role      string // empty for API keys
+	SessionID int32  // zero for API keys and impersonation tokens
+
+	APIKeyID int32    // zero for users
+	Scopes   []string // what an API key may do
+
+	// Set when an admin impersonates the user
+	ActorID         int32
+	ImpersonationID int32
+}
+
+// IsAPIKey reports whether the request was made with an API key
+func (p *Principal) IsAPIKey() bool {
+	return p.APIKeyID != 0
+}
+
+// Impersonating reports whether an admin acts as the user
+func (p *Principal) Impersonating() bool {
+	return p.ImpersonationID != 0
+}
+
+// HasScope reports whether an API key principal was granted the scope
+func (p *Principal) HasScope(scope string) bool {
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the principal
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal of an authenticated request
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
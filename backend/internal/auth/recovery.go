@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"strings"
+)
+
+// RecoveryCodeCount is the number of recovery codes handed out at a time
+const RecoveryCodeCount = 10
+
+// recoveryCodeBytes gives 80 bits of randomness, 16 base32 characters
+const recoveryCodeBytes = 10
+
+// NewRecoveryCodes returns one-time codes for signing in without the
+// authenticator, formatted for reading, together with the hashes to store
+func NewRecoveryCodes(n int) (codes []string, hashes []string, err error) {
+	for i := 0; i < n; i++ {
+		buf := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+
+		raw := strings.ToLower(base32.StdEncoding.EncodeToString(buf))
+		codes = append(codes, raw[0:4]+"-"+raw[4:8]+"-"+raw[8:12]+"-"+raw[12:16])
+		hashes = append(hashes, HashRecoveryCode(raw))
+	}
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode hashes a recovery code as entered. Case, spaces and
+// dashes are ignored.
+func HashRecoveryCode(code string) string {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+	return HashToken(normalized)
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// AuditContext describes the request behind a change. The audit triggers read
+// it from the audit.context setting of the transaction making the change.
+type AuditContext struct {
+	ActorUserID    int32  `json:"actor_user_id,omitempty"`
+	ActorAPIKeyID  int32  `json:"actor_api_key_id,omitempty"`
+	ImpersonatedBy int32  `json:"impersonated_by,omitempty"`
+	TenantID       string `json:"tenant_id,omitempty"`
+	RequestID      string `json:"request_id,omitempty"`
+	ClientIP       string `json:"client_ip,omitempty"`
+	Source         string `json:"source,omitempty"` // RPC method or socket event
+}
+
+type auditContextKey struct{}
+
+// WithAuditContext returns a context carrying the audit context
+func WithAuditContext(ctx context.Context, audit *AuditContext) context.Context {
+	return context.WithValue(ctx, auditContextKey{}, audit)
+}
+
+// AuditContextFromContext returns the audit context of a request
+func AuditContextFromContext(ctx context.Context) (*AuditContext, bool) {
+	audit, ok := ctx.Value(auditContextKey{}).(*AuditContext)
+	return audit, ok && audit != nil
+}
+
+// WithContext returns a handle whose transactions and Exec calls carry the
+// audit context of ctx, or db itself if ctx has none. Single-row writes with
+// QueryRow have to run in a transaction to be attributed.
+func (db *DB) WithContext(ctx context.Context) *DB {
+	audit, ok := AuditContextFromContext(ctx)
+	if !ok {
+		return db
+	}
+	return &DB{DB: db.DB, audit: audit}
+}
+
+// Begin starts a transaction, with the audit context set if the handle has one
+func (db *DB) Begin() (*sql.Tx, error) {
+	tx, err := db.DB.Begin()
+	if err != nil || db.audit == nil {
+		return tx, err
+	}
+
+	if err := setAuditContext(tx, db.audit); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return tx, nil
+}
+
+// Exec runs a statement, in a transaction with the audit context if the
+// handle has one
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	if db.audit == nil {
+		return db.DB.Exec(query, args...)
+	}
+
+	var result sql.Result
+	err := db.Transaction(func(tx *sql.Tx) error {
+		var err error
+		result, err = tx.Exec(query, args...)
+		return err
+	})
+	return result, err
+}
+
+// Transaction runs fn in a transaction and commits it if fn succeeds
+func (db *DB) Transaction(fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func setAuditContext(tx *sql.Tx, audit *AuditContext) error {
+	data, err := json.Marshal(audit)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit context: %w", err)
+	}
+
+	// Local to the transaction, pooled connections don't keep it
+	if _, err := tx.Exec(`SELECT set_config('audit.context', $1, true)`, string(data)); err != nil {
+		return fmt.Errorf("failed to set audit context: %w", err)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
 	"time"
@@ -13,6 +14,9 @@ import (
 
 type DB struct {
 	*sql.DB
+
+	// audit is set on handles returned by WithContext
+	audit *AuditContext
 }
 
 func NewConnection() (*DB, error) {
@@ -47,7 +51,7 @@ func NewConnection() (*DB, error) {
 	log.Printf("Successfully connected to PostgreSQL database: %s", dbname)
 
 	// Create DB wrapper
-	dbWrapper := &DB{db}
+	dbWrapper := &DB{DB: db}
 
 	// Run migrations automatically if enabled
 	if getEnv("AUTO_MIGRATE", "true") == "true" {
@@ -92,7 +96,7 @@ func NewConnectionWithoutMigrations() (*DB, error) {
 
 	log.Printf("Successfully connected to PostgreSQL database: %s", dbname)
 
-	return &DB{db}, nil
+	return &DB{DB: db}, nil
 }
 
 func getEnv(key, defaultValue string) string {
@@ -117,27 +121,22 @@ func (db *DB) RunMigrations() error {
 	return migrationManager.RunMigrations()
 }
 
-// Add the missing method for tests
+// RunMigrationsFromPath runs the migrations of a directory instead of the
+// ones built into the binary
 func (db *DB) RunMigrationsFromPath(migrationsPath string) error {
-	// Create a temporary migration manager with custom path
-	migrationManager := &MigrationManager{db: db}
-
-	// Load migrations from the specified path
-	migrations, err := migrationManager.loadMigrationsFromPath(migrationsPath)
-	if err != nil {
-		return fmt.Errorf("failed to load migrations from %s: %w", migrationsPath, err)
-	}
-
-	// Create migrations table if it doesn't exist
-	if err := migrationManager.createMigrationsTable(); err != nil {
-		return fmt.Errorf("failed to create migrations table: %w", err)
+	if err := db.RunMigrationsFromFS(os.DirFS(migrationsPath)); err != nil {
+		return fmt.Errorf("failed to run migrations from %s: %w", migrationsPath, err)
 	}
+	return nil
+}
 
-	// Run migrations
-	return migrationManager.runMigrationsFromList(migrations)
+// RunMigrationsFromFS runs the migrations at the root of source, which lets
+// tests and tenants supply their own
+func (db *DB) RunMigrationsFromFS(source fs.FS) error {
+	return NewMigrationManagerWithSource(db, source).RunMigrations()
 }
 
 func (db *DB) RollbackLastMigration() error {
 	migrationManager := NewMigrationManager(db)
-	return migrationManager.Rollback()
+	return migrationManager.Rollback(1)
 }
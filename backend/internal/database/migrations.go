@@ -3,14 +3,29 @@ package database
 
 import (
 	"database/sql"
+	"embed"
 	"fmt"
-	"io/ioutil"
+	"io/fs"
 	"log"
-	"path/filepath"
 	"sort"
 	"strings"
 )
 
+// embeddedMigrations are the migrations of this backend, built into the binary
+//
+//go:embed migrations/*.sql
+var embeddedMigrations embed.FS
+
+// Migrations returns the migrations built into the binary
+func Migrations() fs.FS {
+	migrations, err := fs.Sub(embeddedMigrations, "migrations")
+	if err != nil {
+		// Only fails for invalid paths, which the embed directive rules out
+		panic(err)
+	}
+	return migrations
+}
+
 // Migration represents a database migration
 type Migration struct {
 	Version     string
@@ -22,12 +37,20 @@ type Migration struct {
 
 // MigrationManager handles database migrations
 type MigrationManager struct {
-	db *DB
+	db     *DB
+	source fs.FS
 }
 
-// NewMigrationManager creates a new migration manager
+// NewMigrationManager creates a new migration manager for the migrations
+// built into the binary
 func NewMigrationManager(db *DB) *MigrationManager {
-	return &MigrationManager{db: db}
+	return NewMigrationManagerWithSource(db, Migrations())
+}
+
+// NewMigrationManagerWithSource creates a migration manager reading the
+// migration files from the root of source, e.g. os.DirFS of a directory
+func NewMigrationManagerWithSource(db *DB, source fs.FS) *MigrationManager {
+	return &MigrationManager{db: db, source: source}
 }
 
 // createMigrationsTable creates the migrations tracking table
@@ -61,29 +84,50 @@ func (m *MigrationManager) markUnapplied(version string) error {
 	return err
 }
 
-// loadMigrationsFromFiles loads migrations from .sql files in the default filesystem
+// loadMigrationsFromFiles loads migrations from the .sql files of the source
 func (m *MigrationManager) loadMigrationsFromFiles() ([]Migration, error) {
-	return m.loadMigrationsFromPath("./internal/database/migrations")
+	return loadMigrations(m.source)
 }
 
-// loadMigrationsFromPath loads migrations from .sql files in a specific path
-func (m *MigrationManager) loadMigrationsFromPath(migrationsDir string) ([]Migration, error) {
-	files, err := ioutil.ReadDir(migrationsDir)
+// Migrations are either a single VERSION_description.sql file, whose down
+// script follows a "-- +migrate Down" line, or a pair of .up.sql and
+// .down.sql files. The down script is optional, migrations without one can't
+// be rolled back.
+const (
+	upFileSuffix    = ".up.sql"
+	downFileSuffix  = ".down.sql"
+	upSectionLine   = "-- +migrate up"
+	downSectionLine = "-- +migrate down"
+)
+
+// loadMigrations loads migrations from the .sql files at the root of source
+func loadMigrations(source fs.FS) ([]Migration, error) {
+	files, err := fs.ReadDir(source, ".")
 	if err != nil {
-		return nil, fmt.Errorf("failed to read migrations directory %s: %w", migrationsDir, err)
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
 	}
 
-	var migrations []Migration
+	byVersion := make(map[string]*Migration)
+	var versions []string
 
 	for _, file := range files {
 		// Only accept .sql files
-		if !strings.HasSuffix(file.Name(), ".sql") {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".sql") {
 			log.Printf("Skipping non-SQL file: %s", file.Name())
 			continue
 		}
 
 		// Parse filename: 2507211130_setup.sql -> version: 2507211130, description: setup
-		name := strings.TrimSuffix(file.Name(), ".sql")
+		name := file.Name()
+		direction := ""
+		switch {
+		case strings.HasSuffix(name, upFileSuffix):
+			name, direction = strings.TrimSuffix(name, upFileSuffix), "up"
+		case strings.HasSuffix(name, downFileSuffix):
+			name, direction = strings.TrimSuffix(name, downFileSuffix), "down"
+		default:
+			name = strings.TrimSuffix(name, ".sql")
+		}
 		parts := strings.SplitN(name, "_", 2)
 		if len(parts) != 2 {
 			log.Printf("Skipping migration file with invalid name format: %s (expected: YYYYMMDDHHNN_description)", file.Name())
@@ -93,27 +137,82 @@ func (m *MigrationManager) loadMigrationsFromPath(migrationsDir string) ([]Migra
 		version := parts[0]
 		description := strings.ReplaceAll(parts[1], "_", " ")
 
-		filePath := filepath.Join(migrationsDir, file.Name())
-		content, err := ioutil.ReadFile(filePath)
+		filePath := file.Name()
+		content, err := fs.ReadFile(source, filePath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read migration file %s: %w", file.Name(), err)
 		}
 
-		migration := Migration{
-			Version:     version,
-			Description: description,
-			Up:          string(content),
-			Down:        "", // Rollback not supported for file-based migrations
-			FilePath:    filePath,
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{
+				Version:     version,
+				Description: description,
+			}
+			byVersion[version] = migration
+			versions = append(versions, version)
+		}
+
+		switch direction {
+		case "down":
+			if migration.Down != "" {
+				return nil, fmt.Errorf("migration %s has more than one down script", version)
+			}
+			migration.Down = string(content)
+		default:
+			if migration.FilePath != "" {
+				return nil, fmt.Errorf("migration %s has more than one up script: %s and %s", version, migration.FilePath, file.Name())
+			}
+			if direction == "up" {
+				migration.Up = string(content)
+			} else {
+				up, down := splitMigrationSections(string(content))
+				if down != "" && migration.Down != "" {
+					return nil, fmt.Errorf("migration %s has more than one down script", version)
+				}
+				migration.Up = up
+				if down != "" {
+					migration.Down = down
+				}
+			}
+			migration.FilePath = filePath
 		}
+	}
 
-		migrations = append(migrations, migration)
-		log.Printf("Loaded migration: %s (%s)", version, description)
+	var migrations []Migration
+	for _, version := range versions {
+		migration := byVersion[version]
+		if migration.FilePath == "" {
+			return nil, fmt.Errorf("migration %s has a down script but no up script", version)
+		}
+		migrations = append(migrations, *migration)
+		log.Printf("Loaded migration: %s (%s)", migration.Version, migration.Description)
 	}
 
 	return migrations, nil
 }
 
+// splitMigrationSections splits a single file migration into its up script
+// and the down script after the "-- +migrate Down" line, if there is one
+func splitMigrationSections(content string) (string, string) {
+	var up, down []string
+	section := &up
+
+	for _, line := range strings.Split(content, "\n") {
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case upSectionLine:
+			section = &up
+			continue
+		case downSectionLine:
+			section = &down
+			continue
+		}
+		*section = append(*section, line)
+	}
+
+	return strings.TrimSpace(strings.Join(up, "\n")), strings.TrimSpace(strings.Join(down, "\n"))
+}
+
 // RunMigrations runs all pending migrations from files
 func (m *MigrationManager) RunMigrations() error {
 	log.Println("Starting file-based migrations...")
@@ -165,7 +264,7 @@ func (m *MigrationManager) runMigrationsFromList(migrations []Migration) error {
 		}
 
 		// Execute migration as a single script
-		if err := m.executeMigrationScript(tx, migration); err != nil {
+		if err := m.executeMigrationScript(tx, migration.Version, migration.Up); err != nil {
 			tx.Rollback()
 			return fmt.Errorf("failed to execute migration %s: %w", migration.Version, err)
 		}
@@ -188,10 +287,11 @@ func (m *MigrationManager) runMigrationsFromList(migrations []Migration) error {
 	return nil
 }
 
-// executeMigrationScript executes the entire migration file as a single script
-func (m *MigrationManager) executeMigrationScript(tx *sql.Tx, migration Migration) error {
+// executeMigrationScript executes the up or down script of a migration as a
+// single script
+func (m *MigrationManager) executeMigrationScript(tx *sql.Tx, version string, script string) error {
 	// Clean up the SQL content
-	sqlContent := strings.TrimSpace(migration.Up)
+	sqlContent := strings.TrimSpace(script)
 
 	// Remove comments that start with -- (but keep SQL inside functions)
 	lines := strings.Split(sqlContent, "\n")
@@ -207,7 +307,7 @@ func (m *MigrationManager) executeMigrationScript(tx *sql.Tx, migration Migratio
 	}
 
 	if len(cleanLines) == 0 {
-		log.Printf("No SQL content found in migration %s", migration.Version)
+		log.Printf("No SQL content found in migration %s", version)
 		return nil
 	}
 
@@ -232,11 +332,144 @@ func truncateString(s string, maxLen int) string {
 	return s[:maxLen] + "..."
 }
 
-// Rollback rolls back the last migration (limited support for file-based migrations)
-func (m *MigrationManager) Rollback() error {
-	log.Println("Rollback not supported for file-based migrations")
-	log.Println("To rollback, create a new migration file with reverse operations")
-	return fmt.Errorf("rollback not implemented for file-based migrations")
+// Rollback rolls back the last steps applied migrations, newest first
+func (m *MigrationManager) Rollback(steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("number of migrations to roll back must be at least 1, got %d", steps)
+	}
+
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return err
+	}
+	if steps > len(applied) {
+		return fmt.Errorf("cannot roll back %d migrations, only %d are applied", steps, len(applied))
+	}
+
+	return m.rollbackVersions(applied[len(applied)-steps:])
+}
+
+// RollbackTo rolls back every migration applied after version, which stays
+// applied. Version 0 rolls back all migrations.
+func (m *MigrationManager) RollbackTo(version string) error {
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return err
+	}
+
+	var versions []string
+	found := version == "0"
+	for _, appliedVersion := range applied {
+		if appliedVersion == version {
+			found = true
+		}
+		if appliedVersion > version {
+			versions = append(versions, appliedVersion)
+		}
+	}
+	if !found {
+		return fmt.Errorf("migration %s is not applied", version)
+	}
+
+	if len(versions) == 0 {
+		log.Printf("No migrations applied after %s, nothing to roll back", version)
+		return nil
+	}
+
+	return m.rollbackVersions(versions)
+}
+
+// appliedVersions returns the versions of the applied migrations, oldest first
+func (m *MigrationManager) appliedVersions() ([]string, error) {
+	if err := m.createMigrationsTable(); err != nil {
+		return nil, fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	rows, err := m.db.Query("SELECT version FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []string
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to scan migration row: %w", err)
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, rows.Err()
+}
+
+// rollbackVersions runs the down scripts of the applied versions, newest
+// first, each in its own transaction
+func (m *MigrationManager) rollbackVersions(versions []string) error {
+	migrations, err := m.loadMigrationsFromFiles()
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	plan, err := planRollback(migrations, versions)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range plan {
+		log.Printf("Rolling back migration %s: %s", migration.Version, migration.Description)
+
+		tx, err := m.db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to start transaction for rollback of %s: %w", migration.Version, err)
+		}
+
+		if err := m.executeMigrationScript(tx, migration.Version, migration.Down); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to roll back migration %s: %w", migration.Version, err)
+		}
+
+		if _, err := tx.Exec("DELETE FROM schema_migrations WHERE version = $1", migration.Version); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to mark migration %s as rolled back: %w", migration.Version, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit rollback of %s: %w", migration.Version, err)
+		}
+
+		log.Printf("Successfully rolled back migration %s", migration.Version)
+	}
+
+	return nil
+}
+
+// planRollback returns the migrations of versions, newest first. It fails
+// before anything is rolled back if one of them has no file or no down
+// script.
+func planRollback(migrations []Migration, versions []string) ([]Migration, error) {
+	byVersion := make(map[string]Migration, len(migrations))
+	for _, migration := range migrations {
+		byVersion[migration.Version] = migration
+	}
+
+	plan := make([]Migration, 0, len(versions))
+	for _, version := range versions {
+		migration, ok := byVersion[version]
+		if !ok {
+			return nil, fmt.Errorf("cannot roll back migration %s: no migration file found", version)
+		}
+		if strings.TrimSpace(migration.Down) == "" {
+			return nil, fmt.Errorf("cannot roll back migration %s (%s): it has no down script", version, migration.Description)
+		}
+		plan = append(plan, migration)
+	}
+
+	sort.Slice(plan, func(i, j int) bool {
+		return plan[i].Version > plan[j].Version
+	})
+
+	return plan, nil
 }
 
 // ListAppliedMigrations shows which migrations have been applied
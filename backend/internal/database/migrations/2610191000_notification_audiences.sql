@@ -0,0 +1,29 @@
+-- internal/database/migrations/2610191000_notification_audiences.sql
+-- Saved audiences for notification targeting
+
+-- Create audiences table, the filter is stored as JSONB over user attributes
+CREATE TABLE IF NOT EXISTS notification_audiences (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) UNIQUE NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    filter JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+DROP TRIGGER IF EXISTS update_notification_audiences_updated_at ON notification_audiences;
+CREATE TRIGGER update_notification_audiences_updated_at
+    BEFORE UPDATE ON notification_audiences
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Speed up audience resolution
+CREATE INDEX IF NOT EXISTS idx_users_age ON users(age);
+CREATE INDEX IF NOT EXISTS idx_users_lower_email ON users(LOWER(email));
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_users_lower_email;
+DROP INDEX IF EXISTS idx_users_age;
+
+DROP TABLE IF EXISTS notification_audiences;
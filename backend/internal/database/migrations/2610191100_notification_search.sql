@@ -0,0 +1,57 @@
+-- internal/database/migrations/2610191100_notification_search.sql
+-- Full-text search over notifications with per-notification language
+
+-- Store the extra payload and the text search language with each notification
+ALTER TABLE notifications
+ADD COLUMN IF NOT EXISTS data JSONB NOT NULL DEFAULT '{}'::jsonb,
+ADD COLUMN IF NOT EXISTS language VARCHAR(32) NOT NULL DEFAULT 'english',
+ADD COLUMN IF NOT EXISTS search_vector TSVECTOR;
+
+-- Resolve a language name to a text search configuration, falling back to simple
+CREATE OR REPLACE FUNCTION notification_search_config(p_language TEXT)
+RETURNS REGCONFIG AS $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = p_language) THEN
+        RETURN p_language::regconfig;
+    END IF;
+    RETURN 'simple'::regconfig;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+-- Keep the search vector in sync with message and language
+CREATE OR REPLACE FUNCTION update_notification_search_vector()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector = to_tsvector(notification_search_config(NEW.language), COALESCE(NEW.message, ''));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS update_notifications_search_vector ON notifications;
+CREATE TRIGGER update_notifications_search_vector
+    BEFORE INSERT OR UPDATE OF message, language ON notifications
+    FOR EACH ROW
+    EXECUTE FUNCTION update_notification_search_vector();
+
+-- Backfill existing notifications
+UPDATE notifications
+SET search_vector = to_tsvector(notification_search_config(language), message)
+WHERE search_vector IS NULL;
+
+-- Indexes for search and data key filters
+CREATE INDEX IF NOT EXISTS idx_notifications_search_vector ON notifications USING GIN(search_vector);
+CREATE INDEX IF NOT EXISTS idx_notifications_data ON notifications USING GIN(data);
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_notifications_data;
+DROP INDEX IF EXISTS idx_notifications_search_vector;
+
+DROP TRIGGER IF EXISTS update_notifications_search_vector ON notifications;
+DROP FUNCTION IF EXISTS update_notification_search_vector();
+DROP FUNCTION IF EXISTS notification_search_config(TEXT);
+
+ALTER TABLE notifications
+DROP COLUMN IF EXISTS search_vector,
+DROP COLUMN IF EXISTS language,
+DROP COLUMN IF EXISTS data;
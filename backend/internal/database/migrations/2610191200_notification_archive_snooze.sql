@@ -0,0 +1,21 @@
+-- internal/database/migrations/2610191200_notification_archive_snooze.sql
+-- Archive and snooze states for notifications
+
+-- archived_at: hidden from the inbox but still retrievable
+-- snoozed_until: hidden from the inbox until the time has passed
+ALTER TABLE notifications
+ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS snoozed_until TIMESTAMP WITH TIME ZONE;
+
+-- Partial indexes keep the snooze worker and archive listing cheap
+CREATE INDEX IF NOT EXISTS idx_notifications_snoozed_until ON notifications(snoozed_until) WHERE snoozed_until IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_notifications_archived_at ON notifications(user_id, archived_at) WHERE archived_at IS NOT NULL;
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_notifications_archived_at;
+DROP INDEX IF EXISTS idx_notifications_snoozed_until;
+
+ALTER TABLE notifications
+DROP COLUMN IF EXISTS snoozed_until,
+DROP COLUMN IF EXISTS archived_at;
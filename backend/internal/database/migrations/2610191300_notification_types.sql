@@ -0,0 +1,37 @@
+-- internal/database/migrations/2610191300_notification_types.sql
+-- Registry of notification types with delivery and retention settings
+
+-- Custom types registered at runtime, the builtin types are seeded below
+CREATE TABLE IF NOT EXISTS notification_types (
+    name VARCHAR(50) PRIMARY KEY,
+    severity VARCHAR(20) NOT NULL DEFAULT 'info' CHECK (severity IN ('info', 'warning', 'error', 'success')),
+    icon VARCHAR(100) NOT NULL DEFAULT '',
+    retention_days INTEGER NOT NULL DEFAULT 0 CHECK (retention_days >= 0),
+    channels TEXT[] NOT NULL DEFAULT ARRAY['in_app', 'realtime'],
+    builtin BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+DROP TRIGGER IF EXISTS update_notification_types_updated_at ON notification_types;
+CREATE TRIGGER update_notification_types_updated_at
+    BEFORE UPDATE ON notification_types
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+INSERT INTO notification_types (name, severity, icon, builtin) VALUES
+    ('info', 'info', 'info', TRUE),
+    ('warning', 'warning', 'warning', TRUE),
+    ('error', 'error', 'error', TRUE),
+    ('success', 'success', 'check_circle', TRUE)
+ON CONFLICT (name) DO NOTHING;
+
+-- Speed up retention purges per type
+CREATE INDEX IF NOT EXISTS idx_notifications_type_created_at ON notifications(type, created_at);
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_notifications_type_created_at;
+
+-- Takes its updated_at trigger along
+DROP TABLE IF EXISTS notification_types;
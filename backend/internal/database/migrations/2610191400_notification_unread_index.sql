@@ -0,0 +1,11 @@
+-- internal/database/migrations/2610191400_notification_unread_index.sql
+-- Keep the unread badge count cheap
+
+-- Only unread inbox notifications are counted for the badge
+CREATE INDEX IF NOT EXISTS idx_notifications_unread_inbox
+    ON notifications(user_id)
+    WHERE read = false AND archived_at IS NULL;
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_notifications_unread_inbox;
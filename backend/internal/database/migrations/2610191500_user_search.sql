@@ -0,0 +1,11 @@
+-- internal/database/migrations/2610191500_user_search.sql
+-- Indexes for user search and sorting
+
+-- Prefix search and sorting on name and email, case-insensitive
+CREATE INDEX IF NOT EXISTS idx_users_lower_name_pattern ON users(LOWER(name) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_users_lower_email_pattern ON users(LOWER(email) text_pattern_ops);
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_users_lower_email_pattern;
+DROP INDEX IF EXISTS idx_users_lower_name_pattern;
@@ -0,0 +1,12 @@
+-- internal/database/migrations/2610191600_keyset_pagination.sql
+-- Indexes for page tokens, which page on (created_at, id)
+
+CREATE INDEX IF NOT EXISTS idx_users_created_at_id ON users(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_notifications_created_at_id ON notifications(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_created_at_id ON notifications(user_id, created_at, id);
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_notifications_user_created_at_id;
+DROP INDEX IF EXISTS idx_notifications_created_at_id;
+DROP INDEX IF EXISTS idx_users_created_at_id;
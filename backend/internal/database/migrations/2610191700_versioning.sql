@@ -0,0 +1,35 @@
+-- internal/database/migrations/2610191700_versioning.sql
+-- Row versions for optimistic concurrency control
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+-- Every update bumps the version, whichever code path issued it
+CREATE OR REPLACE FUNCTION increment_version()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.version = OLD.version + 1;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS increment_users_version ON users;
+CREATE TRIGGER increment_users_version
+    BEFORE UPDATE ON users
+    FOR EACH ROW
+    EXECUTE FUNCTION increment_version();
+
+DROP TRIGGER IF EXISTS increment_notifications_version ON notifications;
+CREATE TRIGGER increment_notifications_version
+    BEFORE UPDATE ON notifications
+    FOR EACH ROW
+    EXECUTE FUNCTION increment_version();
+
+-- +migrate Down
+
+DROP TRIGGER IF EXISTS increment_notifications_version ON notifications;
+DROP TRIGGER IF EXISTS increment_users_version ON users;
+DROP FUNCTION IF EXISTS increment_version();
+
+ALTER TABLE notifications DROP COLUMN IF EXISTS version;
+ALTER TABLE users DROP COLUMN IF EXISTS version;
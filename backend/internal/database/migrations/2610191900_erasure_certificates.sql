@@ -0,0 +1,20 @@
+-- internal/database/migrations/2610191900_erasure_certificates.sql
+-- Audit trail of GDPR erasures. Certificates outlive the user, so there is
+-- no foreign key on user_id.
+
+CREATE TABLE IF NOT EXISTS erasure_certificates (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    mode VARCHAR(20) NOT NULL CHECK (mode IN ('anonymize', 'delete')),
+    requested_by VARCHAR(255) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    sources JSONB NOT NULL DEFAULT '{}'::jsonb,
+    digest CHAR(64) NOT NULL,
+    erased_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_erasure_certificates_user_id ON erasure_certificates(user_id);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS erasure_certificates;
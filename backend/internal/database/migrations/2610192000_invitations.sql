@@ -0,0 +1,29 @@
+-- internal/database/migrations/2610192000_invitations.sql
+-- Invitations and passwords for invited users
+
+-- Users created before invitations have no password
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_hash TEXT;
+
+CREATE TABLE IF NOT EXISTS invitations (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(100) NOT NULL DEFAULT 'user',
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    invited_by VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    accepted_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- At most one open invitation per address, inviting again replaces it
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invitations_open_email
+    ON invitations(LOWER(email))
+    WHERE accepted_at IS NULL AND revoked_at IS NULL;
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS invitations;
+
+ALTER TABLE users DROP COLUMN IF EXISTS password_hash;
@@ -0,0 +1,56 @@
+-- internal/database/migrations/2610192100_email_verification.sql
+-- Case-insensitive emails and email verification
+
+CREATE EXTENSION IF NOT EXISTS citext;
+
+-- Addresses differing only in case can't be merged automatically
+DO $$
+DECLARE
+    duplicate CITEXT;
+BEGIN
+    SELECT LOWER(email) INTO duplicate
+    FROM users
+    WHERE deleted_at IS NULL
+    GROUP BY LOWER(email)
+    HAVING COUNT(*) > 1
+    LIMIT 1;
+
+    IF duplicate IS NOT NULL THEN
+        RAISE EXCEPTION 'users share the email address % in different case, merge them before migrating', duplicate;
+    END IF;
+END;
+$$;
+
+-- The unique index on active users becomes case-insensitive with the type
+ALTER TABLE users ALTER COLUMN email TYPE CITEXT;
+ALTER TABLE invitations ALTER COLUMN email TYPE CITEXT;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Invited users proved they own their address by accepting
+UPDATE users SET email_verified = TRUE
+WHERE id IN (SELECT user_id FROM invitations WHERE accepted_at IS NOT NULL AND user_id IS NOT NULL);
+
+CREATE TABLE IF NOT EXISTS email_verifications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email CITEXT NOT NULL,
+    purpose VARCHAR(20) NOT NULL CHECK (purpose IN ('verify', 'change')),
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    consumed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_verifications_user_created ON email_verifications(user_id, created_at);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS email_verifications;
+
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified;
+
+-- Uniqueness is case-sensitive again. The citext extension stays, other
+-- schemas may use it.
+ALTER TABLE invitations ALTER COLUMN email TYPE VARCHAR(255);
+ALTER TABLE users ALTER COLUMN email TYPE VARCHAR(255);
@@ -0,0 +1,31 @@
+-- internal/database/migrations/2610192600_impersonation.sql
+-- Log of admins acting as users and of the requests they made
+
+CREATE TABLE IF NOT EXISTS impersonations (
+    id SERIAL PRIMARY KEY,
+    -- Kept when either user is deleted, the log outlives them
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL,
+    client_ip VARCHAR(45) NOT NULL DEFAULT '',
+    started_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ended_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_impersonations_actor_id ON impersonations(actor_id);
+CREATE INDEX IF NOT EXISTS idx_impersonations_user_id ON impersonations(user_id);
+
+CREATE TABLE IF NOT EXISTS impersonation_log (
+    id BIGSERIAL PRIMARY KEY,
+    impersonation_id INTEGER NOT NULL REFERENCES impersonations(id) ON DELETE CASCADE,
+    method VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_impersonation_log_impersonation_id ON impersonation_log(impersonation_id);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS impersonation_log;
+DROP TABLE IF EXISTS impersonations;
@@ -0,0 +1,200 @@
+-- internal/database/migrations/2610192700_audit_events.sql
+-- Append-only audit log written by triggers, in the same transaction as the change
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id SERIAL PRIMARY KEY,
+    -- No foreign keys, events outlive the users and keys they name
+    actor_user_id INTEGER,
+    actor_api_key_id INTEGER,
+    impersonated_by INTEGER,
+    tenant_id VARCHAR(100),
+    action VARCHAR(100) NOT NULL,
+    target_type VARCHAR(50) NOT NULL,
+    target_id VARCHAR(100) NOT NULL,
+    -- Inserts only have after, deletes only before, updates the changed columns
+    before JSONB,
+    after JSONB,
+    request_id VARCHAR(64),
+    client_ip VARCHAR(45),
+    -- The RPC method or socket event, empty for background jobs
+    source VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at_id ON audit_events(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor_user_id ON audit_events(actor_user_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_target ON audit_events(target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_request_id ON audit_events(request_id);
+
+-- Events can't be changed or removed once written. The only exception is the
+-- erasure of personal data, which may blank before and after of an event
+-- when the transaction sets audit.redact.
+CREATE OR REPLACE FUNCTION prevent_audit_event_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE'
+        AND current_setting('audit.redact', true) = 'on'
+        AND (to_jsonb(NEW) - ARRAY['before', 'after']) = (to_jsonb(OLD) - ARRAY['before', 'after']) THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events;
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW
+    EXECUTE FUNCTION prevent_audit_event_change();
+
+-- Records a row change. The request behind it is read from the audit.context
+-- setting, which the backend sets at the start of the transaction. The first
+-- argument names the key column, id by default.
+CREATE OR REPLACE FUNCTION audit_row_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    context JSONB := NULLIF(current_setting('audit.context', true), '')::JSONB;
+    key_column TEXT := COALESCE(TG_ARGV[0], 'id');
+    -- Never copied into the log
+    secret_columns TEXT[] := ARRAY['password_hash', 'totp_secret', 'token_hash', 'key_hash'];
+    -- Derived from other columns, left out of the snapshots entirely
+    derived_columns TEXT[] := ARRAY['search_vector'];
+    -- Bookkeeping that doesn't make an update worth logging on its own
+    noise_columns TEXT[] := ARRAY['updated_at', 'version', 'last_used_at', 'last_login_at', 'last_seen_at', 'totp_last_step'];
+    old_row JSONB;
+    new_row JSONB;
+    before_row JSONB;
+    after_row JSONB;
+    changed BOOLEAN := FALSE;
+    column_name TEXT;
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        old_row := to_jsonb(OLD) - derived_columns;
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        new_row := to_jsonb(NEW) - derived_columns;
+    END IF;
+
+    IF TG_OP = 'INSERT' THEN
+        after_row := new_row - secret_columns;
+    ELSIF TG_OP = 'DELETE' THEN
+        before_row := old_row - secret_columns;
+    ELSE
+        before_row := '{}'::JSONB;
+        after_row := '{}'::JSONB;
+        FOR column_name IN SELECT jsonb_object_keys(new_row) LOOP
+            CONTINUE WHEN old_row -> column_name IS NOT DISTINCT FROM new_row -> column_name;
+
+            IF column_name = ANY (secret_columns) THEN
+                -- Record that a secret changed, not its value
+                before_row := before_row || jsonb_build_object(column_name, 'redacted');
+                after_row := after_row || jsonb_build_object(column_name, 'redacted');
+            ELSE
+                before_row := before_row || jsonb_build_object(column_name, old_row -> column_name);
+                after_row := after_row || jsonb_build_object(column_name, new_row -> column_name);
+            END IF;
+
+            IF NOT column_name = ANY (noise_columns) THEN
+                changed := TRUE;
+            END IF;
+        END LOOP;
+
+        IF NOT changed THEN
+            RETURN NULL;
+        END IF;
+    END IF;
+
+    INSERT INTO audit_events (
+        actor_user_id, actor_api_key_id, impersonated_by, tenant_id,
+        action, target_type, target_id, before, after,
+        request_id, client_ip, source
+    ) VALUES (
+        (context ->> 'actor_user_id')::INTEGER,
+        (context ->> 'actor_api_key_id')::INTEGER,
+        (context ->> 'impersonated_by')::INTEGER,
+        context ->> 'tenant_id',
+        TG_TABLE_NAME || '.' || lower(TG_OP),
+        TG_TABLE_NAME,
+        COALESCE(new_row ->> key_column, old_row ->> key_column),
+        before_row,
+        after_row,
+        context ->> 'request_id',
+        context ->> 'client_ip',
+        COALESCE(context ->> 'source', '')
+    );
+
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS audit_users ON users;
+CREATE TRIGGER audit_users
+    AFTER INSERT OR UPDATE OR DELETE ON users
+    FOR EACH ROW
+    EXECUTE FUNCTION audit_row_change();
+
+DROP TRIGGER IF EXISTS audit_notifications ON notifications;
+CREATE TRIGGER audit_notifications
+    AFTER INSERT OR UPDATE OR DELETE ON notifications
+    FOR EACH ROW
+    EXECUTE FUNCTION audit_row_change();
+
+DROP TRIGGER IF EXISTS audit_notification_audiences ON notification_audiences;
+CREATE TRIGGER audit_notification_audiences
+    AFTER INSERT OR UPDATE OR DELETE ON notification_audiences
+    FOR EACH ROW
+    EXECUTE FUNCTION audit_row_change();
+
+DROP TRIGGER IF EXISTS audit_notification_types ON notification_types;
+CREATE TRIGGER audit_notification_types
+    AFTER INSERT OR UPDATE OR DELETE ON notification_types
+    FOR EACH ROW
+    EXECUTE FUNCTION audit_row_change('name');
+
+DROP TRIGGER IF EXISTS audit_invitations ON invitations;
+CREATE TRIGGER audit_invitations
+    AFTER INSERT OR UPDATE OR DELETE ON invitations
+    FOR EACH ROW
+    EXECUTE FUNCTION audit_row_change();
+
+DROP TRIGGER IF EXISTS audit_api_keys ON api_keys;
+CREATE TRIGGER audit_api_keys
+    AFTER INSERT OR UPDATE OR DELETE ON api_keys
+    FOR EACH ROW
+    EXECUTE FUNCTION audit_row_change();
+
+DROP TRIGGER IF EXISTS audit_user_identities ON user_identities;
+CREATE TRIGGER audit_user_identities
+    AFTER INSERT OR UPDATE OR DELETE ON user_identities
+    FOR EACH ROW
+    EXECUTE FUNCTION audit_row_change();
+
+DROP TRIGGER IF EXISTS audit_impersonations ON impersonations;
+CREATE TRIGGER audit_impersonations
+    AFTER INSERT OR UPDATE OR DELETE ON impersonations
+    FOR EACH ROW
+    EXECUTE FUNCTION audit_row_change();
+
+DROP TRIGGER IF EXISTS audit_erasure_certificates ON erasure_certificates;
+CREATE TRIGGER audit_erasure_certificates
+    AFTER INSERT OR UPDATE OR DELETE ON erasure_certificates
+    FOR EACH ROW
+    EXECUTE FUNCTION audit_row_change();
+
+-- +migrate Down
+
+DROP TRIGGER IF EXISTS audit_users ON users;
+DROP TRIGGER IF EXISTS audit_notifications ON notifications;
+DROP TRIGGER IF EXISTS audit_notification_audiences ON notification_audiences;
+DROP TRIGGER IF EXISTS audit_notification_types ON notification_types;
+DROP TRIGGER IF EXISTS audit_invitations ON invitations;
+DROP TRIGGER IF EXISTS audit_api_keys ON api_keys;
+DROP TRIGGER IF EXISTS audit_user_identities ON user_identities;
+DROP TRIGGER IF EXISTS audit_impersonations ON impersonations;
+DROP TRIGGER IF EXISTS audit_erasure_certificates ON erasure_certificates;
+
+DROP FUNCTION IF EXISTS audit_row_change();
+
+-- Takes its append-only trigger along
+DROP TABLE IF EXISTS audit_events;
+DROP FUNCTION IF EXISTS prevent_audit_event_change();
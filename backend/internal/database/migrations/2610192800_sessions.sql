@@ -0,0 +1,60 @@
+-- internal/database/migrations/2610192800_sessions.sql
+-- Sessions group the refresh tokens of one login across rotations
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    device VARCHAR(100) NOT NULL DEFAULT '',
+    user_agent VARCHAR(500) NOT NULL DEFAULT '',
+    client_ip VARCHAR(45) NOT NULL DEFAULT '',
+    -- Moves forward with every refresh
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_active ON sessions(user_id) WHERE revoked_at IS NULL;
+
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS session_id INTEGER REFERENCES sessions(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens(session_id);
+
+-- Logins from before sessions existed become a session each, numbered like
+-- their current refresh token
+INSERT INTO sessions (id, user_id, device, expires_at, last_seen_at, created_at)
+SELECT id, user_id, 'Unknown device', expires_at, created_at, created_at
+FROM refresh_tokens
+WHERE session_id IS NULL AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+ON CONFLICT (id) DO NOTHING;
+
+UPDATE refresh_tokens
+SET session_id = id
+WHERE session_id IS NULL AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+    AND EXISTS (SELECT 1 FROM sessions WHERE sessions.id = refresh_tokens.id);
+
+SELECT setval(pg_get_serial_sequence('sessions', 'id'), COALESCE((SELECT MAX(id) FROM sessions), 0) + 1, false);
+
+-- Logins, refreshes and revocations are audited from here on, the backfill
+-- above is not
+DROP TRIGGER IF EXISTS audit_sessions ON sessions;
+CREATE TRIGGER audit_sessions
+    AFTER INSERT OR UPDATE OR DELETE ON sessions
+    FOR EACH ROW
+    EXECUTE FUNCTION audit_row_change();
+
+DROP TRIGGER IF EXISTS audit_refresh_tokens ON refresh_tokens;
+CREATE TRIGGER audit_refresh_tokens
+    AFTER INSERT OR UPDATE OR DELETE ON refresh_tokens
+    FOR EACH ROW
+    EXECUTE FUNCTION audit_row_change();
+
+-- +migrate Down
+
+DROP TRIGGER IF EXISTS audit_refresh_tokens ON refresh_tokens;
+
+-- Refresh tokens keep working without their session
+DROP INDEX IF EXISTS idx_refresh_tokens_session_id;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS session_id;
+
+DROP TABLE IF EXISTS sessions;
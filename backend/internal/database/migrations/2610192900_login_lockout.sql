@@ -0,0 +1,19 @@
+-- internal/database/migrations/2610192900_login_lockout.sql
+-- Temporary account lockout after repeated failed logins
+
+-- Set while the account is locked, logins are refused until then
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP WITH TIME ZONE;
+
+-- Failed logins in a row per account. Kept apart from users, so that counting
+-- them doesn't fill the audit log, only locks and unlocks show up there.
+CREATE TABLE IF NOT EXISTS failed_logins (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS failed_logins;
+
+ALTER TABLE users DROP COLUMN IF EXISTS locked_until;
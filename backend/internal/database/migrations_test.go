@@ -0,0 +1,116 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// migrationFiles creates an in-memory migrations directory with the given files
+func migrationFiles(files map[string]string) fstest.MapFS {
+	source := fstest.MapFS{}
+	for name, content := range files {
+		source[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	return source
+}
+
+func TestLoadMigrations(t *testing.T) {
+	source := migrationFiles(map[string]string{
+		"2507211130_setup.sql":          "CREATE TABLE a (id INT);",
+		"2507211200_sections.sql":       "-- +migrate Up\nCREATE TABLE b (id INT);\n\n-- +migrate Down\nDROP TABLE b;\n",
+		"2507211300_paired.up.sql":      "CREATE TABLE c (id INT);",
+		"2507211300_paired.down.sql":    "DROP TABLE c;",
+		"2507211400_marker_in_text.sql": "-- +migrate down is only a marker on its own line\nSELECT 1;",
+		"README.md":                     "not a migration",
+		"invalid.sql":                   "SELECT 1;",
+	})
+
+	migrations, err := loadMigrations(source)
+	require.NoError(t, err)
+	require.Len(t, migrations, 4)
+
+	assert.Equal(t, Migration{Version: "2507211130", Description: "setup", Up: "CREATE TABLE a (id INT);", FilePath: "2507211130_setup.sql"}, migrations[0])
+
+	assert.Equal(t, "CREATE TABLE b (id INT);", migrations[1].Up)
+	assert.Equal(t, "DROP TABLE b;", migrations[1].Down)
+
+	assert.Equal(t, "paired", migrations[2].Description)
+	assert.Equal(t, "CREATE TABLE c (id INT);", migrations[2].Up)
+	assert.Equal(t, "DROP TABLE c;", migrations[2].Down)
+	assert.Equal(t, "2507211300_paired.up.sql", migrations[2].FilePath)
+
+	assert.Contains(t, migrations[3].Up, "SELECT 1;")
+	assert.Empty(t, migrations[3].Down)
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	tests := map[string]map[string]string{
+		"down without up": {
+			"2507211300_paired.down.sql": "DROP TABLE c;",
+		},
+		"two up scripts": {
+			"2507211300_paired.sql":    "CREATE TABLE c (id INT);",
+			"2507211300_paired.up.sql": "CREATE TABLE c (id INT);",
+		},
+		"two down scripts": {
+			"2507211300_paired.sql":      "CREATE TABLE c (id INT);\n-- +migrate Down\nDROP TABLE c;",
+			"2507211300_paired.down.sql": "DROP TABLE c;",
+		},
+	}
+
+	for name, files := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := loadMigrations(migrationFiles(files))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPlanRollback(t *testing.T) {
+	migrations := []Migration{
+		{Version: "2507211130", Description: "setup", Up: "CREATE TABLE a (id INT);"},
+		{Version: "2507211200", Description: "b", Up: "CREATE TABLE b (id INT);", Down: "DROP TABLE b;"},
+		{Version: "2507211300", Description: "c", Up: "CREATE TABLE c (id INT);", Down: "DROP TABLE c;"},
+	}
+
+	plan, err := planRollback(migrations, []string{"2507211200", "2507211300"})
+	require.NoError(t, err)
+	require.Len(t, plan, 2)
+	assert.Equal(t, "2507211300", plan[0].Version)
+	assert.Equal(t, "2507211200", plan[1].Version)
+
+	// Nothing is rolled back if one of the migrations can't be
+	_, err = planRollback(migrations, []string{"2507211130", "2507211200"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no down script")
+	_, err = planRollback(migrations, []string{"2507211400"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no migration file")
+}
+
+func TestMigrations(t *testing.T) {
+	migrations, err := loadMigrations(Migrations())
+	require.NoError(t, err)
+	assert.Equal(t, "2507211130", migrations[0].Version)
+
+	// Down sections are never run with the up scripts
+	for _, migration := range migrations {
+		assert.NotContains(t, migration.Up, "DROP TABLE IF EXISTS audit_events", migration.Version)
+	}
+
+	// Every migration after the initial ones can be rolled back
+	var versions []string
+	for _, migration := range migrations {
+		if migration.Version <= "2507211524" {
+			continue
+		}
+		assert.NotEmpty(t, strings.TrimSpace(migration.Down), migration.Version)
+		versions = append(versions, migration.Version)
+	}
+	_, err = planRollback(migrations, versions)
+	assert.NoError(t, err)
+}
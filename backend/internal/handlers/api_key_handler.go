@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend-grpc-server/internal/auth"
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/storage"
+	pb "backend-grpc-server/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// APIKeyHandler handles API key management. The Authenticator only lets
+// admins call it.
+type APIKeyHandler struct {
+	pb.UnimplementedAPIKeyServiceServer
+	store         storage.APIKeyStore
+	socketHandler *SocketHandler
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(store storage.APIKeyStore, socketHandler *SocketHandler) *APIKeyHandler {
+	return &APIKeyHandler{
+		store:         store,
+		socketHandler: socketHandler,
+	}
+}
+
+// CreateAPIKey creates a key for the signed in admin. The secret key is only
+// returned here.
+func (h *APIKeyHandler) CreateAPIKey(ctx context.Context, req *pb.CreateAPIKeyRequest) (*pb.CreateAPIKeyResponse, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok || principal.IsAPIKey() {
+		return nil, status.Errorf(codes.Unauthenticated, "sign in to create API keys")
+	}
+	if req.ValidForDays < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "valid_for_days cannot be negative")
+	}
+
+	params := &models.CreateAPIKeyParams{
+		Name:      strings.TrimSpace(req.Name),
+		Scopes:    req.Scopes,
+		CreatedBy: principal.UserID,
+	}
+	if req.ValidForDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, int(req.ValidForDays))
+		params.ExpiresAt = &expiresAt
+	}
+
+	if err := params.Validate(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
+	}
+
+	key, prefix, keyHash, err := auth.NewAPIKey()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+
+	apiKey, err := h.store.WithContext(ctx).CreateAPIKey(params, prefix, keyHash)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create API key: %v", err)
+	}
+
+	// Send socket event about the new key
+	h.socketHandler.WithContext(ctx).EmitToAll("api_key_created", map[string]interface{}{
+		"id":   apiKey.ID,
+		"name": apiKey.Name,
+	})
+
+	return &pb.CreateAPIKeyResponse{
+		ApiKey: convertToProtoAPIKey(apiKey),
+		Key:    key,
+	}, nil
+}
+
+// ListAPIKeys returns the active keys, or all of them
+func (h *APIKeyHandler) ListAPIKeys(ctx context.Context, req *pb.ListAPIKeysRequest) (*pb.ListAPIKeysResponse, error) {
+	keys, err := h.store.ListAPIKeys(!req.IncludeInactive)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list API keys: %v", err)
+	}
+
+	pbKeys := make([]*pb.APIKey, 0, len(keys))
+	for _, key := range keys {
+		pbKeys = append(pbKeys, convertToProtoAPIKey(key))
+	}
+
+	return &pb.ListAPIKeysResponse{
+		ApiKeys: pbKeys,
+	}, nil
+}
+
+// RevokeAPIKey invalidates a key. Requests made with it fail from now on.
+func (h *APIKeyHandler) RevokeAPIKey(ctx context.Context, req *pb.RevokeAPIKeyRequest) (*pb.RevokeAPIKeyResponse, error) {
+	if req.Id <= 0 {
+		return &pb.RevokeAPIKeyResponse{
+			Success: false,
+			Message: "API key ID must be greater than 0",
+		}, nil
+	}
+
+	if err := h.store.WithContext(ctx).RevokeAPIKey(req.Id); err != nil {
+		return &pb.RevokeAPIKeyResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	// Send socket event about the revocation
+	h.socketHandler.WithContext(ctx).EmitToAll("api_key_revoked", map[string]interface{}{
+		"id": req.Id,
+	})
+
+	return &pb.RevokeAPIKeyResponse{
+		Success: true,
+		Message: fmt.Sprintf("API key with ID %d successfully revoked", req.Id),
+	}, nil
+}
+
+func convertToProtoAPIKey(key *models.APIKey) *pb.APIKey {
+	var expiresAt, lastUsedAt, revokedAt string
+	if key.ExpiresAt != nil {
+		expiresAt = key.ExpiresAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+	if key.LastUsedAt != nil {
+		lastUsedAt = key.LastUsedAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+	if key.RevokedAt != nil {
+		revokedAt = key.RevokedAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+
+	var createdBy int32
+	if key.CreatedBy != nil {
+		createdBy = *key.CreatedBy
+	}
+
+	return &pb.APIKey{
+		Id:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		Status:     key.Status(time.Now()),
+		ExpiresAt:  expiresAt,
+		LastUsedAt: lastUsedAt,
+		RevokedAt:  revokedAt,
+		CreatedBy:  createdBy,
+		CreatedAt:  key.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
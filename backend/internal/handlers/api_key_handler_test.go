@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"backend-grpc-server/internal/auth"
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/storage"
+	"backend-grpc-server/internal/testutil"
+	pb "backend-grpc-server/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAPIKeyHandler_CreateUseRevoke(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	store := storage.NewPostgresAPIKeyStore(db)
+	handler := NewAPIKeyHandler(store, NewSocketHandler())
+	authenticator := NewAuthenticator(store, storage.NewPostgresImpersonationStore(db), storage.NewPostgresAuthStore(db), auth.NewSigner([]byte("secret"), 0))
+
+	admin, err := storage.NewPostgresUserStore(db).CreateUser(&models.CreateUserParams{Name: "Admin", Email: "admin@example.com", Age: 40, Role: "admin"})
+	require.NoError(t, err)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: admin.ID, Role: "admin"})
+
+	_, err = handler.CreateAPIKey(context.Background(), &pb.CreateAPIKeyRequest{Name: "CRM sync", Scopes: []string{models.ScopeUsersRead}})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = handler.CreateAPIKey(ctx, &pb.CreateAPIKeyRequest{Name: "CRM sync", Scopes: []string{"users:delete"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	created, err := handler.CreateAPIKey(ctx, &pb.CreateAPIKeyRequest{Name: "CRM sync", Scopes: []string{models.ScopeUsersRead}, ValidForDays: 30})
+	require.NoError(t, err)
+	assert.Equal(t, models.APIKeyActive, created.ApiKey.Status)
+	assert.Equal(t, admin.ID, created.ApiKey.CreatedBy)
+	assert.NotEmpty(t, created.ApiKey.ExpiresAt)
+	assert.Empty(t, created.ApiKey.LastUsedAt)
+	assert.Contains(t, created.Key, created.ApiKey.Prefix)
+
+	principal, err := authenticator.apiKeyPrincipal(created.Key)
+	require.NoError(t, err)
+	assert.Equal(t, created.ApiKey.Id, principal.APIKeyID)
+	assert.Equal(t, []string{models.ScopeUsersRead}, principal.Scopes)
+
+	list, err := handler.ListAPIKeys(ctx, &pb.ListAPIKeysRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.ApiKeys, 1)
+	assert.NotEmpty(t, list.ApiKeys[0].LastUsedAt)
+
+	revoked, err := handler.RevokeAPIKey(ctx, &pb.RevokeAPIKeyRequest{Id: created.ApiKey.Id})
+	require.NoError(t, err)
+	assert.True(t, revoked.Success)
+	revoked, err = handler.RevokeAPIKey(ctx, &pb.RevokeAPIKeyRequest{Id: created.ApiKey.Id})
+	require.NoError(t, err)
+	assert.False(t, revoked.Success)
+
+	_, err = authenticator.apiKeyPrincipal(created.Key)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	list, err = handler.ListAPIKeys(ctx, &pb.ListAPIKeysRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, list.ApiKeys)
+	list, err = handler.ListAPIKeys(ctx, &pb.ListAPIKeysRequest{IncludeInactive: true})
+	require.NoError(t, err)
+	require.Len(t, list.ApiKeys, 1)
+	assert.Equal(t, models.APIKeyRevoked, list.ApiKeys[0].Status)
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+
+	"backend-grpc-server/internal/auth"
+	"backend-grpc-server/internal/database"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// requestIDHeader carries the ID that ties the audit events of a request
+// together. Clients may send their own, otherwise one is generated.
+const requestIDHeader = "x-request-id"
+
+// maxRequestIDLength matches the request_id column of audit_events
+const maxRequestIDLength = 64
+
+// withRequestAuditContext attributes the writes of an RPC to its caller. The
+// request ID is returned in the response header.
+func withRequestAuditContext(ctx context.Context, principal *auth.Principal, method string) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	requestID := firstMetadata(md, requestIDHeader)
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		requestID = uuid.New().String()
+	}
+
+	// Only fails outside of a real call, e.g. in tests
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, requestID))
+
+	audit := &database.AuditContext{
+		RequestID: requestID,
+		ClientIP:  clientIP(ctx),
+		Source:    method,
+	}
+	if principal != nil {
+		audit.ActorUserID = principal.UserID
+		audit.ActorAPIKeyID = principal.APIKeyID
+		audit.ImpersonatedBy = principal.ActorID
+	}
+
+	return database.WithAuditContext(ctx, audit)
+}
+
+// socketAuditContext attributes the writes of a socket event to the user the
+// client is subscribed as
+func socketAuditContext(client *SocketClient, event string) context.Context {
+	audit := &database.AuditContext{
+		RequestID: uuid.New().String(),
+		ClientIP:  client.IP,
+		Source:    "socket:" + event,
+	}
+	if client.UserID != nil {
+		audit.ActorUserID = *client.UserID
+	}
+
+	return database.WithAuditContext(context.Background(), audit)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"backend-grpc-server/internal/auth"
+	"backend-grpc-server/internal/database"
+	pb "backend-grpc-server/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestWithRequestAuditContext(t *testing.T) {
+	md := metadata.Pairs("x-request-id", "req-1", "x-real-ip", "203.0.113.7")
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	principal := &auth.Principal{UserID: 2, Role: "user", ActorID: 1, ImpersonationID: 5}
+
+	audit, ok := database.AuditContextFromContext(withRequestAuditContext(ctx, principal, pb.UserService_UpdateUser_FullMethodName))
+	require.True(t, ok)
+	assert.Equal(t, &database.AuditContext{
+		ActorUserID:    2,
+		ImpersonatedBy: 1,
+		RequestID:      "req-1",
+		ClientIP:       "203.0.113.7",
+		Source:         pb.UserService_UpdateUser_FullMethodName,
+	}, audit)
+
+	// Anonymous requests without an ID get a generated one
+	audit, ok = database.AuditContextFromContext(withRequestAuditContext(context.Background(), nil, pb.UserService_CreateUser_FullMethodName))
+	require.True(t, ok)
+	assert.Zero(t, audit.ActorUserID)
+	assert.Len(t, audit.RequestID, 36)
+
+	// Oversized IDs don't fit the log and are replaced
+	long := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", string(make([]byte, 65))))
+	audit, _ = database.AuditContextFromContext(withRequestAuditContext(long, nil, pb.UserService_CreateUser_FullMethodName))
+	assert.Len(t, audit.RequestID, 36)
+}
+
+func TestSocketAuditContext(t *testing.T) {
+	userID := int32(3)
+	client := &SocketClient{ID: "client", UserID: &userID, IP: "198.51.100.4"}
+
+	audit, ok := database.AuditContextFromContext(socketAuditContext(client, "mark_as_read"))
+	require.True(t, ok)
+	assert.Equal(t, int32(3), audit.ActorUserID)
+	assert.Equal(t, "socket:mark_as_read", audit.Source)
+	assert.Equal(t, "198.51.100.4", audit.ClientIP)
+	assert.NotEmpty(t, audit.RequestID)
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/pagination"
+	"backend-grpc-server/internal/storage"
+	pb "backend-grpc-server/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Audit log page sizes
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 500
+)
+
+// AuditHandler serves the audit log. Admin access is enforced by the
+// Authenticator.
+type AuditHandler struct {
+	pb.UnimplementedAuditServiceServer
+	store storage.AuditStore
+}
+
+// NewAuditHandler creates a new audit handler
+func NewAuditHandler(store storage.AuditStore) *AuditHandler {
+	return &AuditHandler{
+		store: store,
+	}
+}
+
+// QueryAuditLog returns the matching audit events, newest first
+func (h *AuditHandler) QueryAuditLog(ctx context.Context, req *pb.QueryAuditLogRequest) (*pb.QueryAuditLogResponse, error) {
+	since, err := parseOptionalTime(req.Since)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "since must be RFC3339: %v", err)
+	}
+	until, err := parseOptionalTime(req.Until)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "until must be RFC3339: %v", err)
+	}
+
+	cursor, err := pagination.Decode(req.PageToken)
+	if err != nil || (cursor != nil && !cursor.Descending) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid page token")
+	}
+
+	pageSize := req.PageSize
+	if pageSize == 0 {
+		pageSize = defaultAuditPageSize
+	}
+
+	filter := &models.AuditFilter{
+		ActorUserID:   req.ActorUserId,
+		ActorAPIKeyID: req.ActorApiKeyId,
+		TenantID:      req.TenantId,
+		Action:        req.Action,
+		TargetType:    req.TargetType,
+		TargetID:      req.TargetId,
+		RequestID:     req.RequestId,
+		Since:         since,
+		Until:         until,
+		Limit:         pageSize,
+		Cursor:        cursor,
+	}
+
+	if err := filter.Validate(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
+	}
+
+	// Fetch one extra row to find out whether there is a next page
+	filter.Limit++
+	events, err := h.store.QueryAuditEvents(filter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to query audit log: %v", err)
+	}
+
+	hasMore := len(events) > int(pageSize)
+	if hasMore {
+		events = events[:pageSize]
+	}
+
+	pbEvents := make([]*pb.AuditEvent, 0, len(events))
+	for _, event := range events {
+		pbEvents = append(pbEvents, convertToProtoAuditEvent(event))
+	}
+
+	var nextPageToken string
+	if hasMore {
+		last := events[len(events)-1]
+		nextPageToken = pagination.NextToken(true, last.CreatedAt, last.ID, true)
+	}
+
+	return &pb.QueryAuditLogResponse{
+		Events:        pbEvents,
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+func convertToProtoAuditEvent(event *models.AuditEvent) *pb.AuditEvent {
+	var actorUserID, actorAPIKeyID, impersonatedBy int32
+	if event.ActorUserID != nil {
+		actorUserID = *event.ActorUserID
+	}
+	if event.ActorAPIKeyID != nil {
+		actorAPIKeyID = *event.ActorAPIKeyID
+	}
+	if event.ImpersonatedBy != nil {
+		impersonatedBy = *event.ImpersonatedBy
+	}
+
+	return &pb.AuditEvent{
+		Id:             event.ID,
+		ActorUserId:    actorUserID,
+		ActorApiKeyId:  actorAPIKeyID,
+		ImpersonatedBy: impersonatedBy,
+		TenantId:       event.TenantID,
+		Action:         event.Action,
+		TargetType:     event.TargetType,
+		TargetId:       event.TargetID,
+		Before:         string(event.Before),
+		After:          string(event.After),
+		RequestId:      event.RequestID,
+		ClientIp:       event.ClientIP,
+		Source:         event.Source,
+		CreatedAt:      event.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
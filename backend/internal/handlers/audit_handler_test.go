@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"backend-grpc-server/internal/auth"
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/storage"
+	"backend-grpc-server/internal/testutil"
+	pb "backend-grpc-server/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuditHandler_QueryAuditLog(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	userStore := storage.NewPostgresUserStore(db)
+	signer := auth.NewSigner([]byte("secret"), 15*time.Minute)
+	authenticator := NewAuthenticator(storage.NewPostgresAPIKeyStore(db), storage.NewPostgresImpersonationStore(db), storage.NewPostgresAuthStore(db), signer)
+	userHandler := NewUserHandler(userStore, NewSocketHandler())
+	handler := NewAuditHandler(storage.NewPostgresAuditStore(db))
+
+	admin, err := userStore.CreateUser(&models.CreateUserParams{Name: "Admin", Email: "admin@example.com", Age: 40, Role: "admin"})
+	require.NoError(t, err)
+	token, _, err := signer.Issue(auth.Claims{UserID: admin.ID, Role: admin.Role}, time.Now())
+	require.NoError(t, err)
+
+	// An update made through the interceptor is attributed to the admin
+	md := metadata.Pairs("authorization", "Bearer "+token, "x-request-id", "audit-test-1", "x-real-ip", "203.0.113.7")
+	ctx, err := authenticator.authenticate(metadata.NewIncomingContext(context.Background(), md), pb.UserService_CreateUser_FullMethodName)
+	require.NoError(t, err)
+
+	created, err := userHandler.CreateUser(ctx, &pb.CreateUserRequest{Name: "Customer", Email: "customer@example.com", Age: 30, Role: "user"})
+	require.NoError(t, err)
+	_, err = userHandler.UpdateUser(ctx, &pb.UpdateUserRequest{Id: created.User.Id, Name: "Renamed", Email: "customer@example.com", Age: 30, Role: "user"})
+	require.NoError(t, err)
+
+	resp, err := handler.QueryAuditLog(context.Background(), &pb.QueryAuditLogRequest{RequestId: "audit-test-1"})
+	require.NoError(t, err)
+	require.Len(t, resp.Events, 2)
+
+	// Newest first
+	update, insert := resp.Events[0], resp.Events[1]
+	assert.Equal(t, "users.insert", insert.Action)
+	assert.Equal(t, "users.update", update.Action)
+	assert.Equal(t, "users", update.TargetType)
+	assert.Equal(t, fmt.Sprint(created.User.Id), update.TargetId)
+	assert.Equal(t, admin.ID, update.ActorUserId)
+	assert.Equal(t, "203.0.113.7", update.ClientIp)
+	assert.Equal(t, pb.UserService_CreateUser_FullMethodName, update.Source)
+	assert.Empty(t, insert.Before)
+
+	var before, after map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(update.Before), &before))
+	require.NoError(t, json.Unmarshal([]byte(update.After), &after))
+	assert.Equal(t, "Customer", before["name"])
+	assert.Equal(t, "Renamed", after["name"])
+	assert.NotContains(t, after, "email", "unchanged columns are left out")
+	assert.NotContains(t, insert.After, "password_hash")
+
+	// Changes without an audit context are still recorded, without an actor
+	_, err = userStore.CreateUser(&models.CreateUserParams{Name: "Background", Email: "background@example.com", Age: 30, Role: "user"})
+	require.NoError(t, err)
+
+	page, err := handler.QueryAuditLog(context.Background(), &pb.QueryAuditLogRequest{Action: "users.insert", PageSize: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Events, 2)
+	assert.Zero(t, page.Events[0].ActorUserId)
+	assert.Empty(t, page.Events[0].Source)
+	require.NotEmpty(t, page.NextPageToken)
+
+	next, err := handler.QueryAuditLog(context.Background(), &pb.QueryAuditLogRequest{Action: "users.insert", PageSize: 2, PageToken: page.NextPageToken})
+	require.NoError(t, err)
+	require.Len(t, next.Events, 1)
+	assert.Equal(t, "admin@example.com", jsonField(t, next.Events[0].After, "email"))
+	assert.Empty(t, next.NextPageToken)
+
+	// The log can't be changed
+	_, err = db.Exec(`UPDATE audit_events SET action = 'forged'`)
+	assert.Error(t, err)
+	_, err = db.Exec(`DELETE FROM audit_events`)
+	assert.Error(t, err)
+
+	_, err = handler.QueryAuditLog(context.Background(), &pb.QueryAuditLogRequest{Since: "yesterday"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = handler.QueryAuditLog(context.Background(), &pb.QueryAuditLogRequest{PageSize: 501})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// jsonField returns a top-level string field of a JSON object
+func jsonField(t *testing.T, data string, field string) string {
+	var object map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(data), &object))
+	value, _ := object[field].(string)
+	return value
+}
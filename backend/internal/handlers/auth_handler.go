@@ -0,0 +1,418 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"backend-grpc-server/internal/auth"
+	"backend-grpc-server/internal/mail"
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/oidc"
+	"backend-grpc-server/internal/ratelimit"
+	"backend-grpc-server/internal/storage"
+	pb "backend-grpc-server/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AuthHandler handles authentication-related gRPC requests
+type AuthHandler struct {
+	pb.UnimplementedAuthServiceServer
+	users         storage.UserStore
+	store         storage.AuthStore
+	verifications storage.EmailVerificationStore
+	mailer        mail.Sender
+	socketHandler *SocketHandler
+	signer        *auth.Signer
+	config        AuthConfig
+
+	// challenges signs the tokens between the password and the second factor
+	challenges *auth.Signer
+
+	// Single sign-on, nil unless an identity provider is configured
+	oidc       *oidc.Provider
+	identities storage.IdentityStore
+
+	// Password reset requests per address and per client IP
+	resetsByEmail *ratelimit.Limiter
+	resetsByIP    *ratelimit.Limiter
+	// Second factor attempts per user
+	twoFactorAttempts *ratelimit.Limiter
+	// Failed logins per address and per client IP
+	loginFailuresByEmail *ratelimit.Backoff
+	loginFailuresByIP    *ratelimit.Backoff
+
+	// Lockout warnings go out as notifications, nil until NotifyThrough
+	notifications *NotificationHandler
+}
+
+// AuthConfig holds the lifetimes and links of the auth handler
+type AuthConfig struct {
+	// BaseURL is the frontend the mailed links point to
+	BaseURL string
+	// RefreshTokenTTL is how long a login lasts without being refreshed
+	RefreshTokenTTL time.Duration
+	// PasswordResetValidity is how long a password reset link can be used
+	PasswordResetValidity time.Duration
+	// VerificationValidity is how long a verification link can be used
+	VerificationValidity time.Duration
+	// ResendInterval is the minimum time between two verification mails to a user
+	ResendInterval time.Duration
+	// TOTPIssuer names the service in authenticator apps
+	TOTPIssuer string
+	// OIDCProvision creates users for unknown single sign-on accounts
+	OIDCProvision bool
+	// OIDCDefaultRole is the role of users created by single sign-on
+	OIDCDefaultRole string
+	// LockoutThreshold is the number of failed logins in a row that lock an account
+	LockoutThreshold int32
+	// LockoutDuration is how long a locked account refuses logins
+	LockoutDuration time.Duration
+}
+
+// defaultTOTPIssuer is used when AuthConfig.TOTPIssuer is empty
+const defaultTOTPIssuer = "Backend"
+
+// Password reset rate limits
+const (
+	resetRequestsPerEmail = 3
+	resetRequestsPerIP    = 20
+	resetRequestWindow    = time.Hour
+)
+
+// Second factor limits. Six digit codes can't be guessed in five attempts.
+const (
+	twoFactorChallengeTTL = 5 * time.Minute
+	twoFactorAttempts     = 5
+)
+
+// NewAuthHandler creates a new auth handler
+func NewAuthHandler(users storage.UserStore, store storage.AuthStore, verifications storage.EmailVerificationStore, mailer mail.Sender, socketHandler *SocketHandler, signer *auth.Signer, config AuthConfig) *AuthHandler {
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	if config.TOTPIssuer == "" {
+		config.TOTPIssuer = defaultTOTPIssuer
+	}
+	if config.LockoutThreshold <= 0 {
+		config.LockoutThreshold = defaultLockoutThreshold
+	}
+	if config.LockoutDuration <= 0 {
+		config.LockoutDuration = defaultLockoutDuration
+	}
+
+	return &AuthHandler{
+		users:                users,
+		store:                store,
+		verifications:        verifications,
+		mailer:               mailer,
+		socketHandler:        socketHandler,
+		signer:               signer,
+		config:               config,
+		challenges:           signer.Derive("two-factor", twoFactorChallengeTTL),
+		resetsByEmail:        ratelimit.New(resetRequestsPerEmail, resetRequestWindow),
+		resetsByIP:           ratelimit.New(resetRequestsPerIP, resetRequestWindow),
+		twoFactorAttempts:    ratelimit.New(twoFactorAttempts, twoFactorChallengeTTL),
+		loginFailuresByEmail: ratelimit.NewBackoff(freeLoginFailuresPerEmail, loginDelayBase, loginDelayMax, loginFailureWindow),
+		loginFailuresByIP:    ratelimit.NewBackoff(freeLoginFailuresPerIP, loginDelayBase, loginDelayMax, loginFailureWindow),
+	}
+}
+
+// Login checks the password of a user and starts a new login. Failed logins
+// slow down the next ones and eventually lock the account.
+func (h *AuthHandler) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	now := time.Now()
+	ip := clientIP(ctx)
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	// Unknown addresses come back without a hash and look the same to the
+	// caller as wrong passwords
+	user, passwordHash, _ := h.store.GetUserCredentials(strings.TrimSpace(req.Email))
+	if err := h.checkLoginThrottle(ip, email, now); err != nil {
+		return nil, err
+	}
+	// Locked accounts fail like wrong passwords, so they don't give away that
+	// the address exists. Their owner learns about the lock by notification.
+	if !auth.CheckPassword(passwordHash, req.Password) || user.Locked(now) {
+		h.recordFailedLogin(ctx, ip, email, user, now)
+		return nil, status.Errorf(codes.Unauthenticated, "invalid email or password")
+	}
+	h.clearFailedLogins(ctx, email, user)
+
+	// The password alone is not enough, the client continues with VerifyTwoFactor
+	if user.TwoFactorEnabled {
+		challenge, _, err := h.challenges.Issue(auth.Claims{UserID: user.ID}, time.Now())
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "%v", err)
+		}
+		return &pb.LoginResponse{
+			TwoFactorRequired: true,
+			TwoFactorToken:    challenge,
+		}, nil
+	}
+
+	return h.startLogin(ctx, user)
+}
+
+// RefreshToken exchanges a refresh token for a new access and refresh token
+func (h *AuthHandler) RefreshToken(ctx context.Context, req *pb.RefreshTokenRequest) (*pb.LoginResponse, error) {
+	if req.RefreshToken == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "refresh_token is required")
+	}
+
+	refreshToken, tokenHash, err := auth.NewToken()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+
+	user, stored, err := h.store.WithContext(ctx).RotateRefreshToken(auth.HashToken(req.RefreshToken), tokenHash, time.Now().Add(h.config.RefreshTokenTTL), clientIP(ctx))
+	if err != nil {
+		if errors.Is(err, storage.ErrRefreshTokenInvalid) {
+			return nil, status.Errorf(codes.Unauthenticated, "%v", err)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to refresh token: %v", err)
+	}
+
+	return h.loginResponse(user, refreshToken, stored)
+}
+
+// Logout revokes a refresh token and ends its session, whose sockets are
+// closed. Access tokens of the session stop working with it.
+func (h *AuthHandler) Logout(ctx context.Context, req *pb.LogoutRequest) (*pb.LogoutResponse, error) {
+	sessionID, err := h.store.WithContext(ctx).RevokeRefreshToken(auth.HashToken(req.RefreshToken))
+	if err != nil {
+		return &pb.LogoutResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	if sessionID != 0 {
+		h.socketHandler.CloseSession(sessionID)
+	}
+
+	return &pb.LogoutResponse{
+		Success: true,
+		Message: "Logged out",
+	}, nil
+}
+
+// startLogin starts a session for the client making the request and returns
+// its refresh token with an access token
+func (h *AuthHandler) startLogin(ctx context.Context, user *models.User) (*pb.LoginResponse, error) {
+	refreshToken, tokenHash, err := auth.NewToken()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+
+	params := &models.StartSessionParams{
+		UserID:    user.ID,
+		UserAgent: models.TruncateUserAgent(userAgent(ctx)),
+		ClientIP:  clientIP(ctx),
+	}
+
+	_, stored, err := h.store.WithContext(ctx).StartSession(params, tokenHash, time.Now().Add(h.config.RefreshTokenTTL))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to log in: %v", err)
+	}
+
+	return h.loginResponse(user, refreshToken, stored)
+}
+
+// loginResponse issues an access token to go with a stored refresh token
+func (h *AuthHandler) loginResponse(user *models.User, refreshToken string, stored *models.RefreshToken) (*pb.LoginResponse, error) {
+	accessToken, expiresAt, err := h.signer.Issue(auth.Claims{UserID: user.ID, Role: user.Role, SessionID: stored.SessionID}, time.Now())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+
+	return &pb.LoginResponse{
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  expiresAt.Format("2006-01-02T15:04:05Z07:00"),
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: stored.ExpiresAt.Format("2006-01-02T15:04:05Z07:00"),
+		User:                  convertToProtoUser(user),
+	}, nil
+}
+
+// SendVerificationEmail mails a verification link to the current address of
+// the signed in user, or of any user for admins
+func (h *AuthHandler) SendVerificationEmail(ctx context.Context, req *pb.SendVerificationEmailRequest) (*pb.SendVerificationEmailResponse, error) {
+	userID, err := ownUserID(ctx, req.UserId, "verify email addresses")
+	if err != nil {
+		return nil, err
+	}
+
+	user, exists := h.users.GetUser(userID)
+	if !exists {
+		return nil, status.Errorf(codes.NotFound, "user with ID %d not found", userID)
+	}
+
+	if user.EmailVerified {
+		return &pb.SendVerificationEmailResponse{
+			Success: false,
+			Message: fmt.Sprintf("Email address of user %d is already verified", user.ID),
+		}, nil
+	}
+
+	params := &models.CreateEmailVerificationParams{
+		UserID:  user.ID,
+		Email:   user.Email,
+		Purpose: models.EmailVerify,
+	}
+	if err := h.sendVerification(params); err != nil {
+		return nil, err
+	}
+
+	return &pb.SendVerificationEmailResponse{
+		Success: true,
+		Message: fmt.Sprintf("Verification email sent to %s", user.Email),
+	}, nil
+}
+
+// RequestEmailChange mails a confirmation link to the new address. The
+// current address stays in place until the link is used. Users change their
+// own address, admins anyone's.
+func (h *AuthHandler) RequestEmailChange(ctx context.Context, req *pb.RequestEmailChangeRequest) (*pb.RequestEmailChangeResponse, error) {
+	userID, err := ownUserID(ctx, req.UserId, "change email addresses")
+	if err != nil {
+		return nil, err
+	}
+
+	user, exists := h.users.GetUser(userID)
+	if !exists {
+		return nil, status.Errorf(codes.NotFound, "user with ID %d not found", userID)
+	}
+
+	newEmail := strings.TrimSpace(req.NewEmail)
+	if strings.EqualFold(newEmail, user.Email) {
+		return nil, status.Errorf(codes.InvalidArgument, "new email must differ from the current one")
+	}
+
+	params := &models.CreateEmailVerificationParams{
+		UserID:  user.ID,
+		Email:   newEmail,
+		Purpose: models.EmailChange,
+	}
+	if err := h.sendVerification(params); err != nil {
+		return nil, err
+	}
+
+	// Let the owner of the current address know, in case they didn't ask for it
+	if err := h.mailer.Send(emailChangeNotice(user, newEmail)); err != nil {
+		log.Printf("Failed to notify user %d about email change: %v", user.ID, err)
+	}
+
+	return &pb.RequestEmailChangeResponse{
+		Success: true,
+		Message: fmt.Sprintf("Confirmation email sent to %s", newEmail),
+	}, nil
+}
+
+// VerifyEmail applies a verification or email change link
+func (h *AuthHandler) VerifyEmail(ctx context.Context, req *pb.VerifyEmailRequest) (*pb.VerifyEmailResponse, error) {
+	if req.Token == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "token is required")
+	}
+
+	user, _, err := h.verifications.WithContext(ctx).ConsumeEmailVerification(auth.HashToken(req.Token))
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrVerificationInvalid):
+			return nil, status.Errorf(codes.NotFound, "%v", err)
+		case errors.Is(err, storage.ErrEmailTaken):
+			return nil, status.Errorf(codes.AlreadyExists, "%v", err)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to verify email: %v", err)
+	}
+
+	// Send socket event about user update
+	h.socketHandler.WithContext(ctx).EmitToAll("user_updated", map[string]interface{}{
+		"id":             user.ID,
+		"name":           user.Name,
+		"email":          user.Email,
+		"age":            user.Age,
+		"role":           user.Role,
+		"email_verified": user.EmailVerified,
+	})
+
+	return &pb.VerifyEmailResponse{
+		User: convertToProtoUser(user),
+	}, nil
+}
+
+// sendVerification stores a verification for params and mails its link,
+// unless the user was mailed too recently
+func (h *AuthHandler) sendVerification(params *models.CreateEmailVerificationParams) error {
+	lastSent, err := h.verifications.LastEmailVerificationSent(params.UserID)
+	if err != nil {
+		return status.Errorf(codes.Internal, "%v", err)
+	}
+	if lastSent != nil {
+		if wait := h.config.ResendInterval - time.Since(*lastSent); wait > 0 {
+			return status.Errorf(codes.ResourceExhausted, "please wait %s before requesting another email", wait.Round(time.Second))
+		}
+	}
+
+	params.ExpiresAt = time.Now().Add(h.config.VerificationValidity)
+	if err := params.Validate(); err != nil {
+		return status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
+	}
+
+	token, tokenHash, err := auth.NewToken()
+	if err != nil {
+		return status.Errorf(codes.Internal, "%v", err)
+	}
+
+	verification, err := h.verifications.CreateEmailVerification(params, tokenHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrEmailTaken) {
+			return status.Errorf(codes.AlreadyExists, "%v", err)
+		}
+		return status.Errorf(codes.Internal, "failed to create verification: %v", err)
+	}
+
+	if err := h.mailer.Send(h.verificationMessage(verification, token)); err != nil {
+		return status.Errorf(codes.Unavailable, "failed to send verification email: %v", err)
+	}
+
+	return nil
+}
+
+// verificationMessage builds the email with the verification link
+func (h *AuthHandler) verificationMessage(verification *models.EmailVerification, token string) mail.Message {
+	link := fmt.Sprintf("%s/verify-email?token=%s", h.config.BaseURL, url.QueryEscape(token))
+
+	subject := "Verify your email address"
+	intro := "Please confirm that this is your email address:"
+	if verification.Purpose == models.EmailChange {
+		subject = "Confirm your new email address"
+		intro = "Please confirm this address to make it the new email address of your account:"
+	}
+
+	return mail.Message{
+		To:      verification.Email,
+		Subject: subject,
+		Body: fmt.Sprintf(
+			"%s\n%s\n\nThe link expires on %s.\n",
+			intro,
+			link,
+			verification.ExpiresAt.Format("2006-01-02 15:04 MST"),
+		),
+	}
+}
+
+// emailChangeNotice tells the current address that a change was requested
+func emailChangeNotice(user *models.User, newEmail string) mail.Message {
+	return mail.Message{
+		To:      user.Email,
+		Subject: "Email change requested",
+		Body: fmt.Sprintf(
+			"A change of your email address to %s was requested. Your address stays the same until the new one is confirmed.\n\nIf this wasn't you, you can ignore the confirmation and contact an administrator.\n",
+			newEmail,
+		),
+	}
+}
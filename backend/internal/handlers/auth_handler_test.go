@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend-grpc-server/internal/auth"
+	"backend-grpc-server/internal/database"
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/storage"
+	"backend-grpc-server/internal/testutil"
+	pb "backend-grpc-server/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// newTestAuthHandler creates an auth handler with the default lifetimes
+func newTestAuthHandler(db *database.DB, sender *recordingSender, resendInterval time.Duration) *AuthHandler {
+	return NewAuthHandler(
+		storage.NewPostgresUserStore(db),
+		storage.NewPostgresAuthStore(db),
+		storage.NewPostgresEmailVerificationStore(db),
+		sender,
+		NewSocketHandler(),
+		auth.NewSigner([]byte("test secret"), 15*time.Minute),
+		AuthConfig{
+			BaseURL:               "http://localhost:3000/",
+			RefreshTokenTTL:       24 * time.Hour,
+			PasswordResetValidity: time.Hour,
+			VerificationValidity:  24 * time.Hour,
+			ResendInterval:        resendInterval,
+		},
+	)
+}
+
+func TestAuthHandler_PasswordResetAndLogin(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	userStore := storage.NewPostgresUserStore(db)
+	sender := &recordingSender{}
+	handler := newTestAuthHandler(db, sender, 0)
+	ctx := context.Background()
+
+	user, err := userStore.CreateUser(&models.CreateUserParams{Name: "Forgetful", Email: "forgetful@example.com", Age: 30, Role: "user"})
+	require.NoError(t, err)
+
+	// Users created by an admin have no password yet
+	_, err = handler.Login(ctx, &pb.LoginRequest{Email: "forgetful@example.com", Password: "correct horse"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// Unknown addresses get the same answer, but no mail
+	unknown, err := handler.RequestPasswordReset(ctx, &pb.RequestPasswordResetRequest{Email: "nobody@example.com"})
+	require.NoError(t, err)
+	requested, err := handler.RequestPasswordReset(ctx, &pb.RequestPasswordResetRequest{Email: "Forgetful@Example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, unknown, requested)
+	require.Len(t, sender.sent, 1)
+	assert.Equal(t, "forgetful@example.com", sender.sent[0].To)
+	assert.Contains(t, sender.sent[0].Body, "http://localhost:3000/reset-password?token=")
+
+	_, err = handler.ResetPassword(ctx, &pb.ResetPasswordRequest{Token: tokenFromMessage(t, sender.sent[0]), NewPassword: "short"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	reset, err := handler.ResetPassword(ctx, &pb.ResetPasswordRequest{Token: tokenFromMessage(t, sender.sent[0]), NewPassword: "correct horse"})
+	require.NoError(t, err)
+	assert.True(t, reset.Success)
+
+	// Reset links work once
+	_, err = handler.ResetPassword(ctx, &pb.ResetPasswordRequest{Token: tokenFromMessage(t, sender.sent[0]), NewPassword: "correct horse"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = handler.Login(ctx, &pb.LoginRequest{Email: "forgetful@example.com", Password: "wrong horse"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	login, err := handler.Login(ctx, &pb.LoginRequest{Email: "forgetful@example.com", Password: "correct horse"})
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, login.User.Id)
+	assert.NotEmpty(t, login.AccessToken)
+
+	// Refresh tokens are rotated on use
+	refreshed, err := handler.RefreshToken(ctx, &pb.RefreshTokenRequest{RefreshToken: login.RefreshToken})
+	require.NoError(t, err)
+	_, err = handler.RefreshToken(ctx, &pb.RefreshTokenRequest{RefreshToken: login.RefreshToken})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// Another reset signs out the existing login
+	_, err = handler.RequestPasswordReset(ctx, &pb.RequestPasswordResetRequest{Email: "forgetful@example.com"})
+	require.NoError(t, err)
+	_, err = handler.ResetPassword(ctx, &pb.ResetPasswordRequest{Token: tokenFromMessage(t, sender.sent[len(sender.sent)-1]), NewPassword: "battery staple"})
+	require.NoError(t, err)
+	_, err = handler.RefreshToken(ctx, &pb.RefreshTokenRequest{RefreshToken: refreshed.RefreshToken})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// The third request for an address within the hour is the last one allowed
+	_, err = handler.RequestPasswordReset(ctx, &pb.RequestPasswordResetRequest{Email: "FORGETFUL@example.com"})
+	require.NoError(t, err)
+	_, err = handler.RequestPasswordReset(ctx, &pb.RequestPasswordResetRequest{Email: "forgetful@example.com"})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestAuthHandler_VerifyEmail(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	userStore := storage.NewPostgresUserStore(db)
+	sender := &recordingSender{}
+	handler := newTestAuthHandler(db, sender, time.Hour)
+	ctx := context.Background()
+
+	user, err := userStore.CreateUser(&models.CreateUserParams{Name: "Verify Me", Email: "verify@example.com", Age: 30, Role: "user"})
+	require.NoError(t, err)
+	assert.False(t, user.EmailVerified)
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{UserID: user.ID, Role: "user"})
+
+	sent, err := handler.SendVerificationEmail(ctx, &pb.SendVerificationEmailRequest{})
+	require.NoError(t, err)
+	assert.True(t, sent.Success)
+	require.Len(t, sender.sent, 1)
+	assert.Equal(t, "verify@example.com", sender.sent[0].To)
+	assert.Contains(t, sender.sent[0].Body, "http://localhost:3000/verify-email?token=")
+
+	// Resending is throttled
+	_, err = handler.SendVerificationEmail(ctx, &pb.SendVerificationEmailRequest{UserId: user.ID})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Len(t, sender.sent, 1)
+
+	verified, err := handler.VerifyEmail(ctx, &pb.VerifyEmailRequest{Token: tokenFromMessage(t, sender.sent[0])})
+	require.NoError(t, err)
+	assert.True(t, verified.User.EmailVerified)
+
+	// Tokens work once
+	_, err = handler.VerifyEmail(ctx, &pb.VerifyEmailRequest{Token: tokenFromMessage(t, sender.sent[0])})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// Changing the address directly requires verifying it again
+	updated, err := userStore.UpdateUser(&models.UpdateUserParams{ID: user.ID, Email: "other@example.com", Fields: []string{"email"}})
+	require.NoError(t, err)
+	assert.False(t, updated.EmailVerified)
+}
+
+func TestAuthHandler_ChangeEmail(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	userStore := storage.NewPostgresUserStore(db)
+	sender := &recordingSender{}
+	handler := newTestAuthHandler(db, sender, 0)
+	ctx := context.Background()
+
+	user, err := userStore.CreateUser(&models.CreateUserParams{Name: "Mover", Email: "old@example.com", Age: 30, Role: "user"})
+	require.NoError(t, err)
+	taken, err := userStore.CreateUser(&models.CreateUserParams{Name: "Taken", Email: "taken@example.com", Age: 30, Role: "user"})
+	require.NoError(t, err)
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{UserID: user.ID, Role: "user"})
+
+	// Only admins ask for other users
+	_, err = handler.RequestEmailChange(ctx, &pb.RequestEmailChangeRequest{UserId: taken.ID, NewEmail: "mine@example.com"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = handler.RequestEmailChange(context.Background(), &pb.RequestEmailChangeRequest{UserId: user.ID, NewEmail: "mine@example.com"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Empty(t, sender.sent)
+
+	// Addresses are compared regardless of case
+	_, err = handler.RequestEmailChange(ctx, &pb.RequestEmailChangeRequest{UserId: user.ID, NewEmail: "Taken@Example.com"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	_, err = handler.RequestEmailChange(ctx, &pb.RequestEmailChangeRequest{UserId: user.ID, NewEmail: "OLD@example.com"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	requested, err := handler.RequestEmailChange(ctx, &pb.RequestEmailChangeRequest{UserId: user.ID, NewEmail: "new@example.com"})
+	require.NoError(t, err)
+	assert.True(t, requested.Success)
+	require.Len(t, sender.sent, 2)
+	assert.Equal(t, "new@example.com", sender.sent[0].To)
+	assert.Equal(t, "old@example.com", sender.sent[1].To)
+
+	// The address only changes once confirmed
+	current, exists := userStore.GetUser(user.ID)
+	require.True(t, exists)
+	assert.Equal(t, "old@example.com", current.Email)
+
+	changed, err := handler.VerifyEmail(ctx, &pb.VerifyEmailRequest{Token: tokenFromMessage(t, sender.sent[0])})
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", changed.User.Email)
+	assert.True(t, changed.User.EmailVerified)
+}
+
+func TestAuthHandler_TwoFactor(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	userStore := storage.NewPostgresUserStore(db)
+	sender := &recordingSender{}
+	handler := newTestAuthHandler(db, sender, 0)
+	ctx := context.Background()
+
+	user, err := userStore.CreateUser(&models.CreateUserParams{Name: "Careful", Email: "careful@example.com", Age: 30, Role: "admin"})
+	require.NoError(t, err)
+	passwordHash, err := auth.HashPassword("correct horse")
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE users SET password_hash = $2 WHERE id = $1`, user.ID, passwordHash)
+	require.NoError(t, err)
+
+	// Users enroll themselves
+	userCtx := auth.WithPrincipal(ctx, &auth.Principal{UserID: user.ID, Role: "user"})
+	enrollment, err := handler.StartTOTPEnrollment(userCtx, &pb.StartTOTPEnrollmentRequest{})
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.OtpauthUri, "secret="+enrollment.Secret)
+
+	_, err = handler.ConfirmTOTPEnrollment(userCtx, &pb.ConfirmTOTPEnrollmentRequest{UserId: user.ID, Code: "000000"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	step := auth.TOTPStep(time.Now())
+	code, err := auth.TOTPCode(enrollment.Secret, step)
+	require.NoError(t, err)
+	confirmed, err := handler.ConfirmTOTPEnrollment(userCtx, &pb.ConfirmTOTPEnrollmentRequest{UserId: user.ID, Code: code})
+	require.NoError(t, err)
+	require.Len(t, confirmed.RecoveryCodes, auth.RecoveryCodeCount)
+
+	_, err = handler.StartTOTPEnrollment(userCtx, &pb.StartTOTPEnrollmentRequest{UserId: user.ID})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// The password alone only gets a challenge
+	login, err := handler.Login(ctx, &pb.LoginRequest{Email: "careful@example.com", Password: "correct horse"})
+	require.NoError(t, err)
+	assert.True(t, login.TwoFactorRequired)
+	assert.Empty(t, login.AccessToken)
+
+	// The code used for enrollment can't be replayed
+	_, err = handler.VerifyTwoFactor(ctx, &pb.VerifyTwoFactorRequest{TwoFactorToken: login.TwoFactorToken, Code: code})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	nextCode, err := auth.TOTPCode(enrollment.Secret, step+1)
+	require.NoError(t, err)
+	verified, err := handler.VerifyTwoFactor(ctx, &pb.VerifyTwoFactorRequest{TwoFactorToken: login.TwoFactorToken, Code: nextCode})
+	require.NoError(t, err)
+	assert.NotEmpty(t, verified.AccessToken)
+	assert.True(t, verified.User.TwoFactorEnabled)
+
+	// Recovery codes work once
+	verified, err = handler.VerifyTwoFactor(ctx, &pb.VerifyTwoFactorRequest{TwoFactorToken: login.TwoFactorToken, Code: confirmed.RecoveryCodes[0]})
+	require.NoError(t, err)
+	assert.NotEmpty(t, verified.AccessToken)
+	_, err = handler.VerifyTwoFactor(ctx, &pb.VerifyTwoFactorRequest{TwoFactorToken: login.TwoFactorToken, Code: confirmed.RecoveryCodes[0]})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = handler.VerifyTwoFactor(ctx, &pb.VerifyTwoFactorRequest{TwoFactorToken: "forged", Code: nextCode})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	reset, err := handler.ResetTwoFactor(ctx, &pb.ResetTwoFactorRequest{UserId: user.ID})
+	require.NoError(t, err)
+	assert.True(t, reset.Success)
+	require.NotEmpty(t, sender.sent)
+	assert.Equal(t, "careful@example.com", sender.sent[len(sender.sent)-1].To)
+
+	login, err = handler.Login(ctx, &pb.LoginRequest{Email: "careful@example.com", Password: "correct horse"})
+	require.NoError(t, err)
+	assert.False(t, login.TwoFactorRequired)
+	assert.NotEmpty(t, login.AccessToken)
+}
+
+func TestAuthHandler_TwoFactorEnrollmentPrincipal(t *testing.T) {
+	handler := &AuthHandler{}
+
+	_, err := handler.StartTOTPEnrollment(context.Background(), &pb.StartTOTPEnrollmentRequest{UserId: 1})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = handler.ConfirmTOTPEnrollment(context.Background(), &pb.ConfirmTOTPEnrollmentRequest{UserId: 1, Code: "123456"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	apiKeyCtx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, APIKeyID: 3})
+	_, err = handler.StartTOTPEnrollment(apiKeyCtx, &pb.StartTOTPEnrollmentRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	impersonatorCtx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 2, Role: "user", ActorID: 1, ImpersonationID: 5})
+	_, err = handler.StartTOTPEnrollment(impersonatorCtx, &pb.StartTOTPEnrollmentRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	userCtx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 2, Role: "user"})
+	_, err = handler.StartTOTPEnrollment(userCtx, &pb.StartTOTPEnrollmentRequest{UserId: 1})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = handler.ConfirmTOTPEnrollment(userCtx, &pb.ConfirmTOTPEnrollmentRequest{UserId: 1, Code: "123456"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestAuthHandler_EmailPrincipal(t *testing.T) {
+	handler := &AuthHandler{}
+
+	_, err := handler.SendVerificationEmail(context.Background(), &pb.SendVerificationEmailRequest{UserId: 1})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = handler.RequestEmailChange(context.Background(), &pb.RequestEmailChangeRequest{UserId: 1, NewEmail: "attacker@example.com"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	userCtx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 2, Role: "user"})
+	_, err = handler.SendVerificationEmail(userCtx, &pb.SendVerificationEmailRequest{UserId: 1})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = handler.RequestEmailChange(userCtx, &pb.RequestEmailChangeRequest{UserId: 1, NewEmail: "attacker@example.com"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestAuthHandler_Sessions(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	userStore := storage.NewPostgresUserStore(db)
+	handler := newTestAuthHandler(db, &recordingSender{}, 0)
+
+	user, err := userStore.CreateUser(&models.CreateUserParams{Name: "Traveller", Email: "traveller@example.com", Age: 30, Role: "user"})
+	require.NoError(t, err)
+	other, err := userStore.CreateUser(&models.CreateUserParams{Name: "Other", Email: "other@example.com", Age: 30, Role: "user"})
+	require.NoError(t, err)
+	passwordHash, err := auth.HashPassword("correct horse")
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE users SET password_hash = $2 WHERE id = $1`, user.ID, passwordHash)
+	require.NoError(t, err)
+
+	// Every login starts a session named after the device it came from
+	laptopCtx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"user-agent", "Mozilla/5.0 (X11; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0",
+		"x-real-ip", "192.0.2.1",
+	))
+	laptop, err := handler.Login(laptopCtx, &pb.LoginRequest{Email: "traveller@example.com", Password: "correct horse"})
+	require.NoError(t, err)
+	phone, err := handler.Login(context.Background(), &pb.LoginRequest{Email: "traveller@example.com", Password: "correct horse"})
+	require.NoError(t, err)
+
+	claims, err := handler.signer.Verify(laptop.AccessToken, time.Now())
+	require.NoError(t, err)
+	require.NotZero(t, claims.SessionID)
+
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: user.ID, Role: "user", SessionID: claims.SessionID})
+	listed, err := handler.ListSessions(ctx, &pb.ListSessionsRequest{})
+	require.NoError(t, err)
+	require.Len(t, listed.Sessions, 2)
+	var current *pb.Session
+	for _, session := range listed.Sessions {
+		if session.Current {
+			current = session
+		}
+	}
+	require.NotNil(t, current)
+	assert.Equal(t, claims.SessionID, current.Id)
+	assert.Equal(t, "Firefox on Linux", current.Device)
+	assert.Equal(t, "192.0.2.1", current.ClientIp)
+	assert.Equal(t, models.SessionActive, current.Status)
+
+	// Refreshing keeps the session
+	refreshed, err := handler.RefreshToken(laptopCtx, &pb.RefreshTokenRequest{RefreshToken: laptop.RefreshToken})
+	require.NoError(t, err)
+	refreshedClaims, err := handler.signer.Verify(refreshed.AccessToken, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, claims.SessionID, refreshedClaims.SessionID)
+
+	// Users can't see or revoke the sessions of others
+	otherCtx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: other.ID, Role: "user"})
+	_, err = handler.ListSessions(otherCtx, &pb.ListSessionsRequest{UserId: user.ID})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	revoked, err := handler.RevokeSession(otherCtx, &pb.RevokeSessionRequest{Id: claims.SessionID})
+	require.NoError(t, err)
+	assert.False(t, revoked.Success)
+	_, err = handler.ListSessions(context.Background(), &pb.ListSessionsRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// A revoked session can't be refreshed any more
+	revoked, err = handler.RevokeSession(ctx, &pb.RevokeSessionRequest{Id: claims.SessionID})
+	require.NoError(t, err)
+	assert.True(t, revoked.Success)
+	_, err = handler.RefreshToken(laptopCtx, &pb.RefreshTokenRequest{RefreshToken: refreshed.RefreshToken})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = handler.RefreshToken(context.Background(), &pb.RefreshTokenRequest{RefreshToken: phone.RefreshToken})
+	require.NoError(t, err)
+
+	listed, err = handler.ListSessions(ctx, &pb.ListSessionsRequest{})
+	require.NoError(t, err)
+	assert.Len(t, listed.Sessions, 1)
+
+	// Admins see every session of a user, including ended ones
+	adminCtx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: other.ID, Role: "admin"})
+	listed, err = handler.ListSessions(adminCtx, &pb.ListSessionsRequest{UserId: user.ID, IncludeInactive: true})
+	require.NoError(t, err)
+	assert.Len(t, listed.Sessions, 2)
+}
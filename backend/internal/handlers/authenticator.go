@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend-grpc-server/internal/auth"
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/storage"
+	pb "backend-grpc-server/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// apiKeyScopes maps the methods integrations may call to the scope their API
+// key needs. Every other method is closed to API keys.
+var apiKeyScopes = map[string]string{
+	pb.NotificationService_CreateNotification_FullMethodName:       models.ScopeNotificationsSend,
+	pb.NotificationService_BatchCreateNotifications_FullMethodName: models.ScopeNotificationsSend,
+	pb.NotificationService_SendRealtimeNotification_FullMethodName: models.ScopeNotificationsSend,
+	pb.NotificationService_GetNotification_FullMethodName:          models.ScopeNotificationsRead,
+	pb.NotificationService_ListNotifications_FullMethodName:        models.ScopeNotificationsRead,
+	pb.NotificationService_SearchNotifications_FullMethodName:      models.ScopeNotificationsRead,
+	pb.NotificationService_GetNotificationStats_FullMethodName:     models.ScopeNotificationsRead,
+	pb.UserService_GetUser_FullMethodName:                          models.ScopeUsersRead,
+	pb.UserService_ListUsers_FullMethodName:                        models.ScopeUsersRead,
+	pb.UserService_ExportUsers_FullMethodName:                      models.ScopeUsersRead,
+	pb.UserService_CreateUser_FullMethodName:                       models.ScopeUsersWrite,
+	pb.UserService_UpdateUser_FullMethodName:                       models.ScopeUsersWrite,
+	pb.UserService_DeleteUser_FullMethodName:                       models.ScopeUsersWrite,
+	pb.UserService_ImportUsers_FullMethodName:                      models.ScopeUsersWrite,
+}
+
+// adminMethods can only be called by signed in admins
+var adminMethods = map[string]bool{
+	pb.APIKeyService_CreateAPIKey_FullMethodName:              true,
+	pb.APIKeyService_ListAPIKeys_FullMethodName:               true,
+	pb.APIKeyService_RevokeAPIKey_FullMethodName:              true,
+	pb.ImpersonationService_Impersonate_FullMethodName:        true,
+	pb.ImpersonationService_ListImpersonations_FullMethodName: true,
+	pb.AuditService_QueryAuditLog_FullMethodName:              true,
+	pb.AuthService_UnlockAccount_FullMethodName:               true,
+	pb.AuthService_ResetTwoFactor_FullMethodName:              true,
+	pb.PrivacyService_EraseUserData_FullMethodName:            true,
+	pb.PrivacyService_ListErasureCertificates_FullMethodName:  true,
+	pb.PrivacyService_GetErasureCertificate_FullMethodName:    true,
+	pb.UserService_RestoreUser_FullMethodName:                 true,
+	pb.InvitationService_InviteUser_FullMethodName:            true,
+	pb.InvitationService_RevokeInvitation_FullMethodName:      true,
+	pb.InvitationService_ListInvitations_FullMethodName:       true,
+}
+
+// signedInMethods change users and can't be called anonymously. Admins, users
+// and API keys with the users:write scope may call them, the handlers keep
+// setting roles to admins.
+var signedInMethods = map[string]bool{
+	pb.UserService_CreateUser_FullMethodName:  true,
+	pb.UserService_UpdateUser_FullMethodName:  true,
+	pb.UserService_DeleteUser_FullMethodName:  true,
+	pb.UserService_ImportUsers_FullMethodName: true,
+}
+
+// impersonationClosedServices can't be called while impersonating, so admins
+// can't change the credentials of a user or erase their data as them
+var impersonationClosedServices = []string{
+	"/auth.AuthService/",
+	"/privacy.PrivacyService/",
+}
+
+// errSessionEnded is returned for access tokens whose session was revoked or expired
+var errSessionEnded = status.Error(codes.Unauthenticated, "session has been revoked or has expired, log in again")
+
+// impersonationHeader tells clients the response was made for an impersonating admin
+const impersonationHeader = "x-impersonated-by"
+
+// errInvalidAPIKey doesn't tell unknown, revoked and expired keys apart
+var errInvalidAPIKey = status.Error(codes.Unauthenticated, "API key is invalid, expired or revoked")
+
+// Authenticator resolves who makes a request from its metadata, either an API
+// key in x-api-key or an access token in the authorization header, and puts
+// the principal into the context. Requests without credentials stay anonymous,
+// as most of the API doesn't require a login yet, but admin methods do.
+// Access tokens of revoked or expired sessions are turned away. Requests
+// made with an impersonation token are logged. Every request gets an audit
+// context, so the changes it makes are attributed to it.
+type Authenticator struct {
+	keys           storage.APIKeyStore
+	impersonations storage.ImpersonationStore
+	sessions       storage.AuthStore
+	signer         *auth.Signer
+}
+
+// NewAuthenticator creates an authenticator verifying access tokens with
+// signer and the sessions they were issued for with sessions
+func NewAuthenticator(keys storage.APIKeyStore, impersonations storage.ImpersonationStore, sessions storage.AuthStore, signer *auth.Signer) *Authenticator {
+	return &Authenticator{
+		keys:           keys,
+		impersonations: impersonations,
+		sessions:       sessions,
+		signer:         signer,
+	}
+}
+
+// UnaryInterceptor authenticates unary calls
+func (a *Authenticator) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := a.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor authenticates streaming calls
+func (a *Authenticator) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authenticate(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// authenticate returns the context with the principal of the request, or an
+// error if the credentials are invalid or don't allow calling the method
+func (a *Authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	var principal *auth.Principal
+	var err error
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if key := firstMetadata(md, "x-api-key"); key != "" {
+		principal, err = a.apiKeyPrincipal(key)
+	} else if header := firstMetadata(md, "authorization"); header != "" {
+		principal, err = a.userPrincipal(header)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorize(principal, method); err != nil {
+		return nil, err
+	}
+
+	ctx = withRequestAuditContext(ctx, principal, method)
+	if principal == nil {
+		return ctx, nil
+	}
+	if principal.Impersonating() {
+		if err := a.logImpersonatedRequest(ctx, principal, method); err != nil {
+			return nil, err
+		}
+	}
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+// logImpersonatedRequest records the request in the impersonation log and
+// marks the response, or fails once the impersonation has ended
+func (a *Authenticator) logImpersonatedRequest(ctx context.Context, principal *auth.Principal, method string) error {
+	if err := a.impersonations.LogImpersonatedRequest(principal.ImpersonationID, method); err != nil {
+		if errors.Is(err, storage.ErrImpersonationEnded) {
+			return status.Errorf(codes.Unauthenticated, "%v", err)
+		}
+		return status.Errorf(codes.Internal, "%v", err)
+	}
+
+	// Only fails outside of a real call, e.g. in tests
+	grpc.SetHeader(ctx, metadata.Pairs(impersonationHeader, strconv.Itoa(int(principal.ActorID))))
+	return nil
+}
+
+// apiKeyPrincipal looks the key up by its prefix and compares the hash
+func (a *Authenticator) apiKeyPrincipal(key string) (*auth.Principal, error) {
+	prefix, ok := auth.APIKeyPrefix(key)
+	if !ok {
+		return nil, errInvalidAPIKey
+	}
+
+	stored, keyHash, found := a.keys.GetAPIKeyByPrefix(prefix)
+	if !found || subtle.ConstantTimeCompare([]byte(keyHash), []byte(auth.HashToken(key))) != 1 {
+		return nil, errInvalidAPIKey
+	}
+	if stored.Status(time.Now()) != models.APIKeyActive {
+		return nil, errInvalidAPIKey
+	}
+
+	if err := a.keys.TouchAPIKey(stored.ID); err != nil {
+		log.Printf("Failed to record use of API key %d: %v", stored.ID, err)
+	}
+
+	return &auth.Principal{
+		APIKeyID: stored.ID,
+		Scopes:   stored.Scopes,
+	}, nil
+}
+
+// userPrincipal verifies a bearer access token
+func (a *Authenticator) userPrincipal(header string) (*auth.Principal, error) {
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "bearer") {
+		return nil, status.Errorf(codes.Unauthenticated, "authorization must be a bearer token")
+	}
+
+	now := time.Now()
+	claims, err := a.signer.Verify(strings.TrimSpace(token), now)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "%v", err)
+	}
+	if !sessionActive(a.sessions, claims.SessionID, now) {
+		return nil, errSessionEnded
+	}
+
+	principal := &auth.Principal{
+		UserID:    claims.UserID,
+		Role:      claims.Role,
+		SessionID: claims.SessionID,
+	}
+	if claims.Actor != nil {
+		principal.ActorID = claims.Actor.UserID
+		principal.ImpersonationID = claims.Actor.ImpersonationID
+	}
+	return principal, nil
+}
+
+// authorize checks that the principal may call the method. A nil principal
+// is an anonymous request.
+func authorize(principal *auth.Principal, method string) error {
+	if adminMethods[method] {
+		if err := checkAdmin(principal, "call "+method); err != nil {
+			return err
+		}
+	}
+	if signedInMethods[method] && principal == nil {
+		return status.Errorf(codes.Unauthenticated, "sign in to call %s", method)
+	}
+
+	if principal != nil && principal.Impersonating() {
+		for _, service := range impersonationClosedServices {
+			if strings.HasPrefix(method, service) {
+				return status.Errorf(codes.PermissionDenied, "%s cannot be called while impersonating", method)
+			}
+		}
+	}
+
+	if principal != nil && principal.IsAPIKey() {
+		scope, ok := apiKeyScopes[method]
+		if !ok {
+			return status.Errorf(codes.PermissionDenied, "API keys cannot call %s", method)
+		}
+		if !principal.HasScope(scope) {
+			return status.Errorf(codes.PermissionDenied, "API key lacks the %s scope", scope)
+		}
+	}
+
+	return nil
+}
+
+// sessionActive reports whether the session an access token was issued for
+// may still be used. Tokens issued without a session have nothing to check.
+func sessionActive(sessions storage.AuthStore, sessionID int32, now time.Time) bool {
+	if sessionID == 0 {
+		return true
+	}
+	session, exists := sessions.GetSession(sessionID)
+	return exists && session.Status(now) == models.SessionActive
+}
+
+// checkAdmin refuses anyone but a signed in admin acting as themselves. The
+// action completes "only admins can ..." in the error.
+func checkAdmin(principal *auth.Principal, action string) error {
+	if principal == nil {
+		return status.Errorf(codes.Unauthenticated, "sign in as an admin to %s", action)
+	}
+	if principal.IsAPIKey() || principal.Impersonating() || principal.Role != "admin" {
+		return status.Errorf(codes.PermissionDenied, "only admins can %s", action)
+	}
+	return nil
+}
+
+// ownUserID picks the user a request about a single account is for: the
+// signed in user if userID is 0, or any user for admins. API keys and
+// impersonators are refused. The action completes "sign in to ..." in the error.
+func ownUserID(ctx context.Context, userID int32, action string) (int32, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok || principal.IsAPIKey() || principal.Impersonating() {
+		return 0, status.Errorf(codes.Unauthenticated, "sign in to %s", action)
+	}
+
+	if userID == 0 {
+		return principal.UserID, nil
+	}
+	if userID != principal.UserID && principal.Role != "admin" {
+		return 0, status.Errorf(codes.PermissionDenied, "only admins can %s for other users", action)
+	}
+	return userID, nil
+}
+
+// isAdmin reports whether the request was made by a signed in admin acting as themselves
+func isAdmin(ctx context.Context) bool {
+	return requireAdmin(ctx, "") == nil
+}
+
+// requireAdmin is checkAdmin for the principal of a request, for handlers
+// with admin-only options
+func requireAdmin(ctx context.Context, action string) error {
+	principal, _ := auth.PrincipalFromContext(ctx)
+	return checkAdmin(principal, action)
+}
+
+// firstMetadata returns the first value of a metadata key, or an empty string
+func firstMetadata(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// authenticatedStream replaces the context of a stream with the authenticated one
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
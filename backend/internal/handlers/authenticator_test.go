@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend-grpc-server/internal/auth"
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/storage"
+	pb "backend-grpc-server/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// memoryAPIKeyStore keeps API keys in memory
+type memoryAPIKeyStore struct {
+	storage.APIKeyStore
+	keys    map[string]*models.APIKey
+	hashes  map[string]string
+	touched []int32
+}
+
+func (s *memoryAPIKeyStore) add(t *testing.T, key *models.APIKey) string {
+	secret, prefix, hash, err := auth.NewAPIKey()
+	require.NoError(t, err)
+	key.ID = int32(len(s.keys) + 1)
+	key.Prefix = prefix
+	s.keys[prefix] = key
+	s.hashes[prefix] = hash
+	return secret
+}
+
+func (s *memoryAPIKeyStore) GetAPIKeyByPrefix(prefix string) (*models.APIKey, string, bool) {
+	key, ok := s.keys[prefix]
+	return key, s.hashes[prefix], ok
+}
+
+func (s *memoryAPIKeyStore) TouchAPIKey(id int32) error {
+	s.touched = append(s.touched, id)
+	return nil
+}
+
+// memoryImpersonationStore keeps the impersonation log in memory
+type memoryImpersonationStore struct {
+	storage.ImpersonationStore
+	ended  map[int32]bool
+	logged map[int32][]string
+}
+
+func (s *memoryImpersonationStore) LogImpersonatedRequest(id int32, method string) error {
+	if s.ended[id] {
+		return storage.ErrImpersonationEnded
+	}
+	s.logged[id] = append(s.logged[id], method)
+	return nil
+}
+
+// memorySessionStore keeps sessions in memory
+type memorySessionStore struct {
+	storage.AuthStore
+	sessions map[int32]*models.Session
+}
+
+func (s *memorySessionStore) GetSession(id int32) (*models.Session, bool) {
+	session, ok := s.sessions[id]
+	return session, ok
+}
+
+func TestAuthenticator(t *testing.T) {
+	store := &memoryAPIKeyStore{keys: map[string]*models.APIKey{}, hashes: map[string]string{}}
+	impersonations := &memoryImpersonationStore{ended: map[int32]bool{}, logged: map[int32][]string{}}
+	sessions := &memorySessionStore{sessions: map[int32]*models.Session{}}
+	signer := auth.NewSigner([]byte("secret"), time.Minute)
+	interceptor := NewAuthenticator(store, impersonations, sessions, signer).UnaryInterceptor()
+
+	earlier := time.Now().Add(-time.Hour)
+	sender := store.add(t, &models.APIKey{Scopes: []string{models.ScopeNotificationsSend}})
+	expired := store.add(t, &models.APIKey{Scopes: []string{models.ScopeNotificationsSend}, ExpiresAt: &earlier})
+	revoked := store.add(t, &models.APIKey{Scopes: []string{models.ScopeNotificationsSend}, RevokedAt: &earlier})
+
+	adminToken, _, err := signer.Issue(auth.Claims{UserID: 1, Role: "admin"}, time.Now())
+	require.NoError(t, err)
+	userToken, _, err := signer.Issue(auth.Claims{UserID: 2, Role: "user"}, time.Now())
+	require.NoError(t, err)
+
+	// call runs the interceptor and returns the principal the handler saw
+	call := func(method string, md ...string) (*auth.Principal, error) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(md...))
+		var principal *auth.Principal
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			principal, _ = auth.PrincipalFromContext(ctx)
+			return nil, nil
+		})
+		return principal, err
+	}
+
+	principal, err := call(pb.NotificationService_CreateNotification_FullMethodName, "x-api-key", sender)
+	require.NoError(t, err)
+	assert.True(t, principal.IsAPIKey())
+	assert.Equal(t, []int32{principal.APIKeyID}, store.touched)
+
+	_, err = call(pb.NotificationService_ListNotifications_FullMethodName, "x-api-key", sender)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = call(pb.PrivacyService_ListErasureCertificates_FullMethodName, "x-api-key", sender)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	for _, key := range []string{expired, revoked, sender + "x", "bgs_000000000000_secret", "garbage"} {
+		_, err = call(pb.NotificationService_CreateNotification_FullMethodName, "x-api-key", key)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), key)
+	}
+
+	// Users sign in with access tokens
+	principal, err = call(pb.UserService_ListUsers_FullMethodName, "authorization", "Bearer "+userToken)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), principal.UserID)
+	_, err = call(pb.UserService_ListUsers_FullMethodName, "authorization", "Basic "+userToken)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = call(pb.UserService_ListUsers_FullMethodName, "authorization", "Bearer "+userToken+"x")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// Access tokens stop working once their session ends
+	sessions.sessions[7] = &models.Session{ID: 7, UserID: 2, ExpiresAt: time.Now().Add(time.Hour)}
+	sessionToken, _, err := signer.Issue(auth.Claims{UserID: 2, Role: "user", SessionID: 7}, time.Now())
+	require.NoError(t, err)
+	principal, err = call(pb.UserService_ListUsers_FullMethodName, "authorization", "Bearer "+sessionToken)
+	require.NoError(t, err)
+	assert.Equal(t, int32(7), principal.SessionID)
+	sessions.sessions[7].RevokedAt = &earlier
+	_, err = call(pb.UserService_ListUsers_FullMethodName, "authorization", "Bearer "+sessionToken)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	unknownSessionToken, _, err := signer.Issue(auth.Claims{UserID: 2, Role: "user", SessionID: 8}, time.Now())
+	require.NoError(t, err)
+	_, err = call(pb.UserService_ListUsers_FullMethodName, "authorization", "Bearer "+unknownSessionToken)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// Anonymous requests pass, except for admin methods
+	principal, err = call(pb.UserService_ListUsers_FullMethodName)
+	require.NoError(t, err)
+	assert.Nil(t, principal)
+	_, err = call(pb.APIKeyService_CreateAPIKey_FullMethodName)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = call(pb.APIKeyService_CreateAPIKey_FullMethodName, "authorization", "Bearer "+userToken)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = call(pb.APIKeyService_CreateAPIKey_FullMethodName, "x-api-key", sender)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	principal, err = call(pb.APIKeyService_CreateAPIKey_FullMethodName, "authorization", "Bearer "+adminToken)
+	require.NoError(t, err)
+	assert.Equal(t, "admin", principal.Role)
+
+	for _, method := range []string{
+		pb.AuthService_ResetTwoFactor_FullMethodName,
+		pb.PrivacyService_EraseUserData_FullMethodName,
+		pb.PrivacyService_ListErasureCertificates_FullMethodName,
+		pb.PrivacyService_GetErasureCertificate_FullMethodName,
+		pb.UserService_RestoreUser_FullMethodName,
+		pb.InvitationService_InviteUser_FullMethodName,
+		pb.InvitationService_RevokeInvitation_FullMethodName,
+		pb.InvitationService_ListInvitations_FullMethodName,
+	} {
+		_, err = call(method)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), method)
+		_, err = call(method, "authorization", "Bearer "+userToken)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err), method)
+		_, err = call(method, "authorization", "Bearer "+adminToken)
+		assert.NoError(t, err, method)
+	}
+	writer := store.add(t, &models.APIKey{Scopes: []string{models.ScopeUsersWrite}})
+	_, err = call(pb.UserService_RestoreUser_FullMethodName, "x-api-key", writer)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Changing users needs a sign in, but not an admin
+	for _, method := range []string{
+		pb.UserService_CreateUser_FullMethodName,
+		pb.UserService_UpdateUser_FullMethodName,
+		pb.UserService_DeleteUser_FullMethodName,
+		pb.UserService_ImportUsers_FullMethodName,
+	} {
+		_, err = call(method)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), method)
+		_, err = call(method, "authorization", "Bearer "+userToken)
+		assert.NoError(t, err, method)
+		_, err = call(method, "x-api-key", writer)
+		assert.NoError(t, err, method)
+	}
+
+	// Impersonation tokens act as the user, name the admin and are logged
+	impersonationToken, _, err := signer.Issue(auth.Claims{UserID: 2, Role: "user", Actor: &auth.Actor{UserID: 1, ImpersonationID: 5}}, time.Now())
+	require.NoError(t, err)
+	principal, err = call(pb.NotificationService_ListNotifications_FullMethodName, "authorization", "Bearer "+impersonationToken)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), principal.UserID)
+	assert.Equal(t, int32(1), principal.ActorID)
+	assert.True(t, principal.Impersonating())
+	assert.Equal(t, []string{pb.NotificationService_ListNotifications_FullMethodName}, impersonations.logged[5])
+
+	_, err = call(pb.AuthService_RequestEmailChange_FullMethodName, "authorization", "Bearer "+impersonationToken)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = call(pb.ImpersonationService_Impersonate_FullMethodName, "authorization", "Bearer "+impersonationToken)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Len(t, impersonations.logged[5], 1)
+
+	impersonations.ended[5] = true
+	_, err = call(pb.NotificationService_ListNotifications_FullMethodName, "authorization", "Bearer "+impersonationToken)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestSocketHandler_WithContext(t *testing.T) {
+	socketHandler := NewSocketHandler()
+	defer socketHandler.Shutdown()
+
+	anonymous := socketHandler.WithContext(context.Background()).message("user_updated", nil)
+	assert.Nil(t, anonymous.Actor)
+
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 2, Role: "user", ActorID: 1, ImpersonationID: 5})
+	impersonated := socketHandler.WithContext(ctx).message("user_updated", nil)
+	assert.Equal(t, &SocketActor{UserID: 2, ImpersonatedBy: 1}, impersonated.Actor)
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestClientIP(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 51234},
+	})
+	assert.Equal(t, "10.0.0.2", clientIP(ctx))
+
+	proxied := metadata.NewIncomingContext(ctx, metadata.Pairs("x-real-ip", "203.0.113.7"))
+	assert.Equal(t, "203.0.113.7", clientIP(proxied))
+
+	assert.Equal(t, "", clientIP(context.Background()))
+}
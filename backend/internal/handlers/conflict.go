@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"errors"
+
+	"backend-grpc-server/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// versionConflictStatus turns a version conflict into codes.Aborted and tells
+// the affected clients via a "conflict" socket event, so they can refetch.
+// Recipients nil broadcasts the event. Other errors are returned unchanged.
+func versionConflictStatus(socketHandler *SocketHandler, err error, recipients []int32) (error, bool) {
+	var conflict *storage.VersionConflictError
+	if !errors.As(err, &conflict) {
+		return err, false
+	}
+
+	event := map[string]interface{}{
+		"entity":           conflict.Entity,
+		"id":               conflict.ID,
+		"expected_version": conflict.ExpectedVersion,
+		"current_version":  conflict.CurrentVersion,
+	}
+	if recipients == nil {
+		socketHandler.EmitToAll("conflict", event)
+	} else {
+		socketHandler.EmitToUsers(recipients, "conflict", event)
+	}
+
+	return status.Errorf(codes.Aborted, "%v", conflict), true
+}
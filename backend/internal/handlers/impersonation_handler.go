@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"backend-grpc-server/internal/auth"
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/storage"
+	pb "backend-grpc-server/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Impersonation log page sizes
+const (
+	defaultImpersonationLimit = 50
+	maxImpersonationLimit     = 500
+)
+
+// ImpersonationHandler lets admins act as users. The Authenticator only lets
+// admins start impersonations and logs every request made under one.
+type ImpersonationHandler struct {
+	pb.UnimplementedImpersonationServiceServer
+	store storage.ImpersonationStore
+	users storage.UserStore
+
+	// signer issues the impersonation tokens, valid for ttl
+	signer *auth.Signer
+	ttl    time.Duration
+}
+
+// NewImpersonationHandler creates a new impersonation handler. Tokens are
+// signed like access tokens but valid for ttl.
+func NewImpersonationHandler(store storage.ImpersonationStore, users storage.UserStore, signer *auth.Signer, ttl time.Duration) *ImpersonationHandler {
+	return &ImpersonationHandler{
+		store:  store,
+		users:  users,
+		signer: signer.WithTTL(ttl),
+		ttl:    ttl,
+	}
+}
+
+// Impersonate issues a token acting as the user for the signed in admin. The
+// token can't be refreshed, the admin goes back to their own login afterwards.
+func (h *ImpersonationHandler) Impersonate(ctx context.Context, req *pb.ImpersonateRequest) (*pb.ImpersonateResponse, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok || principal.IsAPIKey() || principal.Impersonating() {
+		return nil, status.Errorf(codes.Unauthenticated, "sign in as an admin to impersonate users")
+	}
+
+	user, exists := h.users.GetUser(req.UserId)
+	if !exists {
+		return nil, status.Errorf(codes.NotFound, "user with ID %d not found", req.UserId)
+	}
+	if user.Role == "admin" {
+		return nil, status.Errorf(codes.PermissionDenied, "admins cannot be impersonated")
+	}
+
+	now := time.Now()
+	params := &models.StartImpersonationParams{
+		ActorID:   principal.UserID,
+		UserID:    user.ID,
+		Reason:    strings.TrimSpace(req.Reason),
+		ClientIP:  clientIP(ctx),
+		ExpiresAt: now.Add(h.ttl),
+	}
+
+	if err := params.Validate(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
+	}
+
+	impersonation, err := h.store.WithContext(ctx).StartImpersonation(params)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to start impersonation: %v", err)
+	}
+
+	token, expiresAt, err := h.signer.Issue(auth.Claims{
+		UserID: user.ID,
+		Role:   user.Role,
+		Actor: &auth.Actor{
+			UserID:          principal.UserID,
+			ImpersonationID: impersonation.ID,
+		},
+	}, now)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+
+	log.Printf("User %d started impersonating user %d (impersonation %d): %s", principal.UserID, user.ID, impersonation.ID, params.Reason)
+
+	return &pb.ImpersonateResponse{
+		AccessToken:          token,
+		AccessTokenExpiresAt: expiresAt.Format("2006-01-02T15:04:05Z07:00"),
+		User:                 convertToProtoUser(user),
+		Impersonating:        true,
+		ImpersonatedBy:       principal.UserID,
+		Impersonation:        convertToProtoImpersonation(impersonation),
+	}, nil
+}
+
+// StopImpersonation ends the impersonation the request is made under, its
+// token stops working right away
+func (h *ImpersonationHandler) StopImpersonation(ctx context.Context, req *pb.StopImpersonationRequest) (*pb.StopImpersonationResponse, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok || !principal.Impersonating() {
+		return nil, status.Errorf(codes.FailedPrecondition, "the request is not made under an impersonation")
+	}
+
+	if err := h.store.WithContext(ctx).EndImpersonation(principal.ImpersonationID); err != nil {
+		if errors.Is(err, storage.ErrImpersonationEnded) {
+			return &pb.StopImpersonationResponse{
+				Success: false,
+				Message: err.Error(),
+			}, nil
+		}
+		return nil, status.Errorf(codes.Internal, "failed to stop impersonation: %v", err)
+	}
+
+	log.Printf("User %d stopped impersonating user %d (impersonation %d)", principal.ActorID, principal.UserID, principal.ImpersonationID)
+
+	return &pb.StopImpersonationResponse{
+		Success: true,
+		Message: "Impersonation stopped",
+	}, nil
+}
+
+// ListImpersonations returns the impersonation log, newest first
+func (h *ImpersonationHandler) ListImpersonations(ctx context.Context, req *pb.ListImpersonationsRequest) (*pb.ListImpersonationsResponse, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultImpersonationLimit
+	}
+	if limit > maxImpersonationLimit {
+		limit = maxImpersonationLimit
+	}
+
+	impersonations, err := h.store.ListImpersonations(&models.ImpersonationFilter{
+		ActorID: req.ActorId,
+		UserID:  req.UserId,
+		Limit:   limit,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list impersonations: %v", err)
+	}
+
+	pbImpersonations := make([]*pb.Impersonation, 0, len(impersonations))
+	for _, impersonation := range impersonations {
+		pbImpersonations = append(pbImpersonations, convertToProtoImpersonation(impersonation))
+	}
+
+	return &pb.ListImpersonationsResponse{
+		Impersonations: pbImpersonations,
+	}, nil
+}
+
+func convertToProtoImpersonation(impersonation *models.Impersonation) *pb.Impersonation {
+	var endedAt string
+	if impersonation.EndedAt != nil {
+		endedAt = impersonation.EndedAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+
+	var actorID, userID int32
+	if impersonation.ActorID != nil {
+		actorID = *impersonation.ActorID
+	}
+	if impersonation.UserID != nil {
+		userID = *impersonation.UserID
+	}
+
+	return &pb.Impersonation{
+		Id:           impersonation.ID,
+		ActorId:      actorID,
+		UserId:       userID,
+		Reason:       impersonation.Reason,
+		ClientIp:     impersonation.ClientIP,
+		Status:       impersonation.Status(time.Now()),
+		StartedAt:    impersonation.StartedAt.Format("2006-01-02T15:04:05Z07:00"),
+		ExpiresAt:    impersonation.ExpiresAt.Format("2006-01-02T15:04:05Z07:00"),
+		EndedAt:      endedAt,
+		RequestCount: impersonation.RequestCount,
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend-grpc-server/internal/auth"
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/storage"
+	"backend-grpc-server/internal/testutil"
+	pb "backend-grpc-server/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// bearerContext returns the context of a request made with the access token
+func bearerContext(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+}
+
+func TestImpersonationHandler_ImpersonateAndStop(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	userStore := storage.NewPostgresUserStore(db)
+	store := storage.NewPostgresImpersonationStore(db)
+	signer := auth.NewSigner([]byte("secret"), 15*time.Minute)
+	handler := NewImpersonationHandler(store, userStore, signer, 10*time.Minute)
+	authenticator := NewAuthenticator(storage.NewPostgresAPIKeyStore(db), store, storage.NewPostgresAuthStore(db), signer)
+
+	admin, err := userStore.CreateUser(&models.CreateUserParams{Name: "Admin", Email: "admin@example.com", Age: 40, Role: "admin"})
+	require.NoError(t, err)
+	otherAdmin, err := userStore.CreateUser(&models.CreateUserParams{Name: "Other Admin", Email: "other@example.com", Age: 40, Role: "admin"})
+	require.NoError(t, err)
+	user, err := userStore.CreateUser(&models.CreateUserParams{Name: "Customer", Email: "customer@example.com", Age: 30, Role: "user"})
+	require.NoError(t, err)
+	adminCtx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: admin.ID, Role: "admin"})
+
+	_, err = handler.Impersonate(adminCtx, &pb.ImpersonateRequest{UserId: otherAdmin.ID, Reason: "Checking settings"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = handler.Impersonate(adminCtx, &pb.ImpersonateRequest{UserId: user.ID})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	resp, err := handler.Impersonate(adminCtx, &pb.ImpersonateRequest{UserId: user.ID, Reason: "Ticket 1234, missing notifications"})
+	require.NoError(t, err)
+	assert.True(t, resp.Impersonating)
+	assert.Equal(t, admin.ID, resp.ImpersonatedBy)
+	assert.Equal(t, user.ID, resp.User.Id)
+	assert.Equal(t, models.ImpersonationActive, resp.Impersonation.Status)
+
+	// Requests with the token act as the user and are logged
+	ctx, err := authenticator.authenticate(bearerContext(resp.AccessToken), pb.NotificationService_ListNotifications_FullMethodName)
+	require.NoError(t, err)
+	principal, ok := auth.PrincipalFromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, user.ID, principal.UserID)
+	assert.Equal(t, admin.ID, principal.ActorID)
+
+	stopped, err := handler.StopImpersonation(ctx, &pb.StopImpersonationRequest{})
+	require.NoError(t, err)
+	assert.True(t, stopped.Success)
+
+	_, err = authenticator.authenticate(bearerContext(resp.AccessToken), pb.NotificationService_ListNotifications_FullMethodName)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = handler.StopImpersonation(adminCtx, &pb.StopImpersonationRequest{})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	list, err := handler.ListImpersonations(adminCtx, &pb.ListImpersonationsRequest{UserId: user.ID})
+	require.NoError(t, err)
+	require.Len(t, list.Impersonations, 1)
+	assert.Equal(t, models.ImpersonationEnded, list.Impersonations[0].Status)
+	assert.Equal(t, int32(1), list.Impersonations[0].RequestCount)
+	assert.Equal(t, "Ticket 1234, missing notifications", list.Impersonations[0].Reason)
+}
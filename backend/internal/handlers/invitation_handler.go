@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"backend-grpc-server/internal/auth"
+	"backend-grpc-server/internal/mail"
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/storage"
+	pb "backend-grpc-server/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// InvitationHandler handles invitation-related gRPC requests
+type InvitationHandler struct {
+	pb.UnimplementedInvitationServiceServer
+	store         storage.InvitationStore
+	users         storage.UserStore
+	mailer        mail.Sender
+	socketHandler *SocketHandler
+
+	// baseURL is the frontend the invitation links point to
+	baseURL string
+	// validity is how long an invitation can be accepted by default
+	validity time.Duration
+}
+
+// NewInvitationHandler creates a new invitation handler
+func NewInvitationHandler(store storage.InvitationStore, users storage.UserStore, mailer mail.Sender, socketHandler *SocketHandler, baseURL string, validity time.Duration) *InvitationHandler {
+	return &InvitationHandler{
+		store:         store,
+		users:         users,
+		mailer:        mailer,
+		socketHandler: socketHandler,
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		validity:      validity,
+	}
+}
+
+// InviteUser creates an invitation from the signed in admin and mails the
+// link to the address
+func (h *InvitationHandler) InviteUser(ctx context.Context, req *pb.InviteUserRequest) (*pb.InviteUserResponse, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, status.Errorf(codes.Unauthenticated, "sign in as an admin to invite users")
+	}
+	inviter, exists := h.users.GetUser(principal.UserID)
+	if !exists {
+		return nil, status.Errorf(codes.Unauthenticated, "sign in as an admin to invite users")
+	}
+
+	validity := h.validity
+	if req.ValidForHours < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "valid_for_hours cannot be negative")
+	}
+	if req.ValidForHours > 0 {
+		validity = time.Duration(req.ValidForHours) * time.Hour
+	}
+	if validity > models.MaxInvitationValidity {
+		return nil, status.Errorf(codes.InvalidArgument, "invitations cannot be valid for more than %s", models.MaxInvitationValidity)
+	}
+
+	params := &models.CreateInvitationParams{
+		Email:     strings.TrimSpace(req.Email),
+		Role:      req.Role,
+		InvitedBy: inviter.Name,
+		ExpiresAt: time.Now().Add(validity),
+	}
+
+	if err := params.Validate(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
+	}
+
+	token, tokenHash, err := auth.NewToken()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+
+	invitation, err := h.store.WithContext(ctx).CreateInvitation(params, tokenHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrEmailTaken) {
+			return nil, status.Errorf(codes.AlreadyExists, "%v", err)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to create invitation: %v", err)
+	}
+
+	// An invitation nobody received can't be accepted, so don't keep it open
+	if err := h.mailer.Send(h.invitationMessage(invitation, token)); err != nil {
+		if revokeErr := h.store.WithContext(ctx).RevokeInvitation(invitation.ID); revokeErr != nil {
+			log.Printf("Failed to revoke unsent invitation %d: %v", invitation.ID, revokeErr)
+		}
+		return nil, status.Errorf(codes.Unavailable, "failed to send invitation: %v", err)
+	}
+
+	// Send socket event about the invitation
+	h.socketHandler.WithContext(ctx).EmitToAll("invitation_created", map[string]interface{}{
+		"id":   invitation.ID,
+		"role": invitation.Role,
+	})
+
+	return &pb.InviteUserResponse{
+		Invitation: convertToProtoInvitation(invitation),
+	}, nil
+}
+
+// AcceptInvitation creates the invited user with the chosen name and password
+func (h *InvitationHandler) AcceptInvitation(ctx context.Context, req *pb.AcceptInvitationRequest) (*pb.AcceptInvitationResponse, error) {
+	params := &models.AcceptInvitationParams{
+		Token:    req.Token,
+		Name:     req.Name,
+		Age:      req.Age,
+		Password: req.Password,
+	}
+
+	if err := params.Validate(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
+	}
+	if err := auth.ValidatePassword(params.Password); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
+	passwordHash, err := auth.HashPassword(params.Password)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+
+	user, err := h.store.WithContext(ctx).AcceptInvitation(auth.HashToken(params.Token), params, passwordHash)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrInvitationInvalid):
+			return nil, status.Errorf(codes.NotFound, "%v", err)
+		case errors.Is(err, storage.ErrEmailTaken):
+			return nil, status.Errorf(codes.AlreadyExists, "%v", err)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to accept invitation: %v", err)
+	}
+
+	// Send socket event about user creation
+	h.socketHandler.WithContext(ctx).EmitToAll("user_created", map[string]interface{}{
+		"id":    user.ID,
+		"name":  user.Name,
+		"email": user.Email,
+		"age":   user.Age,
+		"role":  user.Role,
+	})
+
+	return &pb.AcceptInvitationResponse{
+		User: convertToProtoUser(user),
+	}, nil
+}
+
+// RevokeInvitation invalidates an open invitation
+func (h *InvitationHandler) RevokeInvitation(ctx context.Context, req *pb.RevokeInvitationRequest) (*pb.RevokeInvitationResponse, error) {
+	if req.Id <= 0 {
+		return &pb.RevokeInvitationResponse{
+			Success: false,
+			Message: "invitation ID must be greater than 0",
+		}, nil
+	}
+
+	if err := h.store.WithContext(ctx).RevokeInvitation(req.Id); err != nil {
+		return &pb.RevokeInvitationResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	// Send socket event about the revocation
+	h.socketHandler.WithContext(ctx).EmitToAll("invitation_revoked", map[string]interface{}{
+		"id": req.Id,
+	})
+
+	return &pb.RevokeInvitationResponse{
+		Success: true,
+		Message: fmt.Sprintf("Invitation with ID %d successfully revoked", req.Id),
+	}, nil
+}
+
+// ListInvitations returns the pending invitations, or all of them
+func (h *InvitationHandler) ListInvitations(ctx context.Context, req *pb.ListInvitationsRequest) (*pb.ListInvitationsResponse, error) {
+	invitations, err := h.store.ListInvitations(!req.IncludeClosed)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list invitations: %v", err)
+	}
+
+	pbInvitations := make([]*pb.Invitation, 0, len(invitations))
+	for _, invitation := range invitations {
+		pbInvitations = append(pbInvitations, convertToProtoInvitation(invitation))
+	}
+
+	return &pb.ListInvitationsResponse{
+		Invitations: pbInvitations,
+	}, nil
+}
+
+// invitationMessage builds the invitation email with the accept link
+func (h *InvitationHandler) invitationMessage(invitation *models.Invitation, token string) mail.Message {
+	link := fmt.Sprintf("%s/invitations/accept?token=%s", h.baseURL, url.QueryEscape(token))
+
+	return mail.Message{
+		To:      invitation.Email,
+		Subject: "You have been invited",
+		Body: fmt.Sprintf(
+			"%s invited you to join as %s.\n\nAccept the invitation here:\n%s\n\nThe link expires on %s.\n",
+			invitation.InvitedBy,
+			invitation.Role,
+			link,
+			invitation.ExpiresAt.Format("2006-01-02 15:04 MST"),
+		),
+	}
+}
+
+func convertToProtoInvitation(invitation *models.Invitation) *pb.Invitation {
+	var acceptedAt, revokedAt string
+	if invitation.AcceptedAt != nil {
+		acceptedAt = invitation.AcceptedAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+	if invitation.RevokedAt != nil {
+		revokedAt = invitation.RevokedAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+
+	var userID int32
+	if invitation.UserID != nil {
+		userID = *invitation.UserID
+	}
+
+	return &pb.Invitation{
+		Id:         invitation.ID,
+		Email:      invitation.Email,
+		Role:       invitation.Role,
+		InvitedBy:  invitation.InvitedBy,
+		Status:     invitation.Status(time.Now()),
+		ExpiresAt:  invitation.ExpiresAt.Format("2006-01-02T15:04:05Z07:00"),
+		AcceptedAt: acceptedAt,
+		RevokedAt:  revokedAt,
+		UserId:     userID,
+		CreatedAt:  invitation.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
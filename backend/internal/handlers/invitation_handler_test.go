@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-grpc-server/internal/auth"
+	"backend-grpc-server/internal/mail"
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/storage"
+	"backend-grpc-server/internal/testutil"
+	pb "backend-grpc-server/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// recordingSender keeps sent messages instead of delivering them
+type recordingSender struct {
+	sent []mail.Message
+	err  error
+}
+
+func (s *recordingSender) Send(msg mail.Message) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+// tokenFromMessage extracts the token from the accept link in an invitation mail
+func tokenFromMessage(t *testing.T, msg mail.Message) string {
+	start := strings.Index(msg.Body, "http")
+	require.GreaterOrEqual(t, start, 0)
+	link, err := url.Parse(strings.Fields(msg.Body[start:])[0])
+	require.NoError(t, err)
+	return link.Query().Get("token")
+}
+
+func TestInvitationHandler_InviteAndAccept(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	sender := &recordingSender{}
+	userStore := storage.NewPostgresUserStore(db)
+	handler := NewInvitationHandler(storage.NewPostgresInvitationStore(db), userStore, sender, NewSocketHandler(), "http://localhost:3000/", 7*24*time.Hour)
+	admin, err := userStore.CreateUser(&models.CreateUserParams{Name: "Inviting Admin", Email: "admin@example.com", Age: 40, Role: "admin"})
+	require.NoError(t, err)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: admin.ID, Role: "admin"})
+
+	invited, err := handler.InviteUser(ctx, &pb.InviteUserRequest{
+		Email: "invitee@example.com",
+		Role:  "moderator",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "pending", invited.Invitation.Status)
+	assert.Equal(t, "Inviting Admin", invited.Invitation.InvitedBy)
+	require.Len(t, sender.sent, 1)
+	assert.Equal(t, "invitee@example.com", sender.sent[0].To)
+	assert.Contains(t, sender.sent[0].Body, "http://localhost:3000/invitations/accept?token=")
+
+	token := tokenFromMessage(t, sender.sent[0])
+
+	_, err = handler.AcceptInvitation(ctx, &pb.AcceptInvitationRequest{Token: token, Name: "Invited User", Age: 30, Password: "short"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	accepted, err := handler.AcceptInvitation(ctx, &pb.AcceptInvitationRequest{Token: token, Name: "Invited User", Age: 30, Password: "correct horse"})
+	require.NoError(t, err)
+	assert.Equal(t, "invitee@example.com", accepted.User.Email)
+	assert.Equal(t, "moderator", accepted.User.Role)
+
+	// Tokens work once
+	_, err = handler.AcceptInvitation(ctx, &pb.AcceptInvitationRequest{Token: token, Name: "Invited User", Age: 30, Password: "correct horse"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// The address now belongs to a user
+	_, err = handler.InviteUser(ctx, &pb.InviteUserRequest{Email: "invitee@example.com", Role: "user"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	list, err := handler.ListInvitations(ctx, &pb.ListInvitationsRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, list.Invitations)
+	list, err = handler.ListInvitations(ctx, &pb.ListInvitationsRequest{IncludeClosed: true})
+	require.NoError(t, err)
+	require.Len(t, list.Invitations, 1)
+	assert.Equal(t, "accepted", list.Invitations[0].Status)
+	assert.Equal(t, accepted.User.Id, list.Invitations[0].UserId)
+}
+
+func TestInvitationHandler_ReinviteAndRevoke(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	sender := &recordingSender{}
+	userStore := storage.NewPostgresUserStore(db)
+	handler := NewInvitationHandler(storage.NewPostgresInvitationStore(db), userStore, sender, NewSocketHandler(), "http://localhost:3000", 7*24*time.Hour)
+	admin, err := userStore.CreateUser(&models.CreateUserParams{Name: "Inviting Admin", Email: "admin@example.com", Age: 40, Role: "admin"})
+	require.NoError(t, err)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: admin.ID, Role: "admin"})
+
+	req := &pb.InviteUserRequest{Email: "again@example.com", Role: "user"}
+	first, err := handler.InviteUser(ctx, req)
+	require.NoError(t, err)
+	second, err := handler.InviteUser(ctx, req)
+	require.NoError(t, err)
+
+	// Inviting again replaces the first link
+	_, err = handler.AcceptInvitation(ctx, &pb.AcceptInvitationRequest{Token: tokenFromMessage(t, sender.sent[0]), Name: "Again", Age: 30, Password: "correct horse"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	revoked, err := handler.RevokeInvitation(ctx, &pb.RevokeInvitationRequest{Id: second.Invitation.Id})
+	require.NoError(t, err)
+	assert.True(t, revoked.Success)
+	revoked, err = handler.RevokeInvitation(ctx, &pb.RevokeInvitationRequest{Id: first.Invitation.Id})
+	require.NoError(t, err)
+	assert.False(t, revoked.Success)
+
+	_, err = handler.AcceptInvitation(ctx, &pb.AcceptInvitationRequest{Token: tokenFromMessage(t, sender.sent[1]), Name: "Again", Age: 30, Password: "correct horse"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// Undeliverable invitations are not left open
+	sender.err = errors.New("smtp down")
+	_, err = handler.InviteUser(ctx, req)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	list, err := handler.ListInvitations(ctx, &pb.ListInvitationsRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, list.Invitations)
+}
+
+func TestInvitationHandler_InviteUserAnonymous(t *testing.T) {
+	handler := &InvitationHandler{}
+
+	_, err := handler.InviteUser(context.Background(), &pb.InviteUserRequest{Email: "invitee@example.com", Role: "admin"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"backend-grpc-server/internal/models"
+	pb "backend-grpc-server/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Login throttling. Past the free failures every failed login doubles the
+// wait before the next attempt from the same address or client IP. Client
+// IPs may be shared by many users, so they get more free failures. Failures
+// are forgotten after a window without any.
+const (
+	freeLoginFailuresPerEmail = 3
+	freeLoginFailuresPerIP    = 20
+	loginDelayBase            = time.Second
+	loginDelayMax             = time.Minute
+	loginFailureWindow        = time.Hour
+)
+
+// Lockout defaults, used when AuthConfig leaves them empty
+const (
+	defaultLockoutThreshold = 10
+	defaultLockoutDuration  = 15 * time.Minute
+)
+
+// NotifyThrough sends the notifications of the auth handler, like lockout
+// warnings, through the notification handler
+func (h *AuthHandler) NotifyThrough(notifications *NotificationHandler) {
+	h.notifications = notifications
+}
+
+// UnlockAccount lifts the lock of an account before it runs out, for admins
+func (h *AuthHandler) UnlockAccount(ctx context.Context, req *pb.UnlockAccountRequest) (*pb.UnlockAccountResponse, error) {
+	user, exists := h.users.GetUser(req.UserId)
+	if !exists {
+		return &pb.UnlockAccountResponse{
+			Success: false,
+			Message: fmt.Sprintf("user with ID %d not found", req.UserId),
+		}, nil
+	}
+
+	if err := h.store.WithContext(ctx).UnlockUser(user.ID); err != nil {
+		return &pb.UnlockAccountResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+	h.loginFailuresByEmail.Reset(strings.ToLower(user.Email))
+
+	return &pb.UnlockAccountResponse{
+		Success: true,
+		Message: fmt.Sprintf("User %d has been unlocked", user.ID),
+	}, nil
+}
+
+// checkLoginThrottle refuses logins that come too soon after failed ones.
+// Unknown addresses are slowed down like known ones.
+func (h *AuthHandler) checkLoginThrottle(ip string, email string, now time.Time) error {
+	if wait := h.loginFailuresByIP.Wait(ip, now); wait > 0 {
+		return status.Errorf(codes.ResourceExhausted, "too many failed logins, try again in %s", wait.Round(time.Second))
+	}
+	if wait := h.loginFailuresByEmail.Wait(email, now); wait > 0 {
+		return status.Errorf(codes.ResourceExhausted, "too many failed logins, try again in %s", wait.Round(time.Second))
+	}
+	return nil
+}
+
+// recordFailedLogin counts a failed login and locks the account once there
+// were too many in a row, which its owner is told about
+func (h *AuthHandler) recordFailedLogin(ctx context.Context, ip string, email string, user *models.User, now time.Time) {
+	h.loginFailuresByIP.Fail(ip, now)
+	h.loginFailuresByEmail.Fail(email, now)
+	if user == nil {
+		return
+	}
+
+	lockedUntil := now.Add(h.config.LockoutDuration)
+	_, locked, err := h.store.WithContext(ctx).RecordFailedLogin(user.ID, now.Add(-loginFailureWindow), h.config.LockoutThreshold, lockedUntil)
+	if err != nil {
+		log.Printf("Failed to record failed login of user %d: %v", user.ID, err)
+		return
+	}
+	if locked {
+		h.notifyLockout(user, ip, lockedUntil)
+	}
+}
+
+// clearFailedLogins starts the count over after a successful login
+func (h *AuthHandler) clearFailedLogins(ctx context.Context, email string, user *models.User) {
+	h.loginFailuresByEmail.Reset(email)
+	if err := h.store.WithContext(ctx).ClearFailedLogins(user.ID); err != nil {
+		log.Printf("Failed to clear failed logins of user %d: %v", user.ID, err)
+	}
+}
+
+// notifyLockout leaves the owner of a locked account a notification, which
+// they see once they are back in
+func (h *AuthHandler) notifyLockout(user *models.User, ip string, lockedUntil time.Time) {
+	if h.notifications == nil {
+		return
+	}
+
+	message := fmt.Sprintf("Your account was locked until %s after %d failed logins. If that wasn't you, reset your password.",
+		lockedUntil.Format("2006-01-02 15:04 MST"), h.config.LockoutThreshold)
+	data := map[string]interface{}{
+		"event":        "account_locked",
+		"locked_until": lockedUntil.Format("2006-01-02T15:04:05Z07:00"),
+		"client_ip":    ip,
+	}
+	if err := h.notifications.NotifyUserWithData(user.ID, message, "warning", true, data); err != nil {
+		log.Printf("Failed to notify user %d about lockout: %v", user.ID, err)
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend-grpc-server/internal/auth"
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/storage"
+	"backend-grpc-server/internal/testutil"
+	pb "backend-grpc-server/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthHandler_LoginThrottle(t *testing.T) {
+	handler := NewAuthHandler(nil, nil, nil, nil, NewSocketHandler(), auth.NewSigner([]byte("secret"), time.Minute), AuthConfig{})
+	now := time.Unix(1700000000, 0)
+
+	// Unknown addresses slow down like accounts do
+	for i := 0; i < freeLoginFailuresPerEmail; i++ {
+		require.NoError(t, handler.checkLoginThrottle("192.0.2.1", "nobody@example.com", now))
+		handler.recordFailedLogin(context.Background(), "192.0.2.1", "nobody@example.com", nil, now)
+	}
+	require.NoError(t, handler.checkLoginThrottle("192.0.2.1", "nobody@example.com", now))
+	handler.recordFailedLogin(context.Background(), "192.0.2.1", "nobody@example.com", nil, now)
+	err := handler.checkLoginThrottle("192.0.2.1", "nobody@example.com", now)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.NoError(t, handler.checkLoginThrottle("192.0.2.1", "nobody@example.com", now.Add(loginDelayBase)))
+	assert.NoError(t, handler.checkLoginThrottle("192.0.2.1", "somebody@example.com", now))
+
+	// Client IPs trying many addresses slow down as well
+	for i := 0; i <= freeLoginFailuresPerIP; i++ {
+		handler.recordFailedLogin(context.Background(), "192.0.2.2", "guess@example.com", nil, now)
+		handler.loginFailuresByEmail.Reset("guess@example.com")
+	}
+	err = handler.checkLoginThrottle("192.0.2.2", "other@example.com", now)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestAuthHandler_Lockout(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	userStore := storage.NewPostgresUserStore(db)
+	notificationStore := storage.NewPostgresNotificationStore(db)
+	handler := newTestAuthHandler(db, &recordingSender{}, 0)
+	handler.NotifyThrough(NewNotificationHandler(notificationStore, handler.socketHandler))
+	handler.config.LockoutThreshold = freeLoginFailuresPerEmail
+	ctx := context.Background()
+
+	user, err := userStore.CreateUser(&models.CreateUserParams{Name: "Targeted", Email: "targeted@example.com", Age: 30, Role: "user"})
+	require.NoError(t, err)
+	passwordHash, err := auth.HashPassword("correct horse")
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE users SET password_hash = $2 WHERE id = $1`, user.ID, passwordHash)
+	require.NoError(t, err)
+
+	// A successful login starts the count over
+	_, err = handler.Login(ctx, &pb.LoginRequest{Email: "targeted@example.com", Password: "wrong horse"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = handler.Login(ctx, &pb.LoginRequest{Email: "targeted@example.com", Password: "correct horse"})
+	require.NoError(t, err)
+
+	for i := 0; i < freeLoginFailuresPerEmail; i++ {
+		_, err = handler.Login(ctx, &pb.LoginRequest{Email: "targeted@example.com", Password: "wrong horse"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	}
+
+	// The account is locked, even for the right password, which looks like a
+	// wrong one to the caller
+	_, err = handler.Login(ctx, &pb.LoginRequest{Email: "targeted@example.com", Password: "correct horse"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Contains(t, err.Error(), "invalid email or password")
+	locked, exists := userStore.GetUser(user.ID)
+	require.True(t, exists)
+	assert.True(t, locked.Locked(time.Now()))
+
+	// The owner is told about it once
+	notifications, _, err := notificationStore.ListNotificationsByUser(user.ID, &models.ListNotificationsParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "warning", notifications[0].Type)
+	assert.Contains(t, notifications[0].Message, "locked")
+
+	unlocked, err := handler.UnlockAccount(ctx, &pb.UnlockAccountRequest{UserId: user.ID})
+	require.NoError(t, err)
+	assert.True(t, unlocked.Success)
+	login, err := handler.Login(ctx, &pb.LoginRequest{Email: "targeted@example.com", Password: "correct horse"})
+	require.NoError(t, err)
+	assert.Empty(t, login.User.LockedUntil)
+
+	missing, err := handler.UnlockAccount(ctx, &pb.UnlockAccountRequest{UserId: user.ID + 1000})
+	require.NoError(t, err)
+	assert.False(t, missing.Success)
+}
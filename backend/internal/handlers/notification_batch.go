@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/notificationtypes"
+	"backend-grpc-server/internal/validation"
+	pb "backend-grpc-server/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Bulk gRPC Methods
+
+// BatchMarkNotifications marks many notifications of a user as read or unread
+func (h *NotificationHandler) BatchMarkNotifications(ctx context.Context, req *pb.BatchMarkNotificationsRequest) (*pb.BatchMarkNotificationsResponse, error) {
+	params, err := convertFromProtoBatch(req.UserId, req.Ids, req.Filter)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
+	}
+
+	results, err := h.store.WithContext(ctx).BatchSetRead(params, req.Read)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to update notifications: %v", err)
+	}
+
+	action := "read"
+	if !req.Read {
+		action = "unread"
+	}
+	h.emitBatch(ctx, req.UserId, action, results)
+
+	succeeded, failed, pbResults := convertToProtoBatchResults(results)
+	return &pb.BatchMarkNotificationsResponse{
+		Succeeded: succeeded,
+		Failed:    failed,
+		Results:   pbResults,
+	}, nil
+}
+
+// BatchDeleteNotifications deletes many notifications of a user
+func (h *NotificationHandler) BatchDeleteNotifications(ctx context.Context, req *pb.BatchDeleteNotificationsRequest) (*pb.BatchDeleteNotificationsResponse, error) {
+	params, err := convertFromProtoBatch(req.UserId, req.Ids, req.Filter)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
+	}
+
+	results, err := h.store.WithContext(ctx).BatchDelete(params)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to delete notifications: %v", err)
+	}
+
+	h.emitBatch(ctx, req.UserId, "deleted", results)
+
+	succeeded, failed, pbResults := convertToProtoBatchResults(results)
+	return &pb.BatchDeleteNotificationsResponse{
+		Succeeded: succeeded,
+		Failed:    failed,
+		Results:   pbResults,
+	}, nil
+}
+
+// BatchCreateNotifications stores many notifications in one transaction.
+// Invalid items are reported and skipped, the valid ones are created together.
+func (h *NotificationHandler) BatchCreateNotifications(ctx context.Context, req *pb.BatchCreateNotificationsRequest) (*pb.BatchCreateNotificationsResponse, error) {
+	if len(req.Notifications) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "at least one notification is required")
+	}
+	if len(req.Notifications) > models.MaxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "cannot create more than %d notifications at once", models.MaxBatchSize)
+	}
+
+	results := make([]models.BatchItemResult, len(req.Notifications))
+	var valid []*models.CreateNotificationParams
+	var validIndexes []int
+
+	for i, item := range req.Notifications {
+		results[i].Index = int32(i)
+
+		if item.Target != nil {
+			results[i].Error = "target is not supported in batch create, use user_id"
+			continue
+		}
+
+		params := &models.CreateNotificationParams{
+			Message:    item.Message,
+			Type:       item.Type,
+			UserID:     convertToInt32Pointer(item.UserId),
+			Persistent: true,
+			Data:       convertFromProtoData(item.Data),
+			Language:   item.Language,
+		}
+		if err := validation.ValidateStruct(params); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		if _, err := checkDeliveryChannels(params); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+
+		valid = append(valid, params)
+		validIndexes = append(validIndexes, i)
+	}
+
+	var created []*models.Notification
+	if len(valid) > 0 {
+		var err error
+		created, err = h.store.WithContext(ctx).CreateNotificationsBatch(valid)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to create notifications: %v", err)
+		}
+	}
+
+	pbNotifications := make([]*pb.Notification, len(created))
+	for i, notification := range created {
+		results[validIndexes[i]].ID = notification.ID
+		results[validIndexes[i]].Success = true
+		pbNotifications[i] = h.convertToProtoNotification(notification)
+	}
+
+	h.emitCreatedBatch(ctx, created)
+
+	succeeded, failed, pbResults := convertToProtoBatchResults(results)
+	return &pb.BatchCreateNotificationsResponse{
+		Succeeded:     succeeded,
+		Failed:        failed,
+		Results:       pbResults,
+		Notifications: pbNotifications,
+	}, nil
+}
+
+// emitBatch sends the user a single event listing every affected notification
+func (h *NotificationHandler) emitBatch(ctx context.Context, userID int32, action string, results []models.BatchItemResult) {
+	var ids []int32
+	for _, result := range results {
+		if result.Success {
+			ids = append(ids, result.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	h.socketHandler.WithContext(ctx).EmitToUser(userID, "notifications_batch", map[string]interface{}{
+		"action": action,
+		"ids":    ids,
+	})
+	h.refreshUnreadCounts(userID)
+}
+
+// emitCreatedBatch sends one event per recipient with all of their new
+// notifications. Global notifications are broadcast in one event.
+func (h *NotificationHandler) emitCreatedBatch(ctx context.Context, notifications []*models.Notification) {
+	var global []interface{}
+	byUser := make(map[int32][]interface{})
+
+	for _, notification := range notifications {
+		if t, ok := notificationtypes.Lookup(notification.Type); !ok || !t.AllowsChannel(notificationtypes.ChannelRealtime) {
+			continue
+		}
+
+		payload := notificationPayload(notification)
+		if notification.UserID == nil {
+			global = append(global, payload)
+		} else {
+			byUser[*notification.UserID] = append(byUser[*notification.UserID], payload)
+		}
+	}
+
+	if len(global) > 0 {
+		h.socketHandler.WithContext(ctx).EmitToAll("notifications_batch", map[string]interface{}{
+			"action":        "created",
+			"notifications": global,
+		})
+	}
+
+	dataByUser := make(map[int32]interface{}, len(byUser))
+	for userID, payloads := range byUser {
+		dataByUser[userID] = map[string]interface{}{
+			"action":        "created",
+			"notifications": payloads,
+		}
+	}
+	h.socketHandler.WithContext(ctx).EmitToEachUser("notifications_batch", dataByUser)
+	h.refreshUnreadCountsFor(notifications)
+}
+
+func convertFromProtoBatch(userID int32, ids []int32, filter *pb.NotificationFilter) (*models.BatchNotificationParams, error) {
+	params := &models.BatchNotificationParams{
+		UserID: userID,
+		IDs:    ids,
+	}
+
+	if filter != nil {
+		createdAfter, err := parseOptionalTime(filter.CreatedAfter)
+		if err != nil {
+			return nil, fmt.Errorf("created_after must be RFC3339: %v", err)
+		}
+		createdBefore, err := parseOptionalTime(filter.CreatedBefore)
+		if err != nil {
+			return nil, fmt.Errorf("created_before must be RFC3339: %v", err)
+		}
+
+		params.Filter = &models.NotificationFilter{
+			Read:          convertToBoolPointer(filter.Read, filter.HasReadFilter),
+			Types:         filter.Types,
+			CreatedAfter:  createdAfter,
+			CreatedBefore: createdBefore,
+			Archived:      convertToBoolPointer(filter.Archived, filter.HasArchivedFilter),
+		}
+	}
+
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	return params, nil
+}
+
+func convertToProtoBatchResults(results []models.BatchItemResult) (int32, int32, []*pb.BatchItemResult) {
+	var succeeded, failed int32
+	pbResults := make([]*pb.BatchItemResult, len(results))
+
+	for i, result := range results {
+		if result.Success {
+			succeeded++
+		} else {
+			failed++
+		}
+		pbResults[i] = &pb.BatchItemResult{
+			Index:   result.Index,
+			Id:      result.ID,
+			Success: result.Success,
+			Error:   result.Error,
+		}
+	}
+
+	return succeeded, failed, pbResults
+}
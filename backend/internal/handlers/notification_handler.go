@@ -2,10 +2,16 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"sync"
 	"time"
 
 	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/notificationtypes"
+	"backend-grpc-server/internal/pagination"
 	"backend-grpc-server/internal/storage"
 	"backend-grpc-server/internal/validation"
 	pb "backend-grpc-server/pb"
@@ -13,11 +19,21 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+// defaultPageSize is used by the list RPCs when no limit is given
+const defaultPageSize = 50
+
 // NotificationHandler with socket integration and validation
 type NotificationHandler struct {
 	pb.UnimplementedNotificationServiceServer
 	store         storage.NotificationStore
 	socketHandler *SocketHandler
+
+	// Cached unread badge counts of connected users
+	unreadCounts *unreadCounter
+
+	// Background workers stop when done is closed
+	done     chan struct{}
+	doneOnce sync.Once
 }
 
 // NewNotificationHandler creates a new notification handler
@@ -25,6 +41,8 @@ func NewNotificationHandler(store storage.NotificationStore, socketHandler *Sock
 	handler := &NotificationHandler{
 		store:         store,
 		socketHandler: socketHandler,
+		unreadCounts:  newUnreadCounter(),
+		done:          make(chan struct{}),
 	}
 
 	// Register socket event handlers for notifications
@@ -49,6 +67,36 @@ func (h *NotificationHandler) setupSocketHandlers() {
 	h.socketHandler.OnEvent("delete_notification", func(client *SocketClient, data interface{}) {
 		h.handleDeleteNotificationEvent(client, data)
 	})
+
+	// Push the unread badge when a client subscribes to a user
+	h.socketHandler.OnEvent("user_subscribed", func(client *SocketClient, data interface{}) {
+		if userID, ok := data.(int32); ok {
+			h.sendUnreadCount(client, userID)
+		}
+	})
+
+	h.socketHandler.OnEvent("user_disconnected", func(client *SocketClient, data interface{}) {
+		if userID, ok := data.(int32); ok && !h.socketHandler.IsUserConnected(userID) {
+			h.unreadCounts.forget(userID)
+		}
+	})
+
+	// Handle archive and snooze requests
+	h.socketHandler.OnEvent("archive_notification", func(client *SocketClient, data interface{}) {
+		h.handleArchiveEvent(client, data, true)
+	})
+
+	h.socketHandler.OnEvent("unarchive_notification", func(client *SocketClient, data interface{}) {
+		h.handleArchiveEvent(client, data, false)
+	})
+
+	h.socketHandler.OnEvent("snooze_notification", func(client *SocketClient, data interface{}) {
+		h.handleSnoozeEvent(client, data)
+	})
+
+	h.socketHandler.OnEvent("unsnooze_notification", func(client *SocketClient, data interface{}) {
+		h.handleUnsnoozeEvent(client, data)
+	})
 }
 
 // Socket Event Handlers with validation
@@ -81,7 +129,8 @@ func (h *NotificationHandler) handleCreateNotificationEvent(client *SocketClient
 	}
 
 	// Create the notification
-	err := h.SendNotification(message, notificationType, "all", nil, persistent, nil)
+	ctx := socketAuditContext(client, "create_notification")
+	_, err := h.sendNotificationToTarget(ctx, params, &models.NotificationTarget{Type: "all"})
 	if err != nil {
 		h.socketHandler.EmitToClient(client.ID, "error", map[string]interface{}{
 			"message": "Failed to create notification: " + err.Error(),
@@ -117,7 +166,8 @@ func (h *NotificationHandler) handleMarkAsReadEvent(client *SocketClient, data i
 		userID = *client.UserID
 	}
 
-	err := h.store.MarkAsRead(int32(notificationID), userID)
+	ctx := socketAuditContext(client, "mark_as_read")
+	err := h.store.WithContext(ctx).MarkAsRead(int32(notificationID), userID)
 	if err != nil {
 		h.socketHandler.EmitToClient(client.ID, "error", map[string]interface{}{
 			"message": "Failed to mark as read: " + err.Error(),
@@ -130,6 +180,7 @@ func (h *NotificationHandler) handleMarkAsReadEvent(client *SocketClient, data i
 		"id":   int32(notificationID),
 		"read": true,
 	})
+	h.refreshUnreadCounts(userID)
 }
 
 func (h *NotificationHandler) handleDeleteNotificationEvent(client *SocketClient, data interface{}) {
@@ -149,7 +200,10 @@ func (h *NotificationHandler) handleDeleteNotificationEvent(client *SocketClient
 		return
 	}
 
-	err := h.store.DeleteNotification(int32(notificationID))
+	notification, _ := h.store.GetNotification(int32(notificationID))
+
+	ctx := socketAuditContext(client, "delete_notification")
+	err := h.store.WithContext(ctx).DeleteNotification(int32(notificationID), 0)
 	if err != nil {
 		h.socketHandler.EmitToClient(client.ID, "error", map[string]interface{}{
 			"message": "Failed to delete notification: " + err.Error(),
@@ -161,10 +215,267 @@ func (h *NotificationHandler) handleDeleteNotificationEvent(client *SocketClient
 	h.socketHandler.EmitToAll("notification_deleted", map[string]interface{}{
 		"id": int32(notificationID),
 	})
+	h.refreshUnreadCountsFor([]*models.Notification{notification})
+}
+
+func (h *NotificationHandler) handleArchiveEvent(client *SocketClient, data interface{}, archive bool) {
+	notificationID, userID, ok := h.parseOwnedNotificationEvent(client, data)
+	if !ok {
+		return
+	}
+
+	var err error
+	if archive {
+		err = h.store.WithContext(socketAuditContext(client, "archive_notification")).ArchiveNotification(notificationID, userID)
+	} else {
+		err = h.store.WithContext(socketAuditContext(client, "unarchive_notification")).UnarchiveNotification(notificationID, userID)
+	}
+	if err != nil {
+		h.socketHandler.EmitToClient(client.ID, "error", map[string]interface{}{
+			"message": "Failed to update archive state: " + err.Error(),
+		})
+		return
+	}
+
+	h.emitArchived(context.Background(), userID, notificationID, archive)
+}
+
+func (h *NotificationHandler) handleSnoozeEvent(client *SocketClient, data interface{}) {
+	notificationID, userID, ok := h.parseOwnedNotificationEvent(client, data)
+	if !ok {
+		return
+	}
+
+	until, _ := data.(map[string]interface{})["until"].(string)
+	params, err := newSnoozeParams(notificationID, userID, until)
+	if err == nil {
+		err = h.store.WithContext(socketAuditContext(client, "snooze_notification")).SnoozeNotification(params)
+	}
+	if err != nil {
+		h.socketHandler.EmitToClient(client.ID, "error", map[string]interface{}{
+			"message": "Failed to snooze notification: " + err.Error(),
+		})
+		return
+	}
+
+	h.emitSnoozed(context.Background(), userID, notificationID, &params.Until)
+}
+
+func (h *NotificationHandler) handleUnsnoozeEvent(client *SocketClient, data interface{}) {
+	notificationID, userID, ok := h.parseOwnedNotificationEvent(client, data)
+	if !ok {
+		return
+	}
+
+	if err := h.store.WithContext(socketAuditContext(client, "unsnooze_notification")).UnsnoozeNotification(notificationID, userID); err != nil {
+		h.socketHandler.EmitToClient(client.ID, "error", map[string]interface{}{
+			"message": "Failed to unsnooze notification: " + err.Error(),
+		})
+		return
+	}
+
+	h.emitSnoozed(context.Background(), userID, notificationID, nil)
+}
+
+// parseOwnedNotificationEvent extracts the notification ID from an event sent
+// by a client that is subscribed to a user, reporting errors to the client
+func (h *NotificationHandler) parseOwnedNotificationEvent(client *SocketClient, data interface{}) (int32, int32, bool) {
+	dataMap, ok := data.(map[string]interface{})
+	if !ok {
+		h.socketHandler.EmitToClient(client.ID, "error", map[string]interface{}{
+			"message": "Invalid data format",
+		})
+		return 0, 0, false
+	}
+
+	notificationID, ok := dataMap["id"].(float64)
+	if !ok || notificationID <= 0 {
+		h.socketHandler.EmitToClient(client.ID, "error", map[string]interface{}{
+			"message": "Invalid notification ID",
+		})
+		return 0, 0, false
+	}
+
+	if client.UserID == nil {
+		h.socketHandler.EmitToClient(client.ID, "error", map[string]interface{}{
+			"message": "Subscribe to a user before changing notifications",
+		})
+		return 0, 0, false
+	}
+
+	return int32(notificationID), *client.UserID, true
+}
+
+func (h *NotificationHandler) emitArchived(ctx context.Context, userID int32, notificationID int32, archived bool) {
+	h.socketHandler.WithContext(ctx).EmitToUser(userID, "notification_archived", map[string]interface{}{
+		"id":       notificationID,
+		"archived": archived,
+	})
+	h.refreshUnreadCounts(userID)
+}
+
+func (h *NotificationHandler) emitSnoozed(ctx context.Context, userID int32, notificationID int32, until *time.Time) {
+	if until == nil {
+		h.socketHandler.WithContext(ctx).EmitToUser(userID, "notification_unsnoozed", map[string]interface{}{
+			"id": notificationID,
+		})
+	} else {
+		h.socketHandler.WithContext(ctx).EmitToUser(userID, "notification_snoozed", map[string]interface{}{
+			"id":    notificationID,
+			"until": until.Format(time.RFC3339),
+		})
+	}
+	h.refreshUnreadCounts(userID)
+}
+
+// newSnoozeParams parses and validates a snooze request
+func newSnoozeParams(notificationID int32, userID int32, until string) (*models.SnoozeNotificationParams, error) {
+	untilTime, err := time.Parse(time.RFC3339, until)
+	if err != nil {
+		return nil, fmt.Errorf("until must be RFC3339: %v", err)
+	}
+
+	params := &models.SnoozeNotificationParams{
+		ID:     notificationID,
+		UserID: userID,
+		Until:  untilTime,
+	}
+	if err := validation.ValidateStruct(params); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !untilTime.After(now) {
+		return nil, fmt.Errorf("until must be in the future")
+	}
+	if untilTime.Sub(now) > models.MaxSnoozeDuration {
+		return nil, fmt.Errorf("until cannot be more than %s in the future", models.MaxSnoozeDuration)
+	}
+
+	return params, nil
+}
+
+// Background Workers
+
+// StartSnoozeWorker periodically re-surfaces notifications whose snooze has
+// expired: they become unread again and are re-emitted to the owner's sockets
+func (h *NotificationHandler) StartSnoozeWorker(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				h.releaseExpiredSnoozes()
+			case <-h.done:
+				return
+			}
+		}
+	}()
+}
+
+func (h *NotificationHandler) releaseExpiredSnoozes() {
+	notifications, err := h.store.ReleaseExpiredSnoozes()
+	if err != nil {
+		log.Printf("Failed to release snoozed notifications: %v", err)
+		return
+	}
+
+	for _, notification := range notifications {
+		if notification.UserID == nil {
+			continue
+		}
+
+		h.socketHandler.EmitToUser(*notification.UserID, "notification_unsnoozed", map[string]interface{}{
+			"id": notification.ID,
+		})
+		h.socketHandler.EmitToUser(*notification.UserID, "notification", notificationPayload(notification))
+	}
+	h.refreshUnreadCountsFor(notifications)
+}
+
+// LoadNotificationTypes registers the custom notification types stored in the
+// database, so types added through the API survive restarts
+func (h *NotificationHandler) LoadNotificationTypes() error {
+	types, err := h.store.ListNotificationTypes()
+	if err != nil {
+		return err
+	}
+
+	for _, t := range types {
+		if t.Builtin {
+			continue
+		}
+		if err := notificationtypes.Register(t); err != nil {
+			log.Printf("Skipping notification type %q: %v", t.Name, err)
+		}
+	}
+	return nil
+}
+
+// StartRetentionWorker periodically reloads the notification types and
+// deletes notifications older than their type's retention period
+func (h *NotificationHandler) StartRetentionWorker(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				h.purgeExpiredNotifications()
+			case <-h.done:
+				return
+			}
+		}
+	}()
+}
+
+func (h *NotificationHandler) purgeExpiredNotifications() {
+	// Pick up types registered by other instances
+	if err := h.LoadNotificationTypes(); err != nil {
+		log.Printf("Failed to reload notification types: %v", err)
+	}
+
+	var purged int64
+	for _, t := range notificationtypes.Default.List() {
+		if t.RetentionDays <= 0 {
+			continue
+		}
+
+		cutoff := time.Now().AddDate(0, 0, -int(t.RetentionDays))
+		deleted, err := h.store.PurgeNotificationsBefore(t.Name, cutoff)
+		if err != nil {
+			log.Printf("Failed to purge %s notifications: %v", t.Name, err)
+			continue
+		}
+		if deleted > 0 {
+			log.Printf("Purged %d %s notifications older than %d days", deleted, t.Name, t.RetentionDays)
+		}
+		purged += deleted
+	}
+
+	if purged > 0 {
+		h.refreshUnreadCounts(h.socketHandler.ConnectedUserIDs()...)
+	}
+}
+
+// Shutdown stops the background workers
+func (h *NotificationHandler) Shutdown() {
+	h.doneOnce.Do(func() {
+		close(h.done)
+	})
 }
 
 // Backend Notification Methods (callable from anywhere in your backend)
 
+// errNotificationValidation marks errors caused by invalid input
+var errNotificationValidation = errors.New("validation failed")
+
+// errAudienceNotFound is returned when a saved audience does not exist
+var errAudienceNotFound = errors.New("audience not found")
+
 // SendNotification is the main method for sending notifications from backend
 func (h *NotificationHandler) SendNotification(
 	message string,
@@ -174,53 +485,207 @@ func (h *NotificationHandler) SendNotification(
 	persistent bool, // true = save to DB, false = real-time only
 	data map[string]interface{}, // optional extra data
 ) error {
-	// Validate input
 	params := &models.CreateNotificationParams{
 		Message:    message,
 		Type:       notificationType,
-		UserID:     targetID,
 		Persistent: persistent,
+		Data:       data,
+	}
+	target := &models.NotificationTarget{
+		Type:   targetType,
+		UserID: targetID,
+	}
+
+	_, err := h.SendNotificationToTarget(params, target)
+	return err
+}
+
+// SendNotificationToTarget sends a notification to any supported target and
+// returns the stored records. Persistent notifications to multi-recipient
+// targets (users, role, group, audience) are fanned out into one record per
+// recipient so every user can read and delete their own copy.
+func (h *NotificationHandler) SendNotificationToTarget(params *models.CreateNotificationParams, target *models.NotificationTarget) ([]*models.Notification, error) {
+	return h.sendNotificationToTarget(context.Background(), params, target)
+}
+
+// sendNotificationToTarget sends a notification on behalf of the request of ctx
+func (h *NotificationHandler) sendNotificationToTarget(ctx context.Context, params *models.CreateNotificationParams, target *models.NotificationTarget) ([]*models.Notification, error) {
+	if target == nil {
+		target = &models.NotificationTarget{Type: models.TargetAll}
+	}
+
+	if err := target.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", errNotificationValidation, err)
+	}
+
+	// Only single-user targets store the recipient on the template itself
+	params.UserID = nil
+	if target.Type == models.TargetUser {
+		params.UserID = target.UserID
 	}
 
+	// Validate input
 	if err := validation.ValidateStruct(params); err != nil {
-		return fmt.Errorf("validation failed: %v", err)
+		return nil, fmt.Errorf("%w: %v", errNotificationValidation, err)
+	}
+
+	realtime, err := checkDeliveryChannels(params)
+	if err != nil {
+		return nil, err
 	}
 
+	persistent := params.Persistent
+
 	// Create notification data
 	notificationData := map[string]interface{}{
 		"id":         fmt.Sprintf("notif_%d", time.Now().UnixNano()),
-		"message":    message,
-		"type":       notificationType,
+		"message":    params.Message,
+		"type":       params.Type,
 		"persistent": persistent,
 		"createdAt":  time.Now().Format(time.RFC3339),
-		"data":       data,
+		"data":       params.Data,
 	}
+	addTypePresentation(notificationData, params.Type)
+
+	switch target.Type {
+	case models.TargetAll, models.TargetUser:
+		var created []*models.Notification
+
+		// If persistent, save to database first
+		if persistent {
+			dbNotification, err := h.store.WithContext(ctx).CreateNotification(params)
+			if err != nil {
+				return nil, fmt.Errorf("failed to save notification to database: %w", err)
+			}
+			created = append(created, dbNotification)
+
+			// Update notification data with database ID
+			notificationData["id"] = dbNotification.ID
+			notificationData["createdAt"] = dbNotification.CreatedAt.Format(time.RFC3339)
+		}
+
+		if realtime {
+			if target.Type == models.TargetAll {
+				h.socketHandler.WithContext(ctx).EmitToAll("notification", notificationData)
+			} else {
+				h.socketHandler.WithContext(ctx).EmitToUser(*target.UserID, "notification", notificationData)
+			}
+		}
+		h.refreshUnreadCountsFor(created)
+		return created, nil
+
+	case models.TargetGroup:
+		if !persistent {
+			h.socketHandler.WithContext(ctx).EmitToGroup(target.Group, "notification", notificationData)
+			return nil, nil
+		}
+
+		// Groups only exist on the socket, so persist for the identified members
+		created, err := h.store.WithContext(ctx).CreateNotificationsForUsers(params, h.socketHandler.GetGroupUserIDs(target.Group))
+		if err != nil {
+			return nil, fmt.Errorf("failed to save notifications to database: %w", err)
+		}
+		if realtime {
+			h.emitPerRecipient(ctx, created, notificationData)
+		}
+		h.refreshUnreadCountsFor(created)
+		return created, nil
+
+	case models.TargetUsers:
+		if !persistent {
+			h.socketHandler.WithContext(ctx).EmitToUsers(target.UserIDs, "notification", notificationData)
+			return nil, nil
+		}
+
+		created, err := h.store.WithContext(ctx).CreateNotificationsForUsers(params, target.UserIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save notifications to database: %w", err)
+		}
+		if realtime {
+			h.emitPerRecipient(ctx, created, notificationData)
+		}
+		h.refreshUnreadCountsFor(created)
+		return created, nil
+
+	case models.TargetRole, models.TargetAudience:
+		filter := &models.AudienceFilter{Roles: []string{target.Role}}
+		if target.Type == models.TargetAudience {
+			audience, exists := h.store.GetAudience(target.AudienceID)
+			if !exists {
+				return nil, fmt.Errorf("%w: ID %d", errAudienceNotFound, target.AudienceID)
+			}
+			filter = &audience.Filter
+		}
+
+		if !persistent {
+			userIDs, err := h.store.ResolveAudience(filter)
+			if err != nil {
+				return nil, err
+			}
+			h.socketHandler.WithContext(ctx).EmitToUsers(userIDs, "notification", notificationData)
+			return nil, nil
+		}
 
-	// If persistent, save to database first
-	if persistent {
-		dbNotification, err := h.store.CreateNotification(params)
+		created, err := h.store.WithContext(ctx).CreateNotificationsForAudience(params, filter)
 		if err != nil {
-			return fmt.Errorf("failed to save notification to database: %w", err)
+			return nil, fmt.Errorf("failed to save notifications to database: %w", err)
 		}
+		if realtime {
+			h.emitPerRecipient(ctx, created, notificationData)
+		}
+		h.refreshUnreadCountsFor(created)
+		return created, nil
+	}
+
+	return nil, fmt.Errorf("invalid target type: %s", target.Type)
+}
+
+// checkDeliveryChannels makes sure the notification type allows the requested
+// delivery and reports whether the notification may be pushed to sockets
+func checkDeliveryChannels(params *models.CreateNotificationParams) (bool, error) {
+	notificationType, ok := notificationtypes.Lookup(params.Type)
+	if !ok {
+		return false, fmt.Errorf("%w: unknown notification type %q", errNotificationValidation, params.Type)
+	}
 
-		// Update notification data with database ID
-		notificationData["id"] = dbNotification.ID
-		notificationData["createdAt"] = dbNotification.CreatedAt.Format(time.RFC3339)
+	if params.Persistent && !notificationType.AllowsChannel(notificationtypes.ChannelInApp) {
+		return false, fmt.Errorf("%w: notification type %q cannot be stored in the inbox", errNotificationValidation, params.Type)
 	}
 
-	// Send via socket based on target type
-	switch targetType {
-	case "all":
-		h.socketHandler.EmitToAll("notification", notificationData)
-	case "user":
-		if targetID != nil {
-			h.socketHandler.EmitToUser(*targetID, "notification", notificationData)
+	realtime := notificationType.AllowsChannel(notificationtypes.ChannelRealtime)
+	if !params.Persistent && !realtime {
+		return false, fmt.Errorf("%w: notification type %q cannot be sent in realtime", errNotificationValidation, params.Type)
+	}
+
+	return realtime, nil
+}
+
+// addTypePresentation adds the registered severity and icon to a socket payload
+func addTypePresentation(payload map[string]interface{}, notificationType string) {
+	if t, ok := notificationtypes.Lookup(notificationType); ok {
+		payload["severity"] = t.Severity
+		payload["icon"] = t.Icon
+	}
+}
+
+// emitPerRecipient sends each recipient the payload with their own record ID
+func (h *NotificationHandler) emitPerRecipient(ctx context.Context, notifications []*models.Notification, notificationData map[string]interface{}) {
+	dataByUser := make(map[int32]interface{}, len(notifications))
+	for _, notification := range notifications {
+		if notification.UserID == nil {
+			continue
 		}
-	default:
-		return fmt.Errorf("invalid target type: %s", targetType)
+
+		payload := make(map[string]interface{}, len(notificationData))
+		for k, v := range notificationData {
+			payload[k] = v
+		}
+		payload["id"] = notification.ID
+		payload["createdAt"] = notification.CreatedAt.Format(time.RFC3339)
+		dataByUser[*notification.UserID] = payload
 	}
 
-	return nil
+	h.socketHandler.WithContext(ctx).EmitToEachUser("notification", dataByUser)
 }
 
 // Convenience methods for different notification types
@@ -237,22 +702,61 @@ func (h *NotificationHandler) NotifyUserWithData(userID int32, message, notifica
 	return h.SendNotification(message, notificationType, "user", &userID, persistent, data)
 }
 
+func (h *NotificationHandler) NotifyUsers(userIDs []int32, message, notificationType string, persistent bool) error {
+	target := &models.NotificationTarget{Type: models.TargetUsers, UserIDs: userIDs}
+	return h.sendToTarget(message, notificationType, target, persistent)
+}
+
+func (h *NotificationHandler) NotifyRole(role, message, notificationType string, persistent bool) error {
+	target := &models.NotificationTarget{Type: models.TargetRole, Role: role}
+	return h.sendToTarget(message, notificationType, target, persistent)
+}
+
+func (h *NotificationHandler) NotifyGroup(group, message, notificationType string, persistent bool) error {
+	target := &models.NotificationTarget{Type: models.TargetGroup, Group: group}
+	return h.sendToTarget(message, notificationType, target, persistent)
+}
+
+func (h *NotificationHandler) NotifyAudience(audienceID int32, message, notificationType string, persistent bool) error {
+	target := &models.NotificationTarget{Type: models.TargetAudience, AudienceID: audienceID}
+	return h.sendToTarget(message, notificationType, target, persistent)
+}
+
+func (h *NotificationHandler) sendToTarget(message, notificationType string, target *models.NotificationTarget, persistent bool) error {
+	params := &models.CreateNotificationParams{
+		Message:    message,
+		Type:       notificationType,
+		Persistent: persistent,
+	}
+	_, err := h.SendNotificationToTarget(params, target)
+	return err
+}
+
 // gRPC Methods with validation
 
 func (h *NotificationHandler) CreateNotification(ctx context.Context, req *pb.CreateNotificationRequest) (*pb.CreateNotificationResponse, error) {
+	if req.Target != nil {
+		return h.createTargetedNotification(ctx, req)
+	}
+
 	params := &models.CreateNotificationParams{
 		Message:    req.Message,
 		Type:       req.Type,
 		UserID:     convertToInt32Pointer(req.UserId),
 		Persistent: req.Persistent,
+		Data:       convertFromProtoData(req.Data),
+		Language:   req.Language,
 	}
 
 	// Validate input
 	if err := validation.ValidateStruct(params); err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
 	}
+	if _, err := checkDeliveryChannels(params); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
 
-	notification, err := h.store.CreateNotification(params)
+	notification, err := h.store.WithContext(ctx).CreateNotification(params)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to create notification: %v", err)
 	}
@@ -272,6 +776,31 @@ func (h *NotificationHandler) CreateNotification(ctx context.Context, req *pb.Cr
 	}, nil
 }
 
+// createTargetedNotification creates and delivers a notification for an explicit target
+func (h *NotificationHandler) createTargetedNotification(ctx context.Context, req *pb.CreateNotificationRequest) (*pb.CreateNotificationResponse, error) {
+	params := &models.CreateNotificationParams{
+		Message:    req.Message,
+		Type:       req.Type,
+		Persistent: req.Persistent,
+		Data:       convertFromProtoData(req.Data),
+		Language:   req.Language,
+	}
+
+	created, err := h.sendNotificationToTarget(ctx, params, convertFromProtoTarget(req.Target))
+	if err != nil {
+		return nil, targetErrorStatus(err, "failed to create notification")
+	}
+
+	resp := &pb.CreateNotificationResponse{
+		Recipients: int32(len(created)),
+	}
+	if len(created) > 0 {
+		resp.Notification = h.convertToProtoNotification(created[0])
+	}
+
+	return resp, nil
+}
+
 // GetNotification retrieves a notification by ID
 func (h *NotificationHandler) GetNotification(ctx context.Context, req *pb.GetNotificationRequest) (*pb.GetNotificationResponse, error) {
 	if req.Id <= 0 {
@@ -301,11 +830,23 @@ func (h *NotificationHandler) ListNotifications(ctx context.Context, req *pb.Lis
 		return nil, status.Errorf(codes.InvalidArgument, "limit cannot exceed 1000")
 	}
 
+	cursor, err := pagination.Decode(req.PageToken)
+	if err != nil || (cursor != nil && !cursor.Descending) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid page token")
+	}
+
+	// Fetch one extra row to find out whether there is a next page
+	pageSize := pageSizeOrDefault(req.Limit)
+
 	params := &models.ListNotificationsParams{
-		Limit:  req.Limit,
-		Offset: req.Offset,
-		UserID: convertToInt32Pointer(req.UserId),
-		Read:   convertToBoolPointer(req.Read, req.HasReadFilter),
+		Limit:          pageSize + 1,
+		Offset:         req.Offset,
+		UserID:         convertToInt32Pointer(req.UserId),
+		Read:           convertToBoolPointer(req.Read, req.HasReadFilter),
+		Archived:       req.Archived,
+		IncludeSnoozed: req.IncludeSnoozed,
+		Cursor:         cursor,
+		SkipTotal:      req.SkipTotal,
 	}
 
 	notifications, total, err := h.store.ListNotifications(params)
@@ -313,14 +854,73 @@ func (h *NotificationHandler) ListNotifications(ctx context.Context, req *pb.Lis
 		return nil, status.Errorf(codes.Internal, "failed to list notifications: %v", err)
 	}
 
+	hasMore := len(notifications) > int(pageSize)
+	if hasMore {
+		notifications = notifications[:pageSize]
+	}
+
 	var pbNotifications []*pb.Notification
 	for _, notification := range notifications {
 		pbNotifications = append(pbNotifications, h.convertToProtoNotification(notification))
 	}
 
+	var nextPageToken string
+	if hasMore {
+		last := notifications[len(notifications)-1]
+		nextPageToken = pagination.NextToken(true, last.CreatedAt, last.ID, true)
+	}
+
 	return &pb.ListNotificationsResponse{
 		Notifications: pbNotifications,
 		Total:         total,
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+// SearchNotifications runs a full-text search with filters and highlighted snippets
+func (h *NotificationHandler) SearchNotifications(ctx context.Context, req *pb.SearchNotificationsRequest) (*pb.SearchNotificationsResponse, error) {
+	params := &models.SearchNotificationsParams{
+		Query:      req.Query,
+		Language:   req.Language,
+		UserID:     convertToInt32Pointer(req.UserId),
+		Types:      req.Types,
+		Persistent: convertToBoolPointer(req.Persistent, req.HasPersistentFilter),
+		DataKeys:   req.DataKeys,
+		SortBy:     req.SortBy,
+		SortOrder:  req.SortOrder,
+		Limit:      req.Limit,
+		Offset:     req.Offset,
+	}
+
+	var err error
+	if params.CreatedAfter, err = parseOptionalTime(req.CreatedAfter); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "created_after must be RFC3339: %v", err)
+	}
+	if params.CreatedBefore, err = parseOptionalTime(req.CreatedBefore); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "created_before must be RFC3339: %v", err)
+	}
+
+	if err := validation.ValidateStruct(params); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
+	}
+
+	results, total, err := h.store.SearchNotifications(params)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to search notifications: %v", err)
+	}
+
+	var pbResults []*pb.NotificationSearchResult
+	for _, result := range results {
+		pbResults = append(pbResults, &pb.NotificationSearchResult{
+			Notification: h.convertToProtoNotification(result.Notification),
+			Snippet:      result.Snippet,
+			Rank:         result.Rank,
+		})
+	}
+
+	return &pb.SearchNotificationsResponse{
+		Results: pbResults,
+		Total:   total,
 	}, nil
 }
 
@@ -340,7 +940,7 @@ func (h *NotificationHandler) MarkNotificationAsRead(ctx context.Context, req *p
 		}, nil
 	}
 
-	err := h.store.MarkAsRead(req.Id, req.UserId)
+	err := h.store.WithContext(ctx).MarkAsRead(req.Id, req.UserId)
 	if err != nil {
 		return &pb.MarkNotificationAsReadResponse{
 			Success: false,
@@ -349,10 +949,11 @@ func (h *NotificationHandler) MarkNotificationAsRead(ctx context.Context, req *p
 	}
 
 	// Send socket update
-	h.socketHandler.EmitToUser(req.UserId, "notification_updated", map[string]interface{}{
+	h.socketHandler.WithContext(ctx).EmitToUser(req.UserId, "notification_updated", map[string]interface{}{
 		"id":   req.Id,
 		"read": true,
 	})
+	h.refreshUnreadCounts(req.UserId)
 
 	return &pb.MarkNotificationAsReadResponse{
 		Success: true,
@@ -376,7 +977,7 @@ func (h *NotificationHandler) MarkNotificationAsUnread(ctx context.Context, req
 		}, nil
 	}
 
-	err := h.store.MarkAsUnread(req.Id, req.UserId)
+	err := h.store.WithContext(ctx).MarkAsUnread(req.Id, req.UserId)
 	if err != nil {
 		return &pb.MarkNotificationAsUnreadResponse{
 			Success: false,
@@ -385,10 +986,11 @@ func (h *NotificationHandler) MarkNotificationAsUnread(ctx context.Context, req
 	}
 
 	// Send socket update
-	h.socketHandler.EmitToUser(req.UserId, "notification_updated", map[string]interface{}{
+	h.socketHandler.WithContext(ctx).EmitToUser(req.UserId, "notification_updated", map[string]interface{}{
 		"id":   req.Id,
 		"read": false,
 	})
+	h.refreshUnreadCounts(req.UserId)
 
 	return &pb.MarkNotificationAsUnreadResponse{
 		Success: true,
@@ -405,7 +1007,7 @@ func (h *NotificationHandler) MarkAllNotificationsAsRead(ctx context.Context, re
 		}, nil
 	}
 
-	err := h.store.MarkAllAsRead(req.UserId)
+	err := h.store.WithContext(ctx).MarkAllAsRead(req.UserId)
 	if err != nil {
 		return &pb.MarkAllNotificationsAsReadResponse{
 			Success: false,
@@ -414,9 +1016,10 @@ func (h *NotificationHandler) MarkAllNotificationsAsRead(ctx context.Context, re
 	}
 
 	// Send socket update
-	h.socketHandler.EmitToUser(req.UserId, "all_notifications_read", map[string]interface{}{
+	h.socketHandler.WithContext(ctx).EmitToUser(req.UserId, "all_notifications_read", map[string]interface{}{
 		"user_id": req.UserId,
 	})
+	h.refreshUnreadCounts(req.UserId)
 
 	return &pb.MarkAllNotificationsAsReadResponse{
 		Success: true,
@@ -424,6 +1027,172 @@ func (h *NotificationHandler) MarkAllNotificationsAsRead(ctx context.Context, re
 	}, nil
 }
 
+// ArchiveNotification hides a notification from the inbox without deleting it
+func (h *NotificationHandler) ArchiveNotification(ctx context.Context, req *pb.ArchiveNotificationRequest) (*pb.ArchiveNotificationResponse, error) {
+	if message := validateOwnedNotification(req.Id, req.UserId); message != "" {
+		return &pb.ArchiveNotificationResponse{
+			Success: false,
+			Message: message,
+		}, nil
+	}
+
+	if err := h.store.WithContext(ctx).ArchiveNotification(req.Id, req.UserId); err != nil {
+		return &pb.ArchiveNotificationResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	// Send socket update
+	h.emitArchived(ctx, req.UserId, req.Id, true)
+
+	return &pb.ArchiveNotificationResponse{
+		Success: true,
+		Message: "Notification archived",
+	}, nil
+}
+
+// UnarchiveNotification moves an archived notification back to the inbox
+func (h *NotificationHandler) UnarchiveNotification(ctx context.Context, req *pb.UnarchiveNotificationRequest) (*pb.UnarchiveNotificationResponse, error) {
+	if message := validateOwnedNotification(req.Id, req.UserId); message != "" {
+		return &pb.UnarchiveNotificationResponse{
+			Success: false,
+			Message: message,
+		}, nil
+	}
+
+	if err := h.store.WithContext(ctx).UnarchiveNotification(req.Id, req.UserId); err != nil {
+		return &pb.UnarchiveNotificationResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	// Send socket update
+	h.emitArchived(ctx, req.UserId, req.Id, false)
+
+	return &pb.UnarchiveNotificationResponse{
+		Success: true,
+		Message: "Notification moved to inbox",
+	}, nil
+}
+
+// SnoozeNotification hides a notification until the given time
+func (h *NotificationHandler) SnoozeNotification(ctx context.Context, req *pb.SnoozeNotificationRequest) (*pb.SnoozeNotificationResponse, error) {
+	if message := validateOwnedNotification(req.Id, req.UserId); message != "" {
+		return &pb.SnoozeNotificationResponse{
+			Success: false,
+			Message: message,
+		}, nil
+	}
+
+	params, err := newSnoozeParams(req.Id, req.UserId, req.Until)
+	if err != nil {
+		return &pb.SnoozeNotificationResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	if err := h.store.WithContext(ctx).SnoozeNotification(params); err != nil {
+		return &pb.SnoozeNotificationResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	// Send socket update
+	h.emitSnoozed(ctx, req.UserId, req.Id, &params.Until)
+
+	return &pb.SnoozeNotificationResponse{
+		Success: true,
+		Message: fmt.Sprintf("Notification snoozed until %s", params.Until.Format(time.RFC3339)),
+	}, nil
+}
+
+// UnsnoozeNotification returns a snoozed notification to the inbox immediately
+func (h *NotificationHandler) UnsnoozeNotification(ctx context.Context, req *pb.UnsnoozeNotificationRequest) (*pb.UnsnoozeNotificationResponse, error) {
+	if message := validateOwnedNotification(req.Id, req.UserId); message != "" {
+		return &pb.UnsnoozeNotificationResponse{
+			Success: false,
+			Message: message,
+		}, nil
+	}
+
+	if err := h.store.WithContext(ctx).UnsnoozeNotification(req.Id, req.UserId); err != nil {
+		return &pb.UnsnoozeNotificationResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	// Send socket update
+	h.emitSnoozed(ctx, req.UserId, req.Id, nil)
+
+	return &pb.UnsnoozeNotificationResponse{
+		Success: true,
+		Message: "Notification unsnoozed",
+	}, nil
+}
+
+// Notification Type Methods
+
+// ListNotificationTypes returns every registered notification type
+func (h *NotificationHandler) ListNotificationTypes(ctx context.Context, req *pb.ListNotificationTypesRequest) (*pb.ListNotificationTypesResponse, error) {
+	types := notificationtypes.Default.List()
+
+	pbTypes := make([]*pb.NotificationType, len(types))
+	for i, t := range types {
+		pbTypes[i] = convertToProtoNotificationType(t)
+	}
+
+	return &pb.ListNotificationTypesResponse{
+		Types: pbTypes,
+	}, nil
+}
+
+// RegisterNotificationType creates or updates a custom notification type
+func (h *NotificationHandler) RegisterNotificationType(ctx context.Context, req *pb.RegisterNotificationTypeRequest) (*pb.RegisterNotificationTypeResponse, error) {
+	if req.Type == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "type is required")
+	}
+
+	t := notificationtypes.Type{
+		Name:          req.Type.Name,
+		Severity:      req.Type.Severity,
+		Icon:          req.Type.Icon,
+		RetentionDays: req.Type.RetentionDays,
+		Channels:      req.Type.Channels,
+	}
+	if t.Severity == "" {
+		t.Severity = notificationtypes.SeverityInfo
+	}
+	if len(t.Channels) == 0 {
+		t.Channels = []string{notificationtypes.ChannelInApp, notificationtypes.ChannelRealtime}
+	}
+
+	if err := t.Validate(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
+	}
+	if existing, ok := notificationtypes.Lookup(t.Name); ok && existing.Builtin {
+		return nil, status.Errorf(codes.FailedPrecondition, "builtin notification type %q cannot be changed", t.Name)
+	}
+
+	if err := h.store.WithContext(ctx).SaveNotificationType(t); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to save notification type: %v", err)
+	}
+	if err := notificationtypes.Register(t); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to register notification type: %v", err)
+	}
+
+	// Send socket update
+	h.socketHandler.WithContext(ctx).EmitToAll("notification_type_registered", t)
+
+	return &pb.RegisterNotificationTypeResponse{
+		Type: convertToProtoNotificationType(t),
+	}, nil
+}
+
 // UpdateNotification updates a notification
 func (h *NotificationHandler) UpdateNotification(ctx context.Context, req *pb.UpdateNotificationRequest) (*pb.UpdateNotificationResponse, error) {
 	if req.Id <= 0 {
@@ -435,10 +1204,17 @@ func (h *NotificationHandler) UpdateNotification(ctx context.Context, req *pb.Up
 		Message: req.Message,
 		Type:    req.Type,
 		Read:    req.Read,
+		Fields:  req.GetUpdateMask().GetPaths(),
+
+		ExpectedVersion: req.ExpectedVersion,
 	}
 
 	// Validate the update parameters
-	if req.Message != "" {
+	if len(params.Fields) > 0 {
+		if err := params.Validate(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
+		}
+	} else if req.Message != "" {
 		testParams := &models.CreateNotificationParams{
 			Message: req.Message,
 			Type:    req.Type,
@@ -448,18 +1224,26 @@ func (h *NotificationHandler) UpdateNotification(ctx context.Context, req *pb.Up
 		}
 	}
 
-	notification, err := h.store.UpdateNotification(params)
+	if req.ExpectedVersion < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "expected_version cannot be negative")
+	}
+
+	notification, err := h.store.WithContext(ctx).UpdateNotification(params)
 	if err != nil {
+		if err, ok := versionConflictStatus(h.socketHandler, err, h.conflictRecipients(req.Id)); ok {
+			return nil, err
+		}
 		return nil, status.Errorf(codes.Internal, "failed to update notification: %v", err)
 	}
 
 	// Send socket update
-	h.socketHandler.EmitToAll("notification_updated", map[string]interface{}{
+	h.socketHandler.WithContext(ctx).EmitToAll("notification_updated", map[string]interface{}{
 		"id":      notification.ID,
 		"message": notification.Message,
 		"type":    notification.Type,
 		"read":    notification.Read,
 	})
+	h.refreshUnreadCountsFor([]*models.Notification{notification})
 
 	return &pb.UpdateNotificationResponse{
 		Notification: h.convertToProtoNotification(notification),
@@ -475,8 +1259,13 @@ func (h *NotificationHandler) DeleteNotification(ctx context.Context, req *pb.De
 		}, nil
 	}
 
-	err := h.store.DeleteNotification(req.Id)
+	notification, _ := h.store.GetNotification(req.Id)
+
+	err := h.store.WithContext(ctx).DeleteNotification(req.Id, req.ExpectedVersion)
 	if err != nil {
+		if err, ok := versionConflictStatus(h.socketHandler, err, h.conflictRecipients(req.Id)); ok {
+			return nil, err
+		}
 		return &pb.DeleteNotificationResponse{
 			Success: false,
 			Message: err.Error(),
@@ -484,9 +1273,10 @@ func (h *NotificationHandler) DeleteNotification(ctx context.Context, req *pb.De
 	}
 
 	// Send socket update
-	h.socketHandler.EmitToAll("notification_deleted", map[string]interface{}{
+	h.socketHandler.WithContext(ctx).EmitToAll("notification_deleted", map[string]interface{}{
 		"id": req.Id,
 	})
+	h.refreshUnreadCountsFor([]*models.Notification{notification})
 
 	return &pb.DeleteNotificationResponse{
 		Success: true,
@@ -494,6 +1284,16 @@ func (h *NotificationHandler) DeleteNotification(ctx context.Context, req *pb.De
 	}, nil
 }
 
+// conflictRecipients returns the owner of a notification for conflict events,
+// nil for global notifications so everyone is told
+func (h *NotificationHandler) conflictRecipients(id int32) []int32 {
+	notification, exists := h.store.GetNotification(id)
+	if !exists || notification.UserID == nil {
+		return nil
+	}
+	return []int32{*notification.UserID}
+}
+
 // DeleteReadNotifications deletes all read notifications for a user
 func (h *NotificationHandler) DeleteReadNotifications(ctx context.Context, req *pb.DeleteReadNotificationsRequest) (*pb.DeleteReadNotificationsResponse, error) {
 	if req.UserId <= 0 {
@@ -503,7 +1303,7 @@ func (h *NotificationHandler) DeleteReadNotifications(ctx context.Context, req *
 		}, nil
 	}
 
-	err := h.store.DeleteReadNotifications(req.UserId)
+	err := h.store.WithContext(ctx).DeleteReadNotifications(req.UserId)
 	if err != nil {
 		return &pb.DeleteReadNotificationsResponse{
 			Success: false,
@@ -512,7 +1312,7 @@ func (h *NotificationHandler) DeleteReadNotifications(ctx context.Context, req *
 	}
 
 	// Send socket update
-	h.socketHandler.EmitToUser(req.UserId, "read_notifications_deleted", map[string]interface{}{
+	h.socketHandler.WithContext(ctx).EmitToUser(req.UserId, "read_notifications_deleted", map[string]interface{}{
 		"user_id": req.UserId,
 	})
 
@@ -534,10 +1334,13 @@ func (h *NotificationHandler) GetNotificationStats(ctx context.Context, req *pb.
 	}
 
 	return &pb.GetNotificationStatsResponse{
-		Total:  stats.Total,
-		Unread: stats.Unread,
-		Read:   stats.Read,
-		ByType: stats.ByType,
+		Total:    stats.Total,
+		Unread:   stats.Unread,
+		Read:     stats.Read,
+		ByType:   stats.ByType,
+		Inbox:    stats.Inbox,
+		Archived: stats.Archived,
+		Snoozed:  stats.Snoozed,
 	}, nil
 }
 
@@ -559,20 +1362,17 @@ func (h *NotificationHandler) SendRealtimeNotification(ctx context.Context, req
 	}
 
 	// Convert data map
-	data := make(map[string]interface{})
-	for k, v := range req.Data {
-		data[k] = v
-	}
+	params.Data = convertFromProtoData(req.Data)
 
 	// Send the notification
-	targetType := "all"
-	var targetID *int32
-	if req.UserId != 0 {
-		targetType = "user"
-		targetID = &req.UserId
+	target := &models.NotificationTarget{Type: models.TargetAll}
+	if req.Target != nil {
+		target = convertFromProtoTarget(req.Target)
+	} else if req.UserId != 0 {
+		target = &models.NotificationTarget{Type: models.TargetUser, UserID: &req.UserId}
 	}
 
-	err := h.SendNotification(req.Message, req.Type, targetType, targetID, false, data)
+	_, err := h.sendNotificationToTarget(ctx, params, target)
 	if err != nil {
 		return &pb.SendRealtimeNotificationResponse{
 			Success: false,
@@ -586,6 +1386,105 @@ func (h *NotificationHandler) SendRealtimeNotification(ctx context.Context, req
 	}, nil
 }
 
+// Audience Methods
+
+// CreateAudience saves a named audience filter for later targeting
+func (h *NotificationHandler) CreateAudience(ctx context.Context, req *pb.CreateAudienceRequest) (*pb.CreateAudienceResponse, error) {
+	filter, err := convertFromProtoAudienceFilter(req.Filter)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
+	}
+
+	params := &models.CreateAudienceParams{
+		Name:        req.Name,
+		Description: req.Description,
+		Filter:      *filter,
+	}
+
+	if err := validation.ValidateStruct(params); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
+	}
+	if filter.MinAge > 0 && filter.MaxAge > 0 && filter.MinAge > filter.MaxAge {
+		return nil, status.Errorf(codes.InvalidArgument, "validation failed: min_age cannot exceed max_age")
+	}
+
+	audience, err := h.store.WithContext(ctx).CreateAudience(params)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create audience: %v", err)
+	}
+
+	userIDs, err := h.store.ResolveAudience(&audience.Filter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to resolve audience: %v", err)
+	}
+
+	return &pb.CreateAudienceResponse{
+		Audience: h.convertToProtoAudience(audience),
+		Size:     int32(len(userIDs)),
+	}, nil
+}
+
+// GetAudience retrieves a saved audience and its current size
+func (h *NotificationHandler) GetAudience(ctx context.Context, req *pb.GetAudienceRequest) (*pb.GetAudienceResponse, error) {
+	if req.Id <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "audience ID must be greater than 0")
+	}
+
+	audience, exists := h.store.GetAudience(req.Id)
+	if !exists {
+		return nil, status.Errorf(codes.NotFound, "audience with ID %d not found", req.Id)
+	}
+
+	userIDs, err := h.store.ResolveAudience(&audience.Filter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to resolve audience: %v", err)
+	}
+
+	return &pb.GetAudienceResponse{
+		Audience: h.convertToProtoAudience(audience),
+		Size:     int32(len(userIDs)),
+	}, nil
+}
+
+// ListAudiences returns all saved audiences
+func (h *NotificationHandler) ListAudiences(ctx context.Context, req *pb.ListAudiencesRequest) (*pb.ListAudiencesResponse, error) {
+	audiences, err := h.store.ListAudiences()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list audiences: %v", err)
+	}
+
+	var pbAudiences []*pb.Audience
+	for _, audience := range audiences {
+		pbAudiences = append(pbAudiences, h.convertToProtoAudience(audience))
+	}
+
+	return &pb.ListAudiencesResponse{
+		Audiences: pbAudiences,
+	}, nil
+}
+
+// DeleteAudience deletes a saved audience
+func (h *NotificationHandler) DeleteAudience(ctx context.Context, req *pb.DeleteAudienceRequest) (*pb.DeleteAudienceResponse, error) {
+	if req.Id <= 0 {
+		return &pb.DeleteAudienceResponse{
+			Success: false,
+			Message: "audience ID must be greater than 0",
+		}, nil
+	}
+
+	if err := h.store.WithContext(ctx).DeleteAudience(req.Id); err != nil {
+		return &pb.DeleteAudienceResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	return &pb.DeleteAudienceResponse{
+		Success: true,
+		Message: fmt.Sprintf("Audience with ID %d successfully deleted", req.Id),
+	}, nil
+}
+
 // Helper methods
 func (h *NotificationHandler) convertToProtoNotification(notification *models.Notification) *pb.Notification {
 	var userID int32
@@ -593,16 +1492,201 @@ func (h *NotificationHandler) convertToProtoNotification(notification *models.No
 		userID = *notification.UserID
 	}
 
+	var severity, icon string
+	if t, ok := notificationtypes.Lookup(notification.Type); ok {
+		severity = t.Severity
+		icon = t.Icon
+	}
+
+	var archivedAt, snoozedUntil string
+	if notification.ArchivedAt != nil {
+		archivedAt = notification.ArchivedAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+	if notification.SnoozedUntil != nil {
+		snoozedUntil = notification.SnoozedUntil.Format("2006-01-02T15:04:05Z07:00")
+	}
+
 	return &pb.Notification{
-		Id:         notification.ID,
-		Message:    notification.Message,
-		Type:       notification.Type,
-		UserId:     userID,
-		Read:       notification.Read,
-		Persistent: notification.Persistent,
-		CreatedAt:  notification.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt:  notification.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		Id:           notification.ID,
+		Message:      notification.Message,
+		Type:         notification.Type,
+		UserId:       userID,
+		Read:         notification.Read,
+		Persistent:   notification.Persistent,
+		CreatedAt:    notification.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:    notification.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		Data:         convertToProtoData(notification.Data),
+		Language:     notification.Language,
+		Version:      notification.Version,
+		ArchivedAt:   archivedAt,
+		SnoozedUntil: snoozedUntil,
+		Severity:     severity,
+		Icon:         icon,
+	}
+}
+
+func (h *NotificationHandler) convertToProtoAudience(audience *models.Audience) *pb.Audience {
+	filter := &pb.AudienceFilter{
+		Roles:        audience.Filter.Roles,
+		MinAge:       audience.Filter.MinAge,
+		MaxAge:       audience.Filter.MaxAge,
+		EmailDomain:  audience.Filter.EmailDomain,
+		NameContains: audience.Filter.NameContains,
+	}
+	if audience.Filter.CreatedAfter != nil {
+		filter.CreatedAfter = audience.Filter.CreatedAfter.Format(time.RFC3339)
+	}
+	if audience.Filter.CreatedBefore != nil {
+		filter.CreatedBefore = audience.Filter.CreatedBefore.Format(time.RFC3339)
+	}
+
+	return &pb.Audience{
+		Id:          audience.ID,
+		Name:        audience.Name,
+		Description: audience.Description,
+		Filter:      filter,
+		CreatedAt:   audience.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:   audience.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// notificationPayload builds the socket payload for a stored notification
+func notificationPayload(notification *models.Notification) map[string]interface{} {
+	payload := map[string]interface{}{
+		"id":         notification.ID,
+		"message":    notification.Message,
+		"type":       notification.Type,
+		"persistent": notification.Persistent,
+		"createdAt":  notification.CreatedAt.Format(time.RFC3339),
+		"data":       notification.Data,
+	}
+	addTypePresentation(payload, notification.Type)
+	return payload
+}
+
+func convertToProtoNotificationType(t notificationtypes.Type) *pb.NotificationType {
+	return &pb.NotificationType{
+		Name:          t.Name,
+		Severity:      t.Severity,
+		Icon:          t.Icon,
+		RetentionDays: t.RetentionDays,
+		Channels:      t.Channels,
+		Builtin:       t.Builtin,
+	}
+}
+
+// validateOwnedNotification checks the IDs of a per-user notification request
+func validateOwnedNotification(id int32, userID int32) string {
+	if id <= 0 {
+		return "notification ID must be greater than 0"
+	}
+	if userID <= 0 {
+		return "user ID must be greater than 0"
+	}
+	return ""
+}
+
+func convertFromProtoTarget(target *pb.NotificationTarget) *models.NotificationTarget {
+	return &models.NotificationTarget{
+		Type:       target.Type,
+		UserID:     convertToInt32Pointer(target.UserId),
+		UserIDs:    target.UserIds,
+		Role:       target.Role,
+		Group:      target.Group,
+		AudienceID: target.AudienceId,
+	}
+}
+
+func convertFromProtoAudienceFilter(filter *pb.AudienceFilter) (*models.AudienceFilter, error) {
+	if filter == nil {
+		return &models.AudienceFilter{}, nil
+	}
+
+	result := &models.AudienceFilter{
+		Roles:        filter.Roles,
+		MinAge:       filter.MinAge,
+		MaxAge:       filter.MaxAge,
+		EmailDomain:  filter.EmailDomain,
+		NameContains: filter.NameContains,
+	}
+
+	if filter.CreatedAfter != "" {
+		t, err := time.Parse(time.RFC3339, filter.CreatedAfter)
+		if err != nil {
+			return nil, fmt.Errorf("created_after must be RFC3339: %v", err)
+		}
+		result.CreatedAfter = &t
+	}
+	if filter.CreatedBefore != "" {
+		t, err := time.Parse(time.RFC3339, filter.CreatedBefore)
+		if err != nil {
+			return nil, fmt.Errorf("created_before must be RFC3339: %v", err)
+		}
+		result.CreatedBefore = &t
+	}
+
+	return result, nil
+}
+
+// targetErrorStatus maps delivery errors to gRPC status codes
+func targetErrorStatus(err error, message string) error {
+	switch {
+	case errors.Is(err, errNotificationValidation):
+		return status.Errorf(codes.InvalidArgument, "%v", err)
+	case errors.Is(err, errAudienceNotFound):
+		return status.Errorf(codes.NotFound, "%v", err)
+	default:
+		return status.Errorf(codes.Internal, "%s: %v", message, err)
+	}
+}
+
+func convertFromProtoData(data map[string]string) map[string]interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+
+	result := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		result[k] = v
+	}
+	return result
+}
+
+func convertToProtoData(data map[string]interface{}) map[string]string {
+	if len(data) == 0 {
+		return nil
+	}
+
+	result := make(map[string]string, len(data))
+	for k, v := range data {
+		if str, ok := v.(string); ok {
+			result[k] = str
+			continue
+		}
+		encoded, _ := json.Marshal(v)
+		result[k] = string(encoded)
+	}
+	return result
+}
+
+func parseOptionalTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// pageSizeOrDefault applies the default page size of the list RPCs
+func pageSizeOrDefault(limit int32) int32 {
+	if limit <= 0 {
+		return defaultPageSize
 	}
+	return limit
 }
 
 func convertToInt32Pointer(val int32) *int32 {
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/oidc"
+	"backend-grpc-server/internal/oidc/oidctest"
+	"backend-grpc-server/internal/storage"
+	"backend-grpc-server/internal/testutil"
+	pb "backend-grpc-server/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthHandler_OIDCLogin(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	idp := oidctest.NewServer("client", "secret")
+	defer idp.Close()
+
+	handler := newTestAuthHandler(db, &recordingSender{}, 0)
+	handler.config.OIDCProvision = true
+	handler.config.OIDCDefaultRole = "user"
+	handler.EnableOIDC(oidc.NewProvider(oidc.Config{
+		Issuer:       idp.Issuer(),
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:3000/auth/oidc/callback",
+	}), storage.NewPostgresIdentityStore(db))
+	ctx := context.Background()
+
+	login := func(claims map[string]interface{}) (*pb.LoginResponse, error) {
+		started, err := handler.StartOIDCLogin(ctx, &pb.StartOIDCLoginRequest{})
+		require.NoError(t, err)
+		code, state, err := idp.Authorize(started.AuthorizationUrl, claims)
+		require.NoError(t, err)
+		return handler.CompleteOIDCLogin(ctx, &pb.CompleteOIDCLoginRequest{State: state, Code: code})
+	}
+
+	// Unknown accounts are provisioned
+	provisioned, err := login(map[string]interface{}{"sub": "sso-1", "email": "sso@example.com", "email_verified": true, "name": "Single Sign"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, provisioned.AccessToken)
+	assert.Equal(t, "Single Sign", provisioned.User.Name)
+	assert.Equal(t, "user", provisioned.User.Role)
+	assert.True(t, provisioned.User.EmailVerified)
+
+	// The same account maps to the same user, even with a new address
+	again, err := login(map[string]interface{}{"sub": "sso-1", "email": "renamed@example.com", "email_verified": true})
+	require.NoError(t, err)
+	assert.Equal(t, provisioned.User.Id, again.User.Id)
+
+	// Existing users are linked by verified email
+	existing, err := storage.NewPostgresUserStore(db).CreateUser(&models.CreateUserParams{Name: "Existing", Email: "existing@example.com", Age: 40, Role: "admin"})
+	require.NoError(t, err)
+	linked, err := login(map[string]interface{}{"sub": "sso-2", "email": "Existing@Example.com", "email_verified": true})
+	require.NoError(t, err)
+	assert.Equal(t, existing.ID, linked.User.Id)
+	assert.Equal(t, "admin", linked.User.Role)
+
+	// An unverified address can't take over an existing account
+	_, err = login(map[string]interface{}{"sub": "sso-3", "email": "existing@example.com", "email_verified": false})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	// Without provisioning only known users get in
+	handler.config.OIDCProvision = false
+	_, err = login(map[string]interface{}{"sub": "sso-4", "email": "new@example.com", "email_verified": true})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// States can't be reused
+	started, err := handler.StartOIDCLogin(ctx, &pb.StartOIDCLoginRequest{})
+	require.NoError(t, err)
+	code, state, err := idp.Authorize(started.AuthorizationUrl, map[string]interface{}{"sub": "sso-1"})
+	require.NoError(t, err)
+	_, err = handler.CompleteOIDCLogin(ctx, &pb.CompleteOIDCLoginRequest{State: state, Code: code})
+	require.NoError(t, err)
+	_, err = handler.CompleteOIDCLogin(ctx, &pb.CompleteOIDCLoginRequest{State: state, Code: code})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	authURL, err := url.Parse(started.AuthorizationUrl)
+	require.NoError(t, err)
+	assert.NotEmpty(t, authURL.Query().Get("code_challenge"))
+}
+
+func TestAuthHandler_OIDCDisabled(t *testing.T) {
+	handler := &AuthHandler{}
+
+	_, err := handler.StartOIDCLogin(context.Background(), &pb.StartOIDCLoginRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	_, err = handler.CompleteOIDCLogin(context.Background(), &pb.CompleteOIDCLoginRequest{State: "state", Code: "code"})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"backend-grpc-server/internal/auth"
+	"backend-grpc-server/internal/mail"
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/storage"
+	pb "backend-grpc-server/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// passwordResetSentMessage is the answer to every accepted reset request, so
+// it can't be used to find out which addresses have an account
+const passwordResetSentMessage = "If an account exists for this address, a password reset link has been sent to it"
+
+// RequestPasswordReset mails a password reset link if the address belongs to a user
+func (h *AuthHandler) RequestPasswordReset(ctx context.Context, req *pb.RequestPasswordResetRequest) (*pb.RequestPasswordResetResponse, error) {
+	params := &models.RequestPasswordResetParams{Email: strings.TrimSpace(req.Email)}
+	if err := params.Validate(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
+	}
+
+	// Limits apply to every address alike, so hitting them reveals nothing
+	now := time.Now()
+	if allowed, wait := h.resetsByIP.Allow(clientIP(ctx), now); !allowed {
+		return nil, status.Errorf(codes.ResourceExhausted, "too many password reset requests, try again in %s", wait.Round(time.Second))
+	}
+	if allowed, wait := h.resetsByEmail.Allow(strings.ToLower(params.Email), now); !allowed {
+		return nil, status.Errorf(codes.ResourceExhausted, "too many password reset requests, try again in %s", wait.Round(time.Second))
+	}
+
+	if user, exists := h.users.GetUserByEmail(params.Email); exists {
+		// Failures are only logged, an error would tell the account exists
+		if err := h.sendPasswordReset(ctx, user); err != nil {
+			log.Printf("Failed to send password reset to user %d: %v", user.ID, err)
+		}
+	}
+
+	return &pb.RequestPasswordResetResponse{
+		Success: true,
+		Message: passwordResetSentMessage,
+	}, nil
+}
+
+// ResetPassword sets a new password with the token from a reset link and
+// signs out all existing logins of the user
+func (h *AuthHandler) ResetPassword(ctx context.Context, req *pb.ResetPasswordRequest) (*pb.ResetPasswordResponse, error) {
+	if req.Token == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "token is required")
+	}
+	if err := auth.ValidatePassword(req.NewPassword); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
+	passwordHash, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+
+	user, err := h.store.WithContext(ctx).ResetPassword(auth.HashToken(req.Token), passwordHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrPasswordResetInvalid) {
+			return nil, status.Errorf(codes.NotFound, "%v", err)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to reset password: %v", err)
+	}
+
+	h.socketHandler.CloseUserSessions(user.ID)
+
+	if err := h.mailer.Send(passwordChangedNotice(user)); err != nil {
+		log.Printf("Failed to notify user %d about password reset: %v", user.ID, err)
+	}
+
+	return &pb.ResetPasswordResponse{
+		Success: true,
+		Message: "Password has been reset, please log in again",
+	}, nil
+}
+
+// sendPasswordReset stores a reset token for the user and mails its link
+func (h *AuthHandler) sendPasswordReset(ctx context.Context, user *models.User) error {
+	token, tokenHash, err := auth.NewToken()
+	if err != nil {
+		return err
+	}
+
+	reset, err := h.store.WithContext(ctx).CreatePasswordReset(user.ID, tokenHash, time.Now().Add(h.config.PasswordResetValidity), clientIP(ctx))
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", h.config.BaseURL, url.QueryEscape(token))
+
+	return h.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"A password reset was requested for your account.\n\nChoose a new password here:\n%s\n\nThe link expires on %s. If you didn't ask for this, you can ignore this email.\n",
+			link,
+			reset.ExpiresAt.Format("2006-01-02 15:04 MST"),
+		),
+	})
+}
+
+// passwordChangedNotice tells the user their password was reset
+func passwordChangedNotice(user *models.User) mail.Message {
+	return mail.Message{
+		To:      user.Email,
+		Subject: "Your password was changed",
+		Body:    "The password of your account was reset and all devices were signed out.\n\nIf this wasn't you, contact an administrator right away.\n",
+	}
+}
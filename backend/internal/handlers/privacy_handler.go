@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/privacy"
+	"backend-grpc-server/internal/storage"
+	pb "backend-grpc-server/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PrivacyHandler handles the GDPR export and erasure requests
+type PrivacyHandler struct {
+	pb.UnimplementedPrivacyServiceServer
+	service       *privacy.Service
+	userStore     storage.UserStore
+	store         storage.PrivacyStore
+	socketHandler *SocketHandler
+}
+
+// NewPrivacyHandler creates a new privacy handler
+func NewPrivacyHandler(service *privacy.Service, userStore storage.UserStore, store storage.PrivacyStore, socketHandler *SocketHandler) *PrivacyHandler {
+	return &PrivacyHandler{
+		service:       service,
+		userStore:     userStore,
+		store:         store,
+		socketHandler: socketHandler,
+	}
+}
+
+// ExportUserData returns everything stored about a user as a JSON document or
+// ZIP archive. Users export their own data, admins anyone's.
+func (h *PrivacyHandler) ExportUserData(ctx context.Context, req *pb.ExportUserDataRequest) (*pb.ExportUserDataResponse, error) {
+	if req.UserId < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "user ID cannot be negative")
+	}
+	if req.Format != "" && req.Format != privacy.FormatJSON && req.Format != privacy.FormatZIP {
+		return nil, status.Errorf(codes.InvalidArgument, "format must be json or zip")
+	}
+	userID, err := ownUserID(ctx, req.UserId, "export user data")
+	if err != nil {
+		return nil, err
+	}
+
+	// Soft deleted users keep their right to access
+	if _, exists := h.userStore.GetUserIncludingDeleted(userID); !exists {
+		return nil, status.Errorf(codes.NotFound, "user with ID %d not found", userID)
+	}
+
+	export, err := h.service.Export(userID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to export user data: %v", err)
+	}
+
+	data, filename, contentType, err := export.Encode(req.Format)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+
+	return &pb.ExportUserDataResponse{
+		Filename:    filename,
+		ContentType: contentType,
+		Data:        data,
+	}, nil
+}
+
+// EraseUserData anonymizes or deletes a user's personal data in every store
+// and returns the erasure certificate
+func (h *PrivacyHandler) EraseUserData(ctx context.Context, req *pb.EraseUserDataRequest) (*pb.EraseUserDataResponse, error) {
+	params := &models.EraseUserParams{
+		UserID:      req.UserId,
+		Mode:        req.Mode,
+		RequestedBy: req.RequestedBy,
+		Reason:      req.Reason,
+	}
+
+	if err := params.Validate(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
+	}
+
+	if _, exists := h.userStore.GetUserIncludingDeleted(req.UserId); !exists {
+		return nil, status.Errorf(codes.NotFound, "user with ID %d not found", req.UserId)
+	}
+
+	certificate, err := h.service.Erase(ctx, params)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to erase user data: %v", err)
+	}
+
+	// The user is gone from every list
+	h.socketHandler.WithContext(ctx).EmitToAll("user_deleted", map[string]interface{}{
+		"id": req.UserId,
+	})
+
+	return &pb.EraseUserDataResponse{
+		Certificate: convertToProtoCertificate(certificate),
+	}, nil
+}
+
+// GetErasureCertificate returns a certificate and whether it still verifies
+func (h *PrivacyHandler) GetErasureCertificate(ctx context.Context, req *pb.GetErasureCertificateRequest) (*pb.GetErasureCertificateResponse, error) {
+	if req.Id <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "certificate ID must be greater than 0")
+	}
+
+	certificate, exists := h.store.GetErasureCertificate(req.Id)
+	if !exists {
+		return nil, status.Errorf(codes.NotFound, "erasure certificate with ID %d not found", req.Id)
+	}
+
+	return &pb.GetErasureCertificateResponse{
+		Certificate: convertToProtoCertificate(certificate),
+	}, nil
+}
+
+// ListErasureCertificates returns the certificates for a user, or all of them
+func (h *PrivacyHandler) ListErasureCertificates(ctx context.Context, req *pb.ListErasureCertificatesRequest) (*pb.ListErasureCertificatesResponse, error) {
+	if req.UserId < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "user ID cannot be negative")
+	}
+
+	certificates, err := h.store.ListErasureCertificates(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list erasure certificates: %v", err)
+	}
+
+	pbCertificates := make([]*pb.ErasureCertificate, 0, len(certificates))
+	for _, certificate := range certificates {
+		pbCertificates = append(pbCertificates, convertToProtoCertificate(certificate))
+	}
+
+	return &pb.ListErasureCertificatesResponse{
+		Certificates: pbCertificates,
+	}, nil
+}
+
+func convertToProtoCertificate(certificate *models.ErasureCertificate) *pb.ErasureCertificate {
+	return &pb.ErasureCertificate{
+		Id:          certificate.ID,
+		UserId:      certificate.UserID,
+		Mode:        certificate.Mode,
+		RequestedBy: certificate.RequestedBy,
+		Reason:      certificate.Reason,
+		Sources:     certificate.Sources,
+		Digest:      certificate.Digest,
+		ErasedAt:    certificate.ErasedAt.Format("2006-01-02T15:04:05Z07:00"),
+		Verified:    privacy.Verify(certificate),
+	}
+}
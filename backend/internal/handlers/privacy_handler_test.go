@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"backend-grpc-server/internal/auth"
+	pb "backend-grpc-server/pb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPrivacyHandler_ExportUserDataPrincipal(t *testing.T) {
+	handler := &PrivacyHandler{}
+
+	_, err := handler.ExportUserData(context.Background(), &pb.ExportUserDataRequest{UserId: 1})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	apiKeyCtx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, APIKeyID: 3})
+	_, err = handler.ExportUserData(apiKeyCtx, &pb.ExportUserDataRequest{UserId: 1})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	impersonatorCtx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, Role: "user", ActorID: 2, ImpersonationID: 5})
+	_, err = handler.ExportUserData(impersonatorCtx, &pb.ExportUserDataRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	userCtx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 2, Role: "user"})
+	_, err = handler.ExportUserData(userCtx, &pb.ExportUserDataRequest{UserId: 1})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
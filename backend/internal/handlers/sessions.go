@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"backend-grpc-server/internal/auth"
+	"backend-grpc-server/internal/models"
+	pb "backend-grpc-server/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ListSessions returns the sessions of the signed in user, or of any user
+// for admins, most recently used first
+func (h *AuthHandler) ListSessions(ctx context.Context, req *pb.ListSessionsRequest) (*pb.ListSessionsResponse, error) {
+	principal, err := sessionPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userID := req.UserId
+	if userID == 0 {
+		userID = principal.UserID
+	}
+	if userID != principal.UserID && principal.Role != "admin" {
+		return nil, status.Errorf(codes.PermissionDenied, "only admins can list the sessions of other users")
+	}
+
+	sessions, err := h.store.ListSessions(userID, !req.IncludeInactive)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list sessions: %v", err)
+	}
+
+	connections := h.socketHandler.SessionConnections()
+	pbSessions := make([]*pb.Session, 0, len(sessions))
+	for _, session := range sessions {
+		pbSession := convertToProtoSession(session)
+		pbSession.Current = session.ID == principal.SessionID
+		pbSession.Connections = int32(connections[session.ID])
+		pbSessions = append(pbSessions, pbSession)
+	}
+
+	return &pb.ListSessionsResponse{
+		Sessions: pbSessions,
+	}, nil
+}
+
+// RevokeSession ends a session and disconnects its sockets. Users may revoke
+// their own sessions, admins anyone's.
+func (h *AuthHandler) RevokeSession(ctx context.Context, req *pb.RevokeSessionRequest) (*pb.RevokeSessionResponse, error) {
+	principal, err := sessionPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Other users' sessions look the same as missing ones to non-admins
+	session, exists := h.store.GetSession(req.Id)
+	if !exists || (session.UserID != principal.UserID && principal.Role != "admin") {
+		return &pb.RevokeSessionResponse{
+			Success: false,
+			Message: fmt.Sprintf("session with ID %d not found", req.Id),
+		}, nil
+	}
+
+	if err := h.store.WithContext(ctx).RevokeSession(session.ID); err != nil {
+		return &pb.RevokeSessionResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	closed := h.socketHandler.CloseSession(session.ID)
+
+	h.socketHandler.WithContext(ctx).EmitToUser(session.UserID, "session_revoked", map[string]interface{}{
+		"id": session.ID,
+	})
+
+	return &pb.RevokeSessionResponse{
+		Success:           true,
+		Message:           fmt.Sprintf("Session with ID %d successfully revoked", session.ID),
+		ClosedConnections: int32(closed),
+	}, nil
+}
+
+// sessionPrincipal returns the signed in user of a request. Sessions belong
+// to users, so API keys don't have any.
+func sessionPrincipal(ctx context.Context) (*auth.Principal, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok || principal.IsAPIKey() {
+		return nil, status.Errorf(codes.Unauthenticated, "sign in to manage sessions")
+	}
+	return principal, nil
+}
+
+func convertToProtoSession(session *models.Session) *pb.Session {
+	var revokedAt string
+	if session.RevokedAt != nil {
+		revokedAt = session.RevokedAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+
+	return &pb.Session{
+		Id:         session.ID,
+		UserId:     session.UserID,
+		Device:     session.Device,
+		UserAgent:  session.UserAgent,
+		ClientIp:   session.ClientIP,
+		Status:     session.Status(time.Now()),
+		CreatedAt:  session.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		LastSeenAt: session.LastSeenAt.Format("2006-01-02T15:04:05Z07:00"),
+		ExpiresAt:  session.ExpiresAt.Format("2006-01-02T15:04:05Z07:00"),
+		RevokedAt:  revokedAt,
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+
+	"backend-grpc-server/internal/auth"
+)
+
+// SocketEmitter emits events caused by a request, marked with the principal
+// that made it. Events of anonymous requests are sent unmarked.
+type SocketEmitter struct {
+	handler *SocketHandler
+	actor   *SocketActor
+}
+
+// WithContext returns an emitter for events caused by the request of ctx
+func (h *SocketHandler) WithContext(ctx context.Context) *SocketEmitter {
+	emitter := &SocketEmitter{handler: h}
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		emitter.actor = &SocketActor{
+			UserID:         principal.UserID,
+			APIKeyID:       principal.APIKeyID,
+			ImpersonatedBy: principal.ActorID,
+		}
+	}
+	return emitter
+}
+
+// EmitToAll sends event to all connected clients
+func (e *SocketEmitter) EmitToAll(event string, data interface{}) {
+	e.handler.emitToAll(e.message(event, data))
+}
+
+// EmitToUser sends event to specific user
+func (e *SocketEmitter) EmitToUser(userID int32, event string, data interface{}) {
+	e.handler.emitToUser(userID, e.message(event, data))
+}
+
+// EmitToUsers sends event to every client subscribed to one of the given users
+func (e *SocketEmitter) EmitToUsers(userIDs []int32, event string, data interface{}) {
+	e.handler.emitToUsers(userIDs, e.message(event, data))
+}
+
+// EmitToEachUser sends a user-specific payload to every client subscribed to a
+// user in dataByUser
+func (e *SocketEmitter) EmitToEachUser(event string, dataByUser map[int32]interface{}) {
+	e.handler.emitToEachUser(event, dataByUser, e.actor)
+}
+
+// EmitToGroup sends event to all clients in a group
+func (e *SocketEmitter) EmitToGroup(groupName string, event string, data interface{}) {
+	e.handler.emitToGroup(groupName, e.message(event, data))
+}
+
+func (e *SocketEmitter) message(event string, data interface{}) SocketMessage {
+	return SocketMessage{
+		Event: event,
+		Data:  data,
+		Actor: e.actor,
+	}
+}
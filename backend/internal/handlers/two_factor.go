@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"backend-grpc-server/internal/auth"
+	"backend-grpc-server/internal/mail"
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/storage"
+	pb "backend-grpc-server/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// VerifyTwoFactor completes a login with a TOTP or recovery code
+func (h *AuthHandler) VerifyTwoFactor(ctx context.Context, req *pb.VerifyTwoFactorRequest) (*pb.LoginResponse, error) {
+	now := time.Now()
+	claims, err := h.challenges.Verify(req.TwoFactorToken, now)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "two-factor token is invalid or has expired, log in again")
+	}
+
+	if allowed, wait := h.twoFactorAttempts.Allow(fmt.Sprint(claims.UserID), now); !allowed {
+		return nil, status.Errorf(codes.ResourceExhausted, "too many attempts, try again in %s", wait.Round(time.Second))
+	}
+
+	user, exists := h.users.GetUser(claims.UserID)
+	if !exists {
+		return nil, status.Errorf(codes.Unauthenticated, "two-factor token is invalid or has expired, log in again")
+	}
+
+	ok, err := h.checkSecondFactor(user.ID, req.Code, now)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+	if !ok {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid two-factor code")
+	}
+
+	return h.startLogin(ctx, user)
+}
+
+// StartTOTPEnrollment creates a new TOTP secret for a user without two-factor
+// authentication. Users enroll themselves, admins may enroll anyone.
+func (h *AuthHandler) StartTOTPEnrollment(ctx context.Context, req *pb.StartTOTPEnrollmentRequest) (*pb.StartTOTPEnrollmentResponse, error) {
+	userID, err := ownUserID(ctx, req.UserId, "set up two-factor authentication")
+	if err != nil {
+		return nil, err
+	}
+
+	user, exists := h.users.GetUser(userID)
+	if !exists {
+		return nil, status.Errorf(codes.NotFound, "user with ID %d not found", userID)
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+
+	if err := h.store.WithContext(ctx).StartTOTPEnrollment(user.ID, secret); err != nil {
+		if errors.Is(err, storage.ErrTwoFactorState) {
+			return nil, status.Errorf(codes.FailedPrecondition, "two-factor authentication is already enabled")
+		}
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+
+	return &pb.StartTOTPEnrollmentResponse{
+		Secret:     secret,
+		OtpauthUri: auth.TOTPURI(h.config.TOTPIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTPEnrollment enables two-factor authentication once the user
+// proves their app produces valid codes, and hands out the recovery codes
+func (h *AuthHandler) ConfirmTOTPEnrollment(ctx context.Context, req *pb.ConfirmTOTPEnrollmentRequest) (*pb.ConfirmTOTPEnrollmentResponse, error) {
+	userID, err := ownUserID(ctx, req.UserId, "set up two-factor authentication")
+	if err != nil {
+		return nil, err
+	}
+
+	twoFactor, exists := h.store.GetTwoFactor(userID)
+	if !exists {
+		return nil, status.Errorf(codes.NotFound, "user with ID %d not found", userID)
+	}
+	if twoFactor.Enabled() || twoFactor.Secret == "" {
+		return nil, status.Errorf(codes.FailedPrecondition, "no two-factor enrollment in progress")
+	}
+
+	step, ok := auth.ValidateTOTP(twoFactor.Secret, req.Code, time.Now())
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid two-factor code")
+	}
+
+	recoveryCodes, hashes, err := auth.NewRecoveryCodes(auth.RecoveryCodeCount)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+
+	if err := h.store.WithContext(ctx).EnableTOTP(twoFactor.UserID, step, hashes); err != nil {
+		if errors.Is(err, storage.ErrTwoFactorState) {
+			return nil, status.Errorf(codes.FailedPrecondition, "no two-factor enrollment in progress")
+		}
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+
+	h.emitTwoFactorChanged(ctx, twoFactor.UserID, true)
+
+	return &pb.ConfirmTOTPEnrollmentResponse{
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+// ResetTwoFactor turns two-factor authentication off for a user, who is told by mail
+func (h *AuthHandler) ResetTwoFactor(ctx context.Context, req *pb.ResetTwoFactorRequest) (*pb.ResetTwoFactorResponse, error) {
+	user, exists := h.users.GetUser(req.UserId)
+	if !exists {
+		return &pb.ResetTwoFactorResponse{
+			Success: false,
+			Message: fmt.Sprintf("user with ID %d not found", req.UserId),
+		}, nil
+	}
+
+	if err := h.store.WithContext(ctx).ResetTwoFactor(user.ID); err != nil {
+		return &pb.ResetTwoFactorResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	if err := h.mailer.Send(twoFactorResetNotice(user)); err != nil {
+		log.Printf("Failed to notify user %d about two-factor reset: %v", user.ID, err)
+	}
+
+	h.emitTwoFactorChanged(ctx, user.ID, false)
+
+	return &pb.ResetTwoFactorResponse{
+		Success: true,
+		Message: fmt.Sprintf("Two-factor authentication of user %d has been reset", user.ID),
+	}, nil
+}
+
+// checkSecondFactor accepts a TOTP code that wasn't used before or an unused
+// recovery code
+func (h *AuthHandler) checkSecondFactor(userID int32, code string, now time.Time) (bool, error) {
+	twoFactor, exists := h.store.GetTwoFactor(userID)
+	if !exists || !twoFactor.Enabled() {
+		return false, nil
+	}
+
+	if step, ok := auth.ValidateTOTP(twoFactor.Secret, code, now); ok {
+		return h.store.UseTOTPStep(userID, step)
+	}
+
+	used, err := h.store.UseRecoveryCode(userID, auth.HashRecoveryCode(code))
+	if err != nil || !used {
+		return false, err
+	}
+
+	remaining, err := h.store.CountRecoveryCodes(userID)
+	if err != nil {
+		log.Printf("Failed to count recovery codes of user %d: %v", userID, err)
+	} else {
+		h.socketHandler.EmitToUser(userID, "notification", map[string]interface{}{
+			"id":         fmt.Sprintf("recovery_code_used_%d_%d", userID, now.Unix()),
+			"message":    fmt.Sprintf("A recovery code was used to sign in, %d left", remaining),
+			"type":       "warning",
+			"persistent": true,
+			"createdAt":  now.Format("2006-01-02T15:04:05Z07:00"),
+		})
+	}
+
+	return true, nil
+}
+
+// emitTwoFactorChanged sends a socket event about the two-factor state of a user
+func (h *AuthHandler) emitTwoFactorChanged(ctx context.Context, userID int32, enabled bool) {
+	h.socketHandler.WithContext(ctx).EmitToAll("two_factor_changed", map[string]interface{}{
+		"id":      userID,
+		"enabled": enabled,
+	})
+}
+
+// twoFactorResetNotice tells the user their two-factor authentication was turned off
+func twoFactorResetNotice(user *models.User) mail.Message {
+	return mail.Message{
+		To:      user.Email,
+		Subject: "Two-factor authentication was turned off",
+		Body:    "An administrator turned off two-factor authentication for your account. You can enroll your authenticator again after logging in.\n\nIf you didn't ask for this, contact an administrator right away.\n",
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"log"
+	"sync"
+
+	"backend-grpc-server/internal/models"
+)
+
+// unreadCounter caches the unread inbox count of users with connected
+// sockets. Counts are reloaded from the database after every change instead
+// of being incremented, so the cache cannot drift from the database.
+type unreadCounter struct {
+	mu     sync.Mutex
+	counts map[int32]int32
+
+	// Serializes reload and update so an older count never overwrites a newer one
+	refreshMu sync.Mutex
+}
+
+func newUnreadCounter() *unreadCounter {
+	return &unreadCounter{counts: make(map[int32]int32)}
+}
+
+func (c *unreadCounter) get(userID int32) (int32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	count, ok := c.counts[userID]
+	return count, ok
+}
+
+// update stores the count and reports whether it changed
+func (c *unreadCounter) update(userID int32, count int32) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous, ok := c.counts[userID]
+	c.counts[userID] = count
+	return !ok || previous != count
+}
+
+func (c *unreadCounter) forget(userID int32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.counts, userID)
+}
+
+// refreshUnreadCounts reloads the unread counts of the given users and pushes
+// an unread_count event to every user whose count changed. Users without a
+// connected socket are skipped and dropped from the cache.
+func (h *NotificationHandler) refreshUnreadCounts(userIDs ...int32) {
+	seen := make(map[int32]bool, len(userIDs))
+	var connected []int32
+	for _, userID := range userIDs {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+
+		if h.socketHandler.IsUserConnected(userID) {
+			connected = append(connected, userID)
+		} else {
+			h.unreadCounts.forget(userID)
+		}
+	}
+	if len(connected) == 0 {
+		return
+	}
+
+	h.unreadCounts.refreshMu.Lock()
+	defer h.unreadCounts.refreshMu.Unlock()
+
+	counts, err := h.store.GetUnreadCounts(connected)
+	if err != nil {
+		log.Printf("Failed to refresh unread counts: %v", err)
+		for _, userID := range connected {
+			h.unreadCounts.forget(userID)
+		}
+		return
+	}
+
+	for _, userID := range connected {
+		if h.unreadCounts.update(userID, counts[userID]) {
+			h.emitUnreadCount(userID, counts[userID])
+		}
+	}
+}
+
+// refreshUnreadCountsFor refreshes the owners of the given notifications,
+// nil entries and global notifications are ignored
+func (h *NotificationHandler) refreshUnreadCountsFor(notifications []*models.Notification) {
+	var userIDs []int32
+	for _, notification := range notifications {
+		if notification != nil && notification.UserID != nil {
+			userIDs = append(userIDs, *notification.UserID)
+		}
+	}
+	h.refreshUnreadCounts(userIDs...)
+}
+
+// sendUnreadCount sends the current count to a client that just subscribed
+func (h *NotificationHandler) sendUnreadCount(client *SocketClient, userID int32) {
+	count, ok := h.unreadCounts.get(userID)
+	if !ok {
+		h.unreadCounts.refreshMu.Lock()
+		counts, err := h.store.GetUnreadCounts([]int32{userID})
+		if err == nil {
+			count = counts[userID]
+			h.unreadCounts.update(userID, count)
+		}
+		h.unreadCounts.refreshMu.Unlock()
+
+		if err != nil {
+			log.Printf("Failed to load unread count for user %d: %v", userID, err)
+			return
+		}
+	}
+
+	h.socketHandler.EmitToClient(client.ID, "unread_count", map[string]interface{}{
+		"user_id": userID,
+		"count":   count,
+	})
+}
+
+func (h *NotificationHandler) emitUnreadCount(userID int32, count int32) {
+	h.socketHandler.EmitToUser(userID, "unread_count", map[string]interface{}{
+		"user_id": userID,
+		"count":   count,
+	})
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnreadCounter(t *testing.T) {
+	counter := newUnreadCounter()
+
+	_, ok := counter.get(1)
+	assert.False(t, ok)
+
+	// The first count is always reported as a change
+	assert.True(t, counter.update(1, 3))
+	assert.False(t, counter.update(1, 3))
+	assert.True(t, counter.update(1, 2))
+
+	count, ok := counter.get(1)
+	assert.True(t, ok)
+	assert.Equal(t, int32(2), count)
+
+	counter.forget(1)
+	_, ok = counter.get(1)
+	assert.False(t, ok)
+	assert.True(t, counter.update(1, 2))
+}
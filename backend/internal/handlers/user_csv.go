@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/pagination"
+	"backend-grpc-server/internal/validation"
+	pb "backend-grpc-server/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxImportRows limits the data rows of a single import
+const maxImportRows = 10000
+
+// exportPageSize is the number of users written per export chunk
+const exportPageSize = 500
+
+// Import row actions
+const (
+	importActionCreate = "create"
+	importActionUpdate = "update"
+)
+
+// importColumns must all be present in the import header
+var importColumns = []string{"name", "email", "age", "role"}
+
+// exportColumns is the header of exported files. It is a superset of the
+// import columns, so an export can be imported again.
+var exportColumns = []string{"id", "name", "email", "age", "role", "created_at", "updated_at", "deleted_at"}
+
+// ImportUsers creates or updates users from a streamed CSV document. Rows are
+// matched to existing users by email. Invalid rows are reported and skipped,
+// in dry-run mode nothing is written.
+func (h *UserHandler) ImportUsers(stream pb.UserService_ImportUsersServer) error {
+	reader := &importStreamReader{stream: stream}
+	// Every row must have as many fields as the header, which keeps the
+	// column positions of parseImportRow in range
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err == io.EOF {
+		return status.Errorf(codes.InvalidArgument, "CSV is empty")
+	}
+	if err != nil {
+		return importReadError(err)
+	}
+
+	columns, err := parseImportHeader(header)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid CSV header: %v", err)
+	}
+
+	resp := &pb.ImportUsersResponse{DryRun: reader.dryRun}
+	seen := make(map[string]int32)
+
+	for row := int32(1); ; row++ {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if row > maxImportRows {
+			return status.Errorf(codes.InvalidArgument, "import cannot exceed %d rows", maxImportRows)
+		}
+
+		result := &pb.ImportRowResult{Row: row}
+		resp.Results = append(resp.Results, result)
+		resp.Total++
+
+		// A row with the wrong number of fields is reported, anything else
+		// means the document can't be read any further
+		if err != nil {
+			if !errors.Is(err, csv.ErrFieldCount) {
+				return importReadError(err)
+			}
+			result.Error = "wrong number of fields"
+			resp.Failed++
+			continue
+		}
+
+		params, err := parseImportRow(record, columns)
+		if params != nil {
+			result.Email = params.Email
+		}
+		if err == nil {
+			// Addresses are unique regardless of case
+			key := strings.ToLower(params.Email)
+			if first, ok := seen[key]; ok {
+				err = fmt.Errorf("email already used in row %d", first)
+			} else {
+				seen[key] = row
+			}
+		}
+		if err == nil {
+			err = h.importRow(stream.Context(), params, result, reader.dryRun)
+		}
+
+		if err != nil {
+			result.Error = err.Error()
+			resp.Failed++
+			continue
+		}
+
+		result.Success = true
+		if result.Action == importActionCreate {
+			resp.Created++
+		} else {
+			resp.Updated++
+		}
+	}
+
+	if !resp.DryRun && resp.Created+resp.Updated > 0 {
+		// One event for the whole import instead of one per user
+		h.socketHandler.WithContext(stream.Context()).EmitToAll("users_imported", map[string]interface{}{
+			"created": resp.Created,
+			"updated": resp.Updated,
+		})
+	}
+
+	return stream.SendAndClose(resp)
+}
+
+// importRow writes a single validated row, or only looks up what would happen
+// in dry-run mode. Rows that set or replace another role than user need an admin.
+func (h *UserHandler) importRow(ctx context.Context, params *models.CreateUserParams, result *pb.ImportRowResult, dryRun bool) error {
+	existing, exists := h.store.GetUserByEmail(params.Email)
+	if !isAdmin(ctx) {
+		if params.Role != "user" {
+			return fmt.Errorf("only admins can import users with the %s role", params.Role)
+		}
+		if exists && existing.Role != "user" {
+			return fmt.Errorf("only admins can update users with the %s role", existing.Role)
+		}
+	}
+
+	if dryRun {
+		result.Action = importActionCreate
+		if exists {
+			result.Action = importActionUpdate
+			result.UserId = existing.ID
+		}
+		return nil
+	}
+
+	user, created, err := h.store.WithContext(ctx).UpsertUserByEmail(params)
+	if err != nil {
+		return err
+	}
+
+	result.UserId = user.ID
+	result.Action = importActionUpdate
+	if created {
+		result.Action = importActionCreate
+	}
+	return nil
+}
+
+// ExportUsers streams the matching users as a CSV document, oldest first
+func (h *UserHandler) ExportUsers(req *pb.ExportUsersRequest, stream pb.UserService_ExportUsersServer) error {
+	if req.IncludeDeleted {
+		if err := requireAdmin(stream.Context(), "export deleted users"); err != nil {
+			return err
+		}
+	}
+
+	params := &models.ListUsersParams{
+		Limit:          exportPageSize,
+		Roles:          req.Roles,
+		Search:         req.Search,
+		IncludeDeleted: req.IncludeDeleted,
+		SortOrder:      "asc",
+		SkipTotal:      true,
+	}
+
+	if err := params.Validate(); err != nil {
+		return status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write(exportColumns)
+
+	for {
+		if err := stream.Context().Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+
+		users, _, err := h.store.ListUsers(params)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to list users: %v", err)
+		}
+
+		for _, user := range users {
+			writer.Write(exportRecord(user))
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return status.Errorf(codes.Internal, "failed to write CSV: %v", err)
+		}
+
+		if buf.Len() > 0 {
+			if err := stream.Send(&pb.ExportUsersResponse{CsvChunk: buf.Bytes()}); err != nil {
+				return err
+			}
+			// Send may keep the slice, so start a fresh buffer
+			buf = bytes.Buffer{}
+		}
+
+		if len(users) < exportPageSize {
+			return nil
+		}
+
+		last := users[len(users)-1]
+		params.Cursor = &pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+}
+
+// importStreamReader turns the CSV chunks of an import stream into a reader.
+// The dry-run flag is taken from the first message.
+type importStreamReader struct {
+	stream  pb.UserService_ImportUsersServer
+	buf     []byte
+	dryRun  bool
+	started bool
+}
+
+func (r *importStreamReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		req, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		if !r.started {
+			r.dryRun = req.DryRun
+			r.started = true
+		}
+		r.buf = req.CsvChunk
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// importReadError reports CSV syntax errors as invalid input and passes
+// stream errors through
+func importReadError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return status.Errorf(codes.InvalidArgument, "invalid CSV: %v", err)
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Errorf(codes.Internal, "failed to read import: %v", err)
+}
+
+// parseImportHeader maps the import columns to their position. Column names
+// are case-insensitive, unknown columns are ignored.
+func parseImportHeader(header []string) (map[string]int, error) {
+	columns := make(map[string]int, len(importColumns))
+	for _, name := range importColumns {
+		columns[name] = -1
+	}
+
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if i == 0 {
+			// Spreadsheet programs like to start files with a byte order mark
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+
+		position, known := columns[name]
+		if !known {
+			continue
+		}
+		if position >= 0 {
+			return nil, fmt.Errorf("column %q appears twice", name)
+		}
+		columns[name] = i
+	}
+
+	for _, name := range importColumns {
+		if columns[name] < 0 {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	return columns, nil
+}
+
+// parseImportRow converts a record into validated create parameters. The
+// parameters are returned even when invalid so the row can be identified.
+func parseImportRow(record []string, columns map[string]int) (*models.CreateUserParams, error) {
+	field := func(name string) string {
+		return strings.TrimSpace(record[columns[name]])
+	}
+
+	params := &models.CreateUserParams{
+		Name:  field("name"),
+		Email: field("email"),
+		Role:  field("role"),
+	}
+
+	age, err := strconv.ParseInt(field("age"), 10, 32)
+	if err != nil {
+		return params, fmt.Errorf("age must be a whole number")
+	}
+	params.Age = int32(age)
+
+	if err := validation.ValidateStruct(params); err != nil {
+		return params, err
+	}
+
+	return params, nil
+}
+
+// exportRecord formats a user as a row matching exportColumns
+func exportRecord(user *models.User) []string {
+	var deletedAt string
+	if user.DeletedAt != nil {
+		deletedAt = user.DeletedAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+
+	return []string{
+		strconv.Itoa(int(user.ID)),
+		user.Name,
+		user.Email,
+		strconv.Itoa(int(user.Age)),
+		user.Role,
+		user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		user.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		deletedAt,
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	pb "backend-grpc-server/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestParseImportHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  []string
+		want    map[string]int
+		wantErr bool
+	}{
+		{
+			name:   "import columns",
+			header: []string{"name", "email", "age", "role"},
+			want:   map[string]int{"name": 0, "email": 1, "age": 2, "role": 3},
+		},
+		{
+			name:   "export columns in any case with a byte order mark",
+			header: []string{"\ufeffID", "Name", " EMAIL ", "age", "role", "created_at", "", ""},
+			want:   map[string]int{"name": 1, "email": 2, "age": 3, "role": 4},
+		},
+		{
+			name:    "missing column",
+			header:  []string{"name", "email", "age"},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate column",
+			header:  []string{"name", "email", "age", "role", "Email"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, err := parseImportHeader(tt.header)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, columns)
+		})
+	}
+}
+
+func TestParseImportRow(t *testing.T) {
+	columns := map[string]int{"name": 0, "email": 1, "age": 2, "role": 3}
+
+	tests := []struct {
+		name    string
+		record  []string
+		wantErr bool
+	}{
+		{name: "valid row", record: []string{" Jane Doe ", "jane@example.com", "30", "admin"}},
+		{name: "age not a number", record: []string{"Jane Doe", "jane@example.com", "thirty", "admin"}, wantErr: true},
+		{name: "invalid email", record: []string{"Jane Doe", "not-an-email", "30", "admin"}, wantErr: true},
+		{name: "unknown role", record: []string{"Jane Doe", "jane@example.com", "30", "owner"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := parseImportRow(tt.record, columns)
+			require.NotNil(t, params)
+			assert.Equal(t, tt.record[1], params.Email)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "Jane Doe", params.Name)
+			assert.Equal(t, int32(30), params.Age)
+		})
+	}
+}
+
+type fakeImportStream struct {
+	grpc.ServerStream
+	requests []*pb.ImportUsersRequest
+}
+
+func (s *fakeImportStream) Recv() (*pb.ImportUsersRequest, error) {
+	if len(s.requests) == 0 {
+		return nil, io.EOF
+	}
+	req := s.requests[0]
+	s.requests = s.requests[1:]
+	return req, nil
+}
+
+func (s *fakeImportStream) SendAndClose(*pb.ImportUsersResponse) error {
+	return nil
+}
+
+type fakeExportStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	chunks [][]byte
+}
+
+func (s *fakeExportStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeExportStream) Send(resp *pb.ExportUsersResponse) error {
+	s.chunks = append(s.chunks, resp.CsvChunk)
+	return nil
+}
+
+func TestImportStreamReader(t *testing.T) {
+	stream := &fakeImportStream{requests: []*pb.ImportUsersRequest{
+		{CsvChunk: []byte("name,em"), DryRun: true},
+		{},
+		{CsvChunk: []byte("ail\n")},
+	}}
+	reader := &importStreamReader{stream: stream}
+
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "name,email\n", string(data))
+	assert.True(t, reader.dryRun)
+}
@@ -3,8 +3,13 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
 
 	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/pagination"
 	"backend-grpc-server/internal/storage"
 	"backend-grpc-server/internal/validation"
 	pb "backend-grpc-server/pb"
@@ -17,6 +22,10 @@ type UserHandler struct {
 	pb.UnimplementedUserServiceServer
 	store         storage.UserStore
 	socketHandler *SocketHandler
+
+	// Background workers stop when done is closed
+	done     chan struct{}
+	doneOnce sync.Once
 }
 
 // NewUserHandler creates a new user handler with Socket support
@@ -24,6 +33,7 @@ func NewUserHandler(store storage.UserStore, socketHandler *SocketHandler) *User
 	return &UserHandler{
 		store:         store,
 		socketHandler: socketHandler,
+		done:          make(chan struct{}),
 	}
 }
 
@@ -33,17 +43,27 @@ func (h *UserHandler) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.
 		return nil, status.Errorf(codes.InvalidArgument, "user ID must be greater than 0")
 	}
 
-	user, exists := h.store.GetUser(req.Id)
+	var user *models.User
+	var exists bool
+	if req.IncludeDeleted {
+		if err := requireAdmin(ctx, "get deleted users"); err != nil {
+			return nil, err
+		}
+		user, exists = h.store.GetUserIncludingDeleted(req.Id)
+	} else {
+		user, exists = h.store.GetUser(req.Id)
+	}
 	if !exists {
 		return nil, status.Errorf(codes.NotFound, "user with ID %d not found", req.Id)
 	}
 
 	return &pb.GetUserResponse{
-		User: h.convertToProtoUser(user),
+		User: convertToProtoUser(user),
 	}, nil
 }
 
-// CreateUser creates a new user and broadcasts the update via socket
+// CreateUser creates a new user and broadcasts the update via socket. Only
+// admins create users with another role than user.
 func (h *UserHandler) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
 	params := &models.CreateUserParams{
 		Name:  req.Name,
@@ -56,14 +76,19 @@ func (h *UserHandler) CreateUser(ctx context.Context, req *pb.CreateUserRequest)
 	if err := validation.ValidateStruct(params); err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
 	}
+	if params.Role != "user" {
+		if err := requireAdmin(ctx, fmt.Sprintf("create users with the %s role", params.Role)); err != nil {
+			return nil, err
+		}
+	}
 
-	user, err := h.store.CreateUser(params)
+	user, err := h.store.WithContext(ctx).CreateUser(params)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to create user: %v", err)
 	}
 
 	// Send socket event about user creation
-	h.socketHandler.EmitToAll("user_created", map[string]interface{}{
+	h.socketHandler.WithContext(ctx).EmitToAll("user_created", map[string]interface{}{
 		"id":    user.ID,
 		"name":  user.Name,
 		"email": user.Email,
@@ -72,7 +97,7 @@ func (h *UserHandler) CreateUser(ctx context.Context, req *pb.CreateUserRequest)
 	})
 
 	// Send notification about the new user
-	h.socketHandler.EmitToAll("notification", map[string]interface{}{
+	h.socketHandler.WithContext(ctx).EmitToAll("notification", map[string]interface{}{
 		"id":         fmt.Sprintf("user_create_%d", user.ID),
 		"message":    fmt.Sprintf("New user %s was created", user.Name),
 		"type":       "info",
@@ -86,32 +111,53 @@ func (h *UserHandler) CreateUser(ctx context.Context, req *pb.CreateUserRequest)
 	})
 
 	return &pb.CreateUserResponse{
-		User: h.convertToProtoUser(user),
+		User: convertToProtoUser(user),
 	}, nil
 }
 
-// UpdateUser updates an existing user and broadcasts the update via socket
+// UpdateUser updates an existing user and broadcasts the update via socket.
+// Only admins change roles and email addresses here, users confirm their
+// address through RequestEmailChange.
 func (h *UserHandler) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.UpdateUserResponse, error) {
 	params := &models.UpdateUserParams{
-		ID:    req.Id,
-		Name:  req.Name,
-		Email: req.Email,
-		Age:   req.Age,
-		Role:  req.Role,
+		ID:     req.Id,
+		Name:   req.Name,
+		Email:  req.Email,
+		Age:    req.Age,
+		Role:   req.Role,
+		Fields: req.GetUpdateMask().GetPaths(),
+
+		ExpectedVersion: req.ExpectedVersion,
 	}
 
-	// Validate input
-	if err := validation.ValidateStruct(params); err != nil {
+	// Validate input, only the masked fields when an update mask is given
+	if err := params.Validate(); err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
 	}
 
-	user, err := h.store.UpdateUser(params)
+	if current, exists := h.store.GetUser(params.ID); exists {
+		if params.Updates("email") && !strings.EqualFold(current.Email, params.Email) {
+			if err := requireAdmin(ctx, "change email addresses without confirmation"); err != nil {
+				return nil, err
+			}
+		}
+		if params.Updates("role") && current.Role != params.Role {
+			if err := requireAdmin(ctx, "change roles"); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	user, err := h.store.WithContext(ctx).UpdateUser(params)
 	if err != nil {
+		if err, ok := versionConflictStatus(h.socketHandler, err, nil); ok {
+			return nil, err
+		}
 		return nil, status.Errorf(codes.Internal, "failed to update user: %v", err)
 	}
 
 	// Send socket event about user update
-	h.socketHandler.EmitToAll("user_updated", map[string]interface{}{
+	h.socketHandler.WithContext(ctx).EmitToAll("user_updated", map[string]interface{}{
 		"id":    user.ID,
 		"name":  user.Name,
 		"email": user.Email,
@@ -120,7 +166,7 @@ func (h *UserHandler) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest)
 	})
 
 	// Send notification to specific user and admins
-	h.socketHandler.EmitToUser(user.ID, "notification", map[string]interface{}{
+	h.socketHandler.WithContext(ctx).EmitToUser(user.ID, "notification", map[string]interface{}{
 		"id":         fmt.Sprintf("user_update_%d", user.ID),
 		"message":    "Your profile has been updated",
 		"type":       "success",
@@ -133,11 +179,11 @@ func (h *UserHandler) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest)
 	})
 
 	return &pb.UpdateUserResponse{
-		User: h.convertToProtoUser(user),
+		User: convertToProtoUser(user),
 	}, nil
 }
 
-// DeleteUser deletes a user by ID and broadcasts the update via socket
+// DeleteUser soft deletes a user by ID and broadcasts the update via socket
 func (h *UserHandler) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*pb.DeleteUserResponse, error) {
 	if req.Id <= 0 {
 		return &pb.DeleteUserResponse{
@@ -153,8 +199,11 @@ func (h *UserHandler) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest)
 		userName = user.Name
 	}
 
-	err := h.store.DeleteUser(req.Id)
+	err := h.store.WithContext(ctx).DeleteUser(req.Id, req.ExpectedVersion)
 	if err != nil {
+		if err, ok := versionConflictStatus(h.socketHandler, err, nil); ok {
+			return nil, err
+		}
 		return &pb.DeleteUserResponse{
 			Success: false,
 			Message: err.Error(),
@@ -162,14 +211,14 @@ func (h *UserHandler) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest)
 	}
 
 	// Send socket event about user deletion
-	h.socketHandler.EmitToAll("user_deleted", map[string]interface{}{
+	h.socketHandler.WithContext(ctx).EmitToAll("user_deleted", map[string]interface{}{
 		"id":   req.Id,
 		"name": userName,
 	})
 
 	// Send notification about the user deletion
 	if userName != "" {
-		h.socketHandler.EmitToAll("notification", map[string]interface{}{
+		h.socketHandler.WithContext(ctx).EmitToAll("notification", map[string]interface{}{
 			"id":         fmt.Sprintf("user_delete_%d", req.Id),
 			"message":    fmt.Sprintf("User %s was deleted", userName),
 			"type":       "warning",
@@ -189,11 +238,114 @@ func (h *UserHandler) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest)
 	}, nil
 }
 
+// RestoreUser undoes a soft delete and broadcasts the update via socket
+func (h *UserHandler) RestoreUser(ctx context.Context, req *pb.RestoreUserRequest) (*pb.RestoreUserResponse, error) {
+	if req.Id <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "user ID must be greater than 0")
+	}
+
+	if _, exists := h.store.GetUserIncludingDeleted(req.Id); !exists {
+		return nil, status.Errorf(codes.NotFound, "user with ID %d not found", req.Id)
+	}
+
+	user, err := h.store.WithContext(ctx).RestoreUser(req.Id)
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "failed to restore user: %v", err)
+	}
+
+	// Send socket event about user restoration
+	h.socketHandler.WithContext(ctx).EmitToAll("user_restored", map[string]interface{}{
+		"id":    user.ID,
+		"name":  user.Name,
+		"email": user.Email,
+		"age":   user.Age,
+		"role":  user.Role,
+	})
+
+	return &pb.RestoreUserResponse{
+		User: convertToProtoUser(user),
+	}, nil
+}
+
+// StartPurgeWorker hard deletes users once they have been soft deleted for
+// longer than the grace period
+func (h *UserHandler) StartPurgeWorker(interval time.Duration, gracePeriod time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				h.purgeDeletedUsers(gracePeriod)
+			case <-h.done:
+				return
+			}
+		}
+	}()
+}
+
+func (h *UserHandler) purgeDeletedUsers(gracePeriod time.Duration) {
+	ids, err := h.store.PurgeDeletedUsers(time.Now().Add(-gracePeriod))
+	if err != nil {
+		log.Printf("Failed to purge deleted users: %v", err)
+		return
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	log.Printf("Purged %d users deleted more than %s ago", len(ids), gracePeriod)
+	h.socketHandler.EmitToAll("users_purged", map[string]interface{}{
+		"ids": ids,
+	})
+}
+
+// Shutdown stops the background workers
+func (h *UserHandler) Shutdown() {
+	h.doneOnce.Do(func() {
+		close(h.done)
+	})
+}
+
 // ListUsers returns all users with pagination
 func (h *UserHandler) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	if req.IncludeDeleted {
+		if err := requireAdmin(ctx, "list deleted users"); err != nil {
+			return nil, err
+		}
+	}
+
+	createdAfter, err := parseOptionalTime(req.CreatedAfter)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "created_after must be RFC3339: %v", err)
+	}
+	createdBefore, err := parseOptionalTime(req.CreatedBefore)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "created_before must be RFC3339: %v", err)
+	}
+
+	cursor, err := pagination.Decode(req.PageToken)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid page token")
+	}
+
 	params := &models.ListUsersParams{
-		Limit:  req.Limit,
-		Offset: req.Offset,
+		Limit:         req.Limit,
+		Offset:        req.Offset,
+		Roles:         req.Roles,
+		Search:        req.Search,
+		SearchMode:    req.SearchMode,
+		MinAge:        req.MinAge,
+		MaxAge:        req.MaxAge,
+		CreatedAfter:  createdAfter,
+		CreatedBefore: createdBefore,
+		SortBy:        req.SortBy,
+		SortOrder:     req.SortOrder,
+		Cursor:        cursor,
+		SkipTotal:     req.SkipTotal,
+
+		IncludeDeleted: req.IncludeDeleted,
 	}
 
 	// Validate pagination params
@@ -207,31 +359,67 @@ func (h *UserHandler) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (
 		return nil, status.Errorf(codes.InvalidArgument, "limit cannot exceed 1000")
 	}
 
+	// Validate filters and sorting against the allowlists
+	if err := params.Validate(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
+	}
+
+	// Fetch one extra row to find out whether there is a next page
+	pageSize := pageSizeOrDefault(params.Limit)
+	params.Limit = pageSize + 1
+
 	users, total, err := h.store.ListUsers(params)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to list users: %v", err)
 	}
 
+	hasMore := len(users) > int(pageSize)
+	if hasMore {
+		users = users[:pageSize]
+	}
+
 	var pbUsers []*pb.User
 	for _, user := range users {
-		pbUsers = append(pbUsers, h.convertToProtoUser(user))
+		pbUsers = append(pbUsers, convertToProtoUser(user))
+	}
+
+	// Page tokens are keyed on created_at, so other sort orders page by offset
+	var nextPageToken string
+	if hasMore && params.SortsByCreatedAt() {
+		last := users[len(users)-1]
+		nextPageToken = pagination.NextToken(true, last.CreatedAt, last.ID, params.SortOrder != "asc")
 	}
 
 	return &pb.ListUsersResponse{
-		Users: pbUsers,
-		Total: total,
+		Users:         pbUsers,
+		Total:         total,
+		NextPageToken: nextPageToken,
 	}, nil
 }
 
 // Helper method to convert model user to proto user
-func (h *UserHandler) convertToProtoUser(user *models.User) *pb.User {
+func convertToProtoUser(user *models.User) *pb.User {
+	var deletedAt string
+	if user.DeletedAt != nil {
+		deletedAt = user.DeletedAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+	var lockedUntil string
+	if user.LockedUntil != nil {
+		lockedUntil = user.LockedUntil.Format("2006-01-02T15:04:05Z07:00")
+	}
+
 	return &pb.User{
-		Id:        user.ID,
-		Name:      user.Name,
-		Email:     user.Email,
-		Age:       user.Age,
-		Role:      user.Role,
-		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt: user.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		Id:               user.ID,
+		Name:             user.Name,
+		Email:            user.Email,
+		Age:              user.Age,
+		Role:             user.Role,
+		Version:          user.Version,
+		DeletedAt:        deletedAt,
+		EmailVerified:    user.EmailVerified,
+		TwoFactorEnabled: user.TwoFactorEnabled,
+		LockedUntil:      lockedUntil,
+		CreatedAt:        user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:        user.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}
 }
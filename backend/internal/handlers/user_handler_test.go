@@ -2,13 +2,19 @@ package handlers
 
 import (
 	"context"
+	"strings"
 	"testing"
 
+	"backend-grpc-server/internal/auth"
+	"backend-grpc-server/internal/models"
 	"backend-grpc-server/internal/storage"
 	"backend-grpc-server/internal/testutil"
 	pb "backend-grpc-server/pb"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 func TestUserHandler_CreateUser(t *testing.T) {
@@ -53,7 +59,8 @@ func TestUserHandler_GetUser(t *testing.T) {
 		Role:  "admin",
 	}
 
-	createResp, err := handler.CreateUser(context.Background(), createReq)
+	adminCtx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, Role: "admin"})
+	createResp, err := handler.CreateUser(adminCtx, createReq)
 	require.NoError(t, err)
 
 	// Get the user
@@ -86,3 +93,92 @@ func TestUserHandler_GetUser_NotFound(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "not found")
 }
+
+func TestUserHandler_IncludeDeletedIsAdminOnly(t *testing.T) {
+	handler := &UserHandler{}
+	userCtx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 2, Role: "user"})
+
+	_, err := handler.GetUser(context.Background(), &pb.GetUserRequest{Id: 1, IncludeDeleted: true})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = handler.GetUser(userCtx, &pb.GetUserRequest{Id: 1, IncludeDeleted: true})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = handler.ListUsers(context.Background(), &pb.ListUsersRequest{IncludeDeleted: true})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = handler.ListUsers(userCtx, &pb.ListUsersRequest{IncludeDeleted: true})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	err = handler.ExportUsers(&pb.ExportUsersRequest{IncludeDeleted: true}, &fakeExportStream{ctx: userCtx})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+// memoryUserStore looks users up in memory
+type memoryUserStore struct {
+	storage.UserStore
+	users map[int32]*models.User
+}
+
+func (s *memoryUserStore) GetUser(id int32) (*models.User, bool) {
+	user, ok := s.users[id]
+	return user, ok
+}
+
+func (s *memoryUserStore) GetUserByEmail(email string) (*models.User, bool) {
+	for _, user := range s.users {
+		if strings.EqualFold(user.Email, email) {
+			return user, true
+		}
+	}
+	return nil, false
+}
+
+func TestUserHandler_UpdateUserEmailIsAdminOnly(t *testing.T) {
+	store := &memoryUserStore{users: map[int32]*models.User{
+		1: {ID: 1, Name: "Admin", Email: "admin@example.com", Age: 40, Role: "admin"},
+	}}
+	handler := &UserHandler{store: store}
+	userCtx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 2, Role: "user"})
+
+	// Moving an account to another address needs a confirmed email change
+	_, err := handler.UpdateUser(userCtx, &pb.UpdateUserRequest{Id: 1, Name: "Admin", Email: "attacker@example.com", Age: 40, Role: "admin"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = handler.UpdateUser(userCtx, &pb.UpdateUserRequest{Id: 1, Email: "attacker@example.com", UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"email"}}})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = handler.UpdateUser(context.Background(), &pb.UpdateUserRequest{Id: 1, Email: "attacker@example.com", UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"email"}}})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestUserHandler_RolesAreAdminOnly(t *testing.T) {
+	store := &memoryUserStore{users: map[int32]*models.User{
+		1: {ID: 1, Name: "Admin", Email: "admin@example.com", Age: 40, Role: "admin"},
+		2: {ID: 2, Name: "Member", Email: "member@example.com", Age: 30, Role: "user"},
+	}}
+	handler := &UserHandler{store: store}
+	userCtx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 2, Role: "user"})
+	writerCtx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 3, APIKeyID: 4, Scopes: []string{models.ScopeUsersWrite}})
+
+	_, err := handler.CreateUser(userCtx, &pb.CreateUserRequest{Name: "Mallory", Email: "mallory@example.com", Age: 30, Role: "admin"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = handler.CreateUser(writerCtx, &pb.CreateUserRequest{Name: "Mallory", Email: "mallory@example.com", Age: 30, Role: "moderator"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = handler.UpdateUser(userCtx, &pb.UpdateUserRequest{Id: 2, Name: "Member", Email: "member@example.com", Age: 30, Role: "admin"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = handler.UpdateUser(userCtx, &pb.UpdateUserRequest{Id: 2, Role: "admin", UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"role"}}})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Imports can't promote new users or overwrite existing admins
+	result := &pb.ImportRowResult{}
+	err = handler.importRow(userCtx, &models.CreateUserParams{Name: "Mallory", Email: "mallory@example.com", Age: 30, Role: "admin"}, result, true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "only admins")
+	err = handler.importRow(userCtx, &models.CreateUserParams{Name: "Admin", Email: "ADMIN@example.com", Age: 40, Role: "user"}, result, true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "only admins")
+	require.NoError(t, handler.importRow(userCtx, &models.CreateUserParams{Name: "Member", Email: "member@example.com", Age: 31, Role: "user"}, result, true))
+	assert.Equal(t, importActionUpdate, result.Action)
+
+	adminCtx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, Role: "admin"})
+	require.NoError(t, handler.importRow(adminCtx, &models.CreateUserParams{Name: "Moderator", Email: "moderator@example.com", Age: 30, Role: "moderator"}, result, true))
+	assert.Equal(t, importActionCreate, result.Action)
+}
@@ -8,14 +8,28 @@ import (
 	"sync"
 	"time"
 
+	"backend-grpc-server/internal/auth"
+	"backend-grpc-server/internal/storage"
 	"github.com/google/uuid"
 	"nhooyr.io/websocket"
 )
 
+// sessionRevokedStatus closes the connections of a revoked session. Codes
+// from 4000 are free for applications.
+const sessionRevokedStatus websocket.StatusCode = 4001
+
 // Generic Socket Message Structure
 type SocketMessage struct {
-	Event string      `json:"event"`
-	Data  interface{} `json:"data"`
+	Event string       `json:"event"`
+	Data  interface{}  `json:"data"`
+	Actor *SocketActor `json:"actor,omitempty"` // who caused the event, if known
+}
+
+// SocketActor is the principal of the request that caused an event
+type SocketActor struct {
+	UserID         int32 `json:"user_id,omitempty"`
+	APIKeyID       int32 `json:"api_key_id,omitempty"`
+	ImpersonatedBy int32 `json:"impersonated_by,omitempty"` // the admin acting as UserID
 }
 
 // Client represents a connected WebSocket client
@@ -25,6 +39,10 @@ type SocketClient struct {
 	Send   chan SocketMessage
 	UserID *int32   // Optional: for user-specific targeting
 	Groups []string // Optional: for group targeting
+	IP     string   // Address the client connected from
+
+	// SessionID is set for clients that connected with an access token
+	SessionID int32
 }
 
 // SocketHandler manages WebSocket connections and events
@@ -42,6 +60,11 @@ type SocketHandler struct {
 	unregister chan *SocketClient
 	broadcast  chan SocketMessage
 	done       chan struct{}
+
+	// signer verifies the access tokens clients connect with, if set, and
+	// sessions tells whether their session is still active
+	signer   *auth.Signer
+	sessions storage.AuthStore
 }
 
 // NewSocketHandler creates a new socket handler
@@ -63,6 +86,22 @@ func NewSocketHandler() *SocketHandler {
 
 // ServeSocket handles WebSocket connections on /notifications endpoint
 func (h *SocketHandler) ServeSocket(w http.ResponseWriter, r *http.Request) {
+	// Browsers can't set headers on sockets, so the token comes in the query
+	var claims *auth.Claims
+	if token := r.URL.Query().Get("access_token"); token != "" && h.signer != nil {
+		var err error
+		now := time.Now()
+		claims, err = h.signer.Verify(token, now)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if !sessionActive(h.sessions, claims.SessionID, now) {
+			http.Error(w, "session has been revoked or has expired", http.StatusUnauthorized)
+			return
+		}
+	}
+
 	// Upgrade HTTP connection to WebSocket
 	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
 		OriginPatterns: []string{"*"}, // In production: restrict this!
@@ -76,6 +115,11 @@ func (h *SocketHandler) ServeSocket(w http.ResponseWriter, r *http.Request) {
 		ID:   uuid.New().String(),
 		Conn: conn,
 		Send: make(chan SocketMessage, 256),
+		IP:   requestIP(r),
+	}
+	if claims != nil {
+		client.UserID = &claims.UserID
+		client.SessionID = claims.SessionID
 	}
 
 	h.register <- client
@@ -103,6 +147,11 @@ func (h *SocketHandler) run() {
 				Data:  map[string]interface{}{"clientId": client.ID},
 			})
 
+			// Clients that connected with a token are subscribed right away
+			if client.UserID != nil {
+				h.triggerEventHandlers("user_subscribed", client, *client.UserID)
+			}
+
 		case client := <-h.unregister:
 			h.clientsMux.Lock()
 			if _, ok := h.clients[client.ID]; ok {
@@ -112,6 +161,10 @@ func (h *SocketHandler) run() {
 			h.clientsMux.Unlock()
 			log.Printf("Socket client disconnected: %s. Total clients: %d", client.ID, len(h.clients))
 
+			if client.UserID != nil {
+				h.triggerEventHandlers("user_disconnected", client, *client.UserID)
+			}
+
 		case message := <-h.broadcast:
 			h.broadcastToAll(message)
 		}
@@ -199,11 +252,20 @@ func (h *SocketHandler) handleClientMessage(client *SocketClient, msg SocketMess
 		return
 
 	case "subscribe_user":
-		// Client wants to subscribe to user-specific events
+		// Client wants to subscribe to user-specific events. Once sockets
+		// authenticate, clients only get the events of the user they signed
+		// in as, and can't switch users while keeping their session.
 		if userID, ok := msg.Data.(float64); ok {
 			userIDInt := int32(userID)
+			if h.signer != nil && (client.UserID == nil || *client.UserID != userIDInt) {
+				log.Printf("Client %s may not subscribe to user %d", client.ID, userIDInt)
+				return
+			}
 			client.UserID = &userIDInt
 			log.Printf("Client %s subscribed to user %d", client.ID, userIDInt)
+
+			// Let other handlers push initial state, e.g. the unread count
+			h.triggerEventHandlers("user_subscribed", client, userIDInt)
 		}
 		return
 
@@ -269,11 +331,10 @@ func (h *SocketHandler) triggerEventHandlers(event string, client *SocketClient,
 
 // EmitToAll sends event to all connected clients
 func (h *SocketHandler) EmitToAll(event string, data interface{}) {
-	message := SocketMessage{
-		Event: event,
-		Data:  data,
-	}
+	h.emitToAll(SocketMessage{Event: event, Data: data})
+}
 
+func (h *SocketHandler) emitToAll(message SocketMessage) {
 	select {
 	case h.broadcast <- message:
 	default:
@@ -283,11 +344,10 @@ func (h *SocketHandler) EmitToAll(event string, data interface{}) {
 
 // EmitToUser sends event to specific user
 func (h *SocketHandler) EmitToUser(userID int32, event string, data interface{}) {
-	message := SocketMessage{
-		Event: event,
-		Data:  data,
-	}
+	h.emitToUser(userID, SocketMessage{Event: event, Data: data})
+}
 
+func (h *SocketHandler) emitToUser(userID int32, message SocketMessage) {
 	h.clientsMux.RLock()
 	defer h.clientsMux.RUnlock()
 
@@ -298,13 +358,57 @@ func (h *SocketHandler) EmitToUser(userID int32, event string, data interface{})
 	}
 }
 
+// EmitToUsers sends event to every client subscribed to one of the given users
+func (h *SocketHandler) EmitToUsers(userIDs []int32, event string, data interface{}) {
+	h.emitToUsers(userIDs, SocketMessage{Event: event, Data: data})
+}
+
+func (h *SocketHandler) emitToUsers(userIDs []int32, message SocketMessage) {
+	targets := make(map[int32]bool, len(userIDs))
+	for _, id := range userIDs {
+		targets[id] = true
+	}
+
+	h.clientsMux.RLock()
+	defer h.clientsMux.RUnlock()
+
+	for _, client := range h.clients {
+		if client.UserID != nil && targets[*client.UserID] {
+			h.sendToClient(client, message)
+		}
+	}
+}
+
+// EmitToEachUser sends a user-specific payload to every client subscribed to a
+// user in dataByUser, walking the client list only once
+func (h *SocketHandler) EmitToEachUser(event string, dataByUser map[int32]interface{}) {
+	h.emitToEachUser(event, dataByUser, nil)
+}
+
+func (h *SocketHandler) emitToEachUser(event string, dataByUser map[int32]interface{}, actor *SocketActor) {
+	h.clientsMux.RLock()
+	defer h.clientsMux.RUnlock()
+
+	for _, client := range h.clients {
+		if client.UserID == nil {
+			continue
+		}
+		if data, ok := dataByUser[*client.UserID]; ok {
+			h.sendToClient(client, SocketMessage{
+				Event: event,
+				Data:  data,
+				Actor: actor,
+			})
+		}
+	}
+}
+
 // EmitToGroup sends event to all clients in a group
 func (h *SocketHandler) EmitToGroup(groupName string, event string, data interface{}) {
-	message := SocketMessage{
-		Event: event,
-		Data:  data,
-	}
+	h.emitToGroup(groupName, SocketMessage{Event: event, Data: data})
+}
 
+func (h *SocketHandler) emitToGroup(groupName string, message SocketMessage) {
 	h.clientsMux.RLock()
 	defer h.clientsMux.RUnlock()
 
@@ -383,6 +487,65 @@ func (h *SocketHandler) isClientInGroup(client *SocketClient, groupName string)
 	return false
 }
 
+// GetGroupUserIDs returns the distinct user IDs of subscribed clients in a group
+func (h *SocketHandler) GetGroupUserIDs(groupName string) []int32 {
+	h.clientsMux.RLock()
+	defer h.clientsMux.RUnlock()
+
+	seen := make(map[int32]bool)
+	var userIDs []int32
+	for _, client := range h.clients {
+		if client.UserID != nil && h.isClientInGroup(client, groupName) && !seen[*client.UserID] {
+			seen[*client.UserID] = true
+			userIDs = append(userIDs, *client.UserID)
+		}
+	}
+	return userIDs
+}
+
+// IsUserConnected reports whether any client is subscribed to the user
+func (h *SocketHandler) IsUserConnected(userID int32) bool {
+	h.clientsMux.RLock()
+	defer h.clientsMux.RUnlock()
+
+	for _, client := range h.clients {
+		if client.UserID != nil && *client.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// ConnectedUserIDs returns the distinct users with at least one subscribed client
+func (h *SocketHandler) ConnectedUserIDs() []int32 {
+	h.clientsMux.RLock()
+	defer h.clientsMux.RUnlock()
+
+	seen := make(map[int32]bool)
+	var userIDs []int32
+	for _, client := range h.clients {
+		if client.UserID != nil && !seen[*client.UserID] {
+			seen[*client.UserID] = true
+			userIDs = append(userIDs, *client.UserID)
+		}
+	}
+	return userIDs
+}
+
+// SessionConnections returns the number of clients connected per session
+func (h *SocketHandler) SessionConnections() map[int32]int {
+	h.clientsMux.RLock()
+	defer h.clientsMux.RUnlock()
+
+	counts := make(map[int32]int)
+	for _, client := range h.clients {
+		if client.SessionID != 0 {
+			counts[client.SessionID]++
+		}
+	}
+	return counts
+}
+
 // GetConnectedClients returns information about connected clients
 func (h *SocketHandler) GetConnectedClients() map[string]interface{} {
 	h.clientsMux.RLock()
@@ -397,11 +560,55 @@ func (h *SocketHandler) GetConnectedClients() map[string]interface{} {
 		if client.UserID != nil {
 			clientInfo["userId"] = *client.UserID
 		}
+		if client.SessionID != 0 {
+			clientInfo["sessionId"] = client.SessionID
+		}
 		result[id] = clientInfo
 	}
 	return result
 }
 
+// AuthenticateWith lets clients connect with an access token in the
+// access_token query parameter, which subscribes them to their user and ties
+// the connection to their session. Tokens of ended sessions are refused.
+func (h *SocketHandler) AuthenticateWith(signer *auth.Signer, sessions storage.AuthStore) {
+	h.signer = signer
+	h.sessions = sessions
+}
+
+// CloseSession disconnects the clients of a session and returns how many
+// there were
+func (h *SocketHandler) CloseSession(sessionID int32) int {
+	return h.closeClients(func(client *SocketClient) bool {
+		return client.SessionID == sessionID
+	})
+}
+
+// CloseUserSessions disconnects the clients of every session of a user.
+// Clients that only subscribed to the user stay connected.
+func (h *SocketHandler) CloseUserSessions(userID int32) int {
+	return h.closeClients(func(client *SocketClient) bool {
+		return client.SessionID != 0 && client.UserID != nil && *client.UserID == userID
+	})
+}
+
+func (h *SocketHandler) closeClients(match func(client *SocketClient) bool) int {
+	var matched []*SocketClient
+	h.clientsMux.RLock()
+	for _, client := range h.clients {
+		if match(client) {
+			matched = append(matched, client)
+		}
+	}
+	h.clientsMux.RUnlock()
+
+	// Closing waits for the client to answer, the read pump unregisters it
+	for _, client := range matched {
+		go client.Conn.Close(sessionRevokedStatus, "session revoked")
+	}
+	return len(matched)
+}
+
 // Shutdown gracefully closes the socket handler
 func (h *SocketHandler) Shutdown() {
 	close(h.done)
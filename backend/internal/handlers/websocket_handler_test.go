@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"backend-grpc-server/internal/auth"
+	"backend-grpc-server/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"nhooyr.io/websocket"
+)
+
+func TestSocketHandler_Sessions(t *testing.T) {
+	sessions := &memorySessionStore{sessions: map[int32]*models.Session{
+		7: {ID: 7, UserID: 2, ExpiresAt: time.Now().Add(time.Hour)},
+	}}
+	signer := auth.NewSigner([]byte("secret"), time.Minute)
+	socketHandler := NewSocketHandler()
+	socketHandler.AuthenticateWith(signer, sessions)
+	server := httptest.NewServer(http.HandlerFunc(socketHandler.ServeSocket))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	url := "ws" + server.URL[len("http"):] + "/notifications"
+
+	// Invalid tokens are turned away before the upgrade
+	_, resp, err := websocket.Dial(ctx, url+"?access_token=garbage", nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	token, _, err := signer.Issue(auth.Claims{UserID: 2, Role: "user", SessionID: 7}, time.Now())
+	require.NoError(t, err)
+	conn, _, err := websocket.Dial(ctx, url+"?access_token="+token, nil)
+	require.NoError(t, err)
+	defer conn.Close(websocket.StatusNormalClosure, "")
+
+	// Anonymous clients don't belong to a session
+	anonymous, _, err := websocket.Dial(ctx, url, nil)
+	require.NoError(t, err)
+	defer anonymous.Close(websocket.StatusNormalClosure, "")
+
+	assert.Eventually(t, func() bool {
+		return len(socketHandler.GetConnectedClients()) == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, map[int32]int{7: 1}, socketHandler.SessionConnections())
+
+	assert.Equal(t, 0, socketHandler.CloseSession(8))
+	revokedAt := time.Now()
+	sessions.sessions[7].RevokedAt = &revokedAt
+	assert.Equal(t, 1, socketHandler.CloseSession(7))
+	for {
+		if _, _, err = conn.Read(ctx); err != nil {
+			break
+		}
+	}
+	assert.Equal(t, websocket.StatusCode(sessionRevokedStatus), websocket.CloseStatus(err))
+
+	assert.Eventually(t, func() bool {
+		return len(socketHandler.SessionConnections()) == 0
+	}, time.Second, 10*time.Millisecond)
+	assert.Len(t, socketHandler.GetConnectedClients(), 1)
+
+	// The token of the revoked session can't be used to reconnect
+	_, resp, err = websocket.Dial(ctx, url+"?access_token="+token, nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestSocketHandler_SubscribeUser(t *testing.T) {
+	socketHandler := NewSocketHandler()
+	defer socketHandler.Shutdown()
+
+	// Without authentication clients pick the user to follow
+	client := &SocketClient{ID: "anonymous", Send: make(chan SocketMessage, 1)}
+	socketHandler.handleClientMessage(client, SocketMessage{Event: "subscribe_user", Data: float64(3)})
+	require.NotNil(t, client.UserID)
+	assert.Equal(t, int32(3), *client.UserID)
+
+	// With authentication they stay with the user of their token
+	socketHandler.AuthenticateWith(auth.NewSigner([]byte("secret"), time.Minute), &memorySessionStore{})
+	anonymous := &SocketClient{ID: "anonymous", Send: make(chan SocketMessage, 1)}
+	socketHandler.handleClientMessage(anonymous, SocketMessage{Event: "subscribe_user", Data: float64(3)})
+	assert.Nil(t, anonymous.UserID)
+
+	userID := int32(2)
+	signedIn := &SocketClient{ID: "signed-in", Send: make(chan SocketMessage, 1), UserID: &userID, SessionID: 7}
+	socketHandler.handleClientMessage(signedIn, SocketMessage{Event: "subscribe_user", Data: float64(3)})
+	assert.Equal(t, int32(2), *signedIn.UserID)
+	socketHandler.handleClientMessage(signedIn, SocketMessage{Event: "subscribe_user", Data: float64(2)})
+	assert.Equal(t, int32(2), *signedIn.UserID)
+}
@@ -0,0 +1,31 @@
+package mail
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSenderFromEnv(t *testing.T) {
+	t.Setenv("SMTP_HOST", "")
+	assert.IsType(t, &LogSender{}, NewSenderFromEnv())
+
+	t.Setenv("SMTP_HOST", "smtp.example.com")
+	t.Setenv("SMTP_PORT", "")
+	sender, ok := NewSenderFromEnv().(*SMTPSender)
+	assert.True(t, ok)
+	assert.Equal(t, "smtp.example.com:587", sender.Addr)
+}
+
+func TestSMTPSender_Format(t *testing.T) {
+	sender := &SMTPSender{From: "noreply@example.com"}
+
+	data := string(sender.format(Message{To: "jane@example.com", Subject: "Hello", Body: "line one\nline two"}))
+	assert.Contains(t, data, "To: jane@example.com\r\n")
+	assert.Contains(t, data, "Subject: Hello\r\n")
+	assert.Contains(t, data, "\r\n\r\nline one\r\nline two")
+
+	// Line breaks in headers would allow injecting headers
+	err := sender.Send(Message{To: "jane@example.com\r\nBcc: evil@example.com", Subject: "Hello"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"backend-grpc-server/internal/validation"
+)
+
+// API key scopes. Keep the oneof list of CreateAPIKeyParams in sync.
+const (
+	ScopeNotificationsSend = "notifications:send"
+	ScopeNotificationsRead = "notifications:read"
+	ScopeUsersRead         = "users:read"
+	ScopeUsersWrite        = "users:write"
+)
+
+// API key states
+const (
+	APIKeyActive  = "active"
+	APIKeyRevoked = "revoked"
+	APIKeyExpired = "expired"
+)
+
+// APIKey lets an integration call the API without a user login. Only the
+// prefix and the hash of the key are stored.
+type APIKey struct {
+	ID         int32      `json:"id" db:"id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	Scopes     []string   `json:"scopes" db:"scopes"`
+	CreatedBy  *int32     `json:"created_by,omitempty" db:"created_by"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" db:"expires_at"` // nil never expires
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Status returns the state of the key at the given time
+func (k *APIKey) Status(now time.Time) string {
+	switch {
+	case k.RevokedAt != nil:
+		return APIKeyRevoked
+	case k.ExpiresAt != nil && !now.Before(*k.ExpiresAt):
+		return APIKeyExpired
+	default:
+		return APIKeyActive
+	}
+}
+
+// HasScope reports whether the key was granted the scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, granted := range k.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateAPIKeyParams struct {
+	Name      string     `json:"name" validate:"required,min=2,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,oneof=notifications:send notifications:read users:read users:write"`
+	CreatedBy int32      `json:"created_by" validate:"required"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func (p *CreateAPIKeyParams) Validate() error {
+	return validation.ValidateStruct(p)
+}
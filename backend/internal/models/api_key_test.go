@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKey_Status(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+	later := now.Add(time.Hour)
+
+	tests := []struct {
+		name string
+		key  APIKey
+		want string
+	}{
+		{name: "without expiry", key: APIKey{}, want: APIKeyActive},
+		{name: "before expiry", key: APIKey{ExpiresAt: &later}, want: APIKeyActive},
+		{name: "expired", key: APIKey{ExpiresAt: &now}, want: APIKeyExpired},
+		{name: "revoked", key: APIKey{ExpiresAt: &later, RevokedAt: &earlier}, want: APIKeyRevoked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.key.Status(now))
+		})
+	}
+}
+
+func TestCreateAPIKeyParams_Validate(t *testing.T) {
+	valid := CreateAPIKeyParams{Name: "CRM sync", Scopes: []string{ScopeNotificationsSend, ScopeUsersRead}, CreatedBy: 1}
+	assert.NoError(t, valid.Validate())
+
+	noScopes := valid
+	noScopes.Scopes = nil
+	assert.Error(t, noScopes.Validate())
+
+	unknownScope := valid
+	unknownScope.Scopes = []string{"users:delete"}
+	assert.Error(t, unknownScope.Validate())
+
+	key := APIKey{Scopes: valid.Scopes}
+	assert.True(t, key.HasScope(ScopeUsersRead))
+	assert.False(t, key.HasScope(ScopeUsersWrite))
+}
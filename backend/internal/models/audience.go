@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// AudienceFilter selects users by their attributes. Empty fields are ignored,
+// an empty filter matches every user.
+type AudienceFilter struct {
+	Roles         []string   `json:"roles,omitempty" validate:"omitempty,dive,oneof=admin user moderator"`
+	MinAge        int32      `json:"min_age,omitempty" validate:"omitempty,min=1,max=150"`
+	MaxAge        int32      `json:"max_age,omitempty" validate:"omitempty,min=1,max=150"`
+	EmailDomain   string     `json:"email_domain,omitempty" validate:"omitempty,max=255"`
+	NameContains  string     `json:"name_contains,omitempty" validate:"omitempty,max=100"`
+	CreatedAfter  *time.Time `json:"created_after,omitempty"`
+	CreatedBefore *time.Time `json:"created_before,omitempty"`
+}
+
+// Audience is a saved, named AudienceFilter
+type Audience struct {
+	ID          int32          `json:"id" db:"id"`
+	Name        string         `json:"name" db:"name"`
+	Description string         `json:"description" db:"description"`
+	Filter      AudienceFilter `json:"filter" db:"filter"`
+	CreatedAt   time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+type CreateAudienceParams struct {
+	Name        string         `json:"name" validate:"required,min=2,max=100"`
+	Description string         `json:"description" validate:"max=500"`
+	Filter      AudienceFilter `json:"filter"`
+}
+
+func (a *CreateAudienceParams) Validate() error {
+	validate := validator.New()
+	return validate.Struct(a)
+}
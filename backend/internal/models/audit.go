@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"backend-grpc-server/internal/pagination"
+	"backend-grpc-server/internal/validation"
+)
+
+// AuditEvent is a change to a row, recorded by the database in the same
+// transaction. Before and after hold the changed columns as JSON, secrets
+// are left out.
+type AuditEvent struct {
+	ID             int32           `json:"id" db:"id"`
+	ActorUserID    *int32          `json:"actor_user_id,omitempty" db:"actor_user_id"`
+	ActorAPIKeyID  *int32          `json:"actor_api_key_id,omitempty" db:"actor_api_key_id"`
+	ImpersonatedBy *int32          `json:"impersonated_by,omitempty" db:"impersonated_by"`
+	TenantID       string          `json:"tenant_id,omitempty" db:"tenant_id"`
+	Action         string          `json:"action" db:"action"`
+	TargetType     string          `json:"target_type" db:"target_type"`
+	TargetID       string          `json:"target_id" db:"target_id"`
+	Before         json.RawMessage `json:"before,omitempty" db:"before"`
+	After          json.RawMessage `json:"after,omitempty" db:"after"`
+	RequestID      string          `json:"request_id,omitempty" db:"request_id"`
+	ClientIP       string          `json:"client_ip,omitempty" db:"client_ip"`
+	Source         string          `json:"source" db:"source"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+}
+
+// AuditFilter narrows the audit log. Zero values match all, events are
+// returned newest first.
+type AuditFilter struct {
+	ActorUserID   int32      `json:"actor_user_id" validate:"gte=0"`
+	ActorAPIKeyID int32      `json:"actor_api_key_id" validate:"gte=0"`
+	TenantID      string     `json:"tenant_id" validate:"max=100"`
+	Action        string     `json:"action" validate:"max=100"`
+	TargetType    string     `json:"target_type" validate:"max=50"`
+	TargetID      string     `json:"target_id" validate:"max=100"`
+	RequestID     string     `json:"request_id" validate:"max=64"`
+	Since         *time.Time `json:"since"`
+	Until         *time.Time `json:"until"`
+	Limit         int32      `json:"limit" validate:"gte=0,lte=500"`
+
+	// Keyset pagination over (created_at, id), always descending
+	Cursor *pagination.Cursor `json:"-"`
+}
+
+func (f *AuditFilter) Validate() error {
+	return validation.ValidateStruct(f)
+}
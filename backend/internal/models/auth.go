@@ -0,0 +1,129 @@
+package models
+
+import (
+	"time"
+
+	"backend-grpc-server/internal/validation"
+)
+
+// Session states
+const (
+	SessionActive  = "active"
+	SessionRevoked = "revoked"
+	SessionExpired = "expired"
+)
+
+// Session is a login on one device. Its refresh token is replaced on every
+// refresh, the session stays the same.
+type Session struct {
+	ID         int32      `json:"id" db:"id"`
+	UserID     int32      `json:"user_id" db:"user_id"`
+	Device     string     `json:"device" db:"device"`
+	UserAgent  string     `json:"user_agent" db:"user_agent"`
+	ClientIP   string     `json:"client_ip" db:"client_ip"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	LastSeenAt time.Time  `json:"last_seen_at" db:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Status returns the state of the session at the given time
+func (s *Session) Status(now time.Time) string {
+	switch {
+	case s.RevokedAt != nil:
+		return SessionRevoked
+	case !now.Before(s.ExpiresAt):
+		return SessionExpired
+	default:
+		return SessionActive
+	}
+}
+
+// StartSessionParams describe the client a login is made from
+type StartSessionParams struct {
+	UserID    int32  `json:"user_id" validate:"required"`
+	UserAgent string `json:"user_agent" validate:"max=500"`
+	ClientIP  string `json:"client_ip" validate:"max=45"`
+}
+
+func (p *StartSessionParams) Validate() error {
+	return validation.ValidateStruct(p)
+}
+
+// RefreshToken keeps a login alive. Only the hash of the token is stored.
+type RefreshToken struct {
+	ID        int32      `json:"id" db:"id"`
+	UserID    int32      `json:"user_id" db:"user_id"`
+	SessionID int32      `json:"session_id" db:"session_id"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// PasswordReset is a single-use token mailed to reset a forgotten password.
+// Only the hash of the token is stored.
+type PasswordReset struct {
+	ID          int32      `json:"id" db:"id"`
+	UserID      int32      `json:"user_id" db:"user_id"`
+	RequestedIP string     `json:"requested_ip" db:"requested_ip"`
+	ExpiresAt   time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt      *time.Time `json:"used_at,omitempty" db:"used_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+// TwoFactor is the TOTP state of a user. A secret without EnabledAt is an
+// enrollment that wasn't confirmed yet.
+type TwoFactor struct {
+	UserID    int32      `json:"user_id" db:"id"`
+	Secret    string     `json:"-" db:"totp_secret"`
+	EnabledAt *time.Time `json:"enabled_at,omitempty" db:"totp_enabled_at"`
+	LastStep  *int64     `json:"-" db:"totp_last_step"`
+}
+
+// Enabled reports whether logins require a second factor
+func (t *TwoFactor) Enabled() bool {
+	return t.EnabledAt != nil
+}
+
+// FailedLogins counts the failed logins in a row of an account. The count
+// starts over after a successful login or a lockout.
+type FailedLogins struct {
+	UserID       int32     `json:"user_id" db:"user_id"`
+	Attempts     int32     `json:"attempts" db:"attempts"`
+	LastFailedAt time.Time `json:"last_failed_at" db:"last_failed_at"`
+}
+
+type RequestPasswordResetParams struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+func (p *RequestPasswordResetParams) Validate() error {
+	return validation.ValidateStruct(p)
+}
+
+// UserIdentity links a user to an account at an external identity provider
+type UserIdentity struct {
+	ID          int32     `json:"id" db:"id"`
+	UserID      int32     `json:"user_id" db:"user_id"`
+	Issuer      string    `json:"issuer" db:"issuer"`
+	Subject     string    `json:"subject" db:"subject"`
+	Email       string    `json:"email" db:"email"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at" db:"last_login_at"`
+}
+
+// ExternalLoginParams describe a user authenticated by an identity provider.
+// Provision creates a user with Name and Role when none matches.
+type ExternalLoginParams struct {
+	Issuer        string `json:"issuer" validate:"required,max=255"`
+	Subject       string `json:"subject" validate:"required,max=255"`
+	Email         string `json:"email" validate:"omitempty,email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name" validate:"omitempty,min=2,max=100"`
+	Provision     bool   `json:"provision"`
+	Role          string `json:"role" validate:"omitempty,oneof=admin user moderator"`
+}
+
+func (p *ExternalLoginParams) Validate() error {
+	return validation.ValidateStruct(p)
+}
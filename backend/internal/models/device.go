@@ -0,0 +1,66 @@
+package models
+
+import "strings"
+
+// maxUserAgentLength matches the user_agent column of sessions
+const maxUserAgentLength = 500
+
+// Browsers and systems recognized by DeviceName, in the order they are
+// checked. Chrome based browsers also claim to be Chrome and Safari, and
+// Chrome claims to be Safari, so the specific names come first.
+var (
+	knownBrowsers = []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"grpc-", "API client"},
+	}
+	knownSystems = []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+// DeviceName turns a user agent into a short label like "Firefox on Linux"
+// for the session list
+func DeviceName(userAgent string) string {
+	var browser, system string
+	for _, known := range knownBrowsers {
+		if strings.Contains(userAgent, known.token) {
+			browser = known.name
+			break
+		}
+	}
+	for _, known := range knownSystems {
+		if strings.Contains(userAgent, known.token) {
+			system = known.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	default:
+		return "Unknown device"
+	}
+}
+
+// TruncateUserAgent cuts a user agent to the length stored with a session
+func TruncateUserAgent(userAgent string) string {
+	if len(userAgent) <= maxUserAgentLength {
+		return userAgent
+	}
+	return strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceName(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		want      string
+	}{
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0", "Firefox on Linux"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/130.0.0.0 Safari/537.36 Edg/130.0.0.0", "Edge on Windows"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.0 Safari/605.1.15", "Safari on macOS"},
+		{"Mozilla/5.0 (Linux; Android 14) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/130.0.0.0 Mobile Safari/537.36", "Chrome on Android"},
+		{"grpc-go/1.65.0", "API client"},
+		{"", "Unknown device"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, DeviceName(tt.userAgent))
+		})
+	}
+}
+
+func TestTruncateUserAgent(t *testing.T) {
+	assert.Equal(t, "grpc-go/1.65.0", TruncateUserAgent("grpc-go/1.65.0"))
+
+	truncated := TruncateUserAgent(strings.Repeat("a", maxUserAgentLength-1) + "é")
+	assert.Len(t, truncated, maxUserAgentLength-1)
+	assert.True(t, utf8.ValidString(truncated))
+}
+
+func TestSession_Status(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+
+	assert.Equal(t, SessionActive, (&Session{ExpiresAt: now.Add(time.Hour)}).Status(now))
+	assert.Equal(t, SessionExpired, (&Session{ExpiresAt: now}).Status(now))
+	assert.Equal(t, SessionRevoked, (&Session{ExpiresAt: now.Add(time.Hour), RevokedAt: &earlier}).Status(now))
+
+	params := StartSessionParams{UserID: 1, UserAgent: "grpc-go/1.65.0", ClientIP: "192.0.2.1"}
+	assert.NoError(t, params.Validate())
+	params.UserID = 0
+	assert.Error(t, params.Validate())
+}
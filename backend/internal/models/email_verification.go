@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"backend-grpc-server/internal/validation"
+)
+
+// Email verification purposes
+const (
+	// EmailVerify confirms the current address of a user
+	EmailVerify = "verify"
+	// EmailChange confirms a new address, which replaces the current one once confirmed
+	EmailChange = "change"
+)
+
+// EmailVerification is a token mailed to an address to prove the user owns
+// it. Only the hash of the token is stored.
+type EmailVerification struct {
+	ID         int32      `json:"id" db:"id"`
+	UserID     int32      `json:"user_id" db:"user_id"`
+	Email      string     `json:"email" db:"email"`
+	Purpose    string     `json:"purpose" db:"purpose"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	ConsumedAt *time.Time `json:"consumed_at,omitempty" db:"consumed_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+type CreateEmailVerificationParams struct {
+	UserID    int32     `json:"user_id" validate:"required,min=1"`
+	Email     string    `json:"email" validate:"required,email"`
+	Purpose   string    `json:"purpose" validate:"required,oneof=verify change"`
+	ExpiresAt time.Time `json:"expires_at" validate:"required"`
+}
+
+func (p *CreateEmailVerificationParams) Validate() error {
+	return validation.ValidateStruct(p)
+}
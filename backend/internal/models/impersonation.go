@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"backend-grpc-server/internal/validation"
+)
+
+// Impersonation states
+const (
+	ImpersonationActive  = "active"
+	ImpersonationEnded   = "ended"
+	ImpersonationExpired = "expired"
+)
+
+// Impersonation is an admin acting as a user for support. The user IDs are
+// cleared when the users are deleted, the record itself is kept.
+type Impersonation struct {
+	ID           int32      `json:"id" db:"id"`
+	ActorID      *int32     `json:"actor_id,omitempty" db:"actor_id"`
+	UserID       *int32     `json:"user_id,omitempty" db:"user_id"`
+	Reason       string     `json:"reason" db:"reason"`
+	ClientIP     string     `json:"client_ip" db:"client_ip"`
+	StartedAt    time.Time  `json:"started_at" db:"started_at"`
+	ExpiresAt    time.Time  `json:"expires_at" db:"expires_at"`
+	EndedAt      *time.Time `json:"ended_at,omitempty" db:"ended_at"`
+	RequestCount int32      `json:"request_count" db:"request_count"`
+}
+
+// Status returns the state of the impersonation at the given time
+func (i *Impersonation) Status(now time.Time) string {
+	switch {
+	case i.EndedAt != nil:
+		return ImpersonationEnded
+	case !now.Before(i.ExpiresAt):
+		return ImpersonationExpired
+	default:
+		return ImpersonationActive
+	}
+}
+
+type StartImpersonationParams struct {
+	ActorID   int32     `json:"actor_id" validate:"required"`
+	UserID    int32     `json:"user_id" validate:"required,nefield=ActorID"`
+	Reason    string    `json:"reason" validate:"required,min=5,max=500"`
+	ClientIP  string    `json:"client_ip" validate:"max=45"`
+	ExpiresAt time.Time `json:"expires_at" validate:"required"`
+}
+
+func (p *StartImpersonationParams) Validate() error {
+	return validation.ValidateStruct(p)
+}
+
+// ImpersonationFilter narrows the impersonation log. Zero values match all.
+type ImpersonationFilter struct {
+	ActorID int32
+	UserID  int32
+	Limit   int32
+}
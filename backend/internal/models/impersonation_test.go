@@ -0,0 +1,30 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImpersonation_Status(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Minute)
+
+	assert.Equal(t, ImpersonationActive, (&Impersonation{ExpiresAt: now.Add(time.Minute)}).Status(now))
+	assert.Equal(t, ImpersonationExpired, (&Impersonation{ExpiresAt: now}).Status(now))
+	assert.Equal(t, ImpersonationEnded, (&Impersonation{ExpiresAt: now.Add(time.Minute), EndedAt: &earlier}).Status(now))
+}
+
+func TestStartImpersonationParams_Validate(t *testing.T) {
+	valid := StartImpersonationParams{ActorID: 1, UserID: 2, Reason: "Ticket 1234", ExpiresAt: time.Now()}
+	assert.NoError(t, valid.Validate())
+
+	self := valid
+	self.UserID = self.ActorID
+	assert.Error(t, self.Validate())
+
+	noReason := valid
+	noReason.Reason = ""
+	assert.Error(t, noReason.Validate())
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"backend-grpc-server/internal/validation"
+)
+
+// Invitation states
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+// MaxInvitationValidity caps how long an invitation link can stay valid
+const MaxInvitationValidity = 30 * 24 * time.Hour
+
+// Invitation is a pending account for an email address. Only the hash of the
+// invitation token is stored.
+type Invitation struct {
+	ID         int32      `json:"id" db:"id"`
+	Email      string     `json:"email" db:"email"`
+	Role       string     `json:"role" db:"role"`
+	InvitedBy  string     `json:"invited_by" db:"invited_by"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty" db:"accepted_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	UserID     *int32     `json:"user_id,omitempty" db:"user_id"` // set once accepted
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Status returns the state of the invitation at the given time
+func (i *Invitation) Status(now time.Time) string {
+	switch {
+	case i.AcceptedAt != nil:
+		return InvitationAccepted
+	case i.RevokedAt != nil:
+		return InvitationRevoked
+	case !now.Before(i.ExpiresAt):
+		return InvitationExpired
+	default:
+		return InvitationPending
+	}
+}
+
+type CreateInvitationParams struct {
+	Email     string    `json:"email" validate:"required,email"`
+	Role      string    `json:"role" validate:"required,oneof=admin user moderator"`
+	InvitedBy string    `json:"invited_by" validate:"required,max=255"`
+	ExpiresAt time.Time `json:"expires_at" validate:"required"`
+}
+
+func (p *CreateInvitationParams) Validate() error {
+	return validation.ValidateStruct(p)
+}
+
+// AcceptInvitationParams completes the profile of an invited user. The
+// password is checked separately against the password policy.
+type AcceptInvitationParams struct {
+	Token    string `json:"-" validate:"required"`
+	Name     string `json:"name" validate:"required,min=2,max=100"`
+	Age      int32  `json:"age" validate:"required,min=1,max=150"`
+	Password string `json:"-" validate:"required"`
+}
+
+func (p *AcceptInvitationParams) Validate() error {
+	return validation.ValidateStruct(p)
+}
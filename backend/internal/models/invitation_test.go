@@ -0,0 +1,30 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvitation_Status(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+
+	tests := []struct {
+		name       string
+		invitation Invitation
+		want       string
+	}{
+		{name: "pending", invitation: Invitation{ExpiresAt: now.Add(time.Hour)}, want: InvitationPending},
+		{name: "expired", invitation: Invitation{ExpiresAt: now}, want: InvitationExpired},
+		{name: "revoked", invitation: Invitation{ExpiresAt: now.Add(time.Hour), RevokedAt: &earlier}, want: InvitationRevoked},
+		{name: "accepted after expiry", invitation: Invitation{ExpiresAt: earlier, AcceptedAt: &earlier}, want: InvitationAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.invitation.Status(now))
+		})
+	}
+}
@@ -1,52 +1,157 @@
 package models
 
 import (
-	"github.com/go-playground/validator/v10"
+	"fmt"
 	"time"
+
+	"backend-grpc-server/internal/pagination"
+	"backend-grpc-server/internal/validation"
+	"github.com/go-playground/validator/v10"
 )
 
 // Persistent Notification - stored in database
 type Notification struct {
-	ID         int32     `json:"id" db:"id"`
-	Message    string    `json:"message" db:"message" validate:"required,min=1,max=1000"`
-	Type       string    `json:"type" db:"type" validate:"required,oneof=info warning error success"`
-	UserID     *int32    `json:"user_id" db:"user_id" validate:"omitempty,min=1"`
-	Read       bool      `json:"read" db:"read"`
-	Persistent bool      `json:"persistent" db:"persistent"`
-	CreatedAt  time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	ID           int32                  `json:"id" db:"id"`
+	Message      string                 `json:"message" db:"message" validate:"required,min=1,max=1000"`
+	Type         string                 `json:"type" db:"type" validate:"required,notification_type"`
+	UserID       *int32                 `json:"user_id" db:"user_id" validate:"omitempty,min=1"`
+	Read         bool                   `json:"read" db:"read"`
+	Persistent   bool                   `json:"persistent" db:"persistent"`
+	Data         map[string]interface{} `json:"data,omitempty" db:"data"`
+	Language     string                 `json:"language" db:"language"`
+	ArchivedAt   *time.Time             `json:"archived_at,omitempty" db:"archived_at"`
+	SnoozedUntil *time.Time             `json:"snoozed_until,omitempty" db:"snoozed_until"`
+	Version      int32                  `json:"version" db:"version"`
+	CreatedAt    time.Time              `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time              `json:"updated_at" db:"updated_at"`
 }
 
 // Real-time Notification - only sent via WebSocket, not stored
 type RealtimeNotification struct {
 	ID        string                 `json:"id"` // UUID for tracking
 	Message   string                 `json:"message"`
-	Type      string                 `json:"type"` // "info", "warning", "error", "success"
+	Type      string                 `json:"type"`              // "info", "warning", "error", "success"
 	UserID    *int32                 `json:"user_id,omitempty"` // Optional: for targeted notifications
-	Data      map[string]interface{} `json:"data,omitempty"` // Additional payload
+	Data      map[string]interface{} `json:"data,omitempty"`    // Additional payload
 	Timestamp time.Time              `json:"timestamp"`
 }
 
 // CRUD Parameters for persistent notifications
 type CreateNotificationParams struct {
-	Message    string `json:"message" validate:"required,min=1,max=1000"`
-	Type       string `json:"type" validate:"required,oneof=info warning error success"`
-	UserID     *int32 `json:"user_id,omitempty" validate:"omitempty,min=1"`
-	Persistent bool   `json:"persistent"`
+	Message    string                 `json:"message" validate:"required,min=1,max=1000"`
+	Type       string                 `json:"type" validate:"required,notification_type"`
+	UserID     *int32                 `json:"user_id,omitempty" validate:"omitempty,min=1"`
+	Persistent bool                   `json:"persistent"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	Language   string                 `json:"language,omitempty" validate:"omitempty,oneof=simple english german french spanish italian dutch portuguese"`
 }
 
 type UpdateNotificationParams struct {
-	ID      int32  `json:"id"`
-	Message string `json:"message"`
-	Type    string `json:"type"`
+	ID      int32  `json:"id" validate:"required,min=1"`
+	Message string `json:"message" validate:"required,min=1,max=1000"`
+	Type    string `json:"type" validate:"required,notification_type"`
 	Read    bool   `json:"read"`
+
+	// Fields holds the update mask paths, empty updates every field
+	Fields []string `json:"fields,omitempty"`
+
+	// ExpectedVersion must match the stored version, 0 skips the check
+	ExpectedVersion int32 `json:"expected_version,omitempty" validate:"min=0"`
+}
+
+// NotificationUpdateFields maps update mask paths to UpdateNotificationParams fields
+var NotificationUpdateFields = map[string]string{
+	"message": "Message",
+	"type":    "Type",
+	"read":    "Read",
+}
+
+// Validate checks the ID and the fields selected by the update mask
+func (n *UpdateNotificationParams) Validate() error {
+	if len(n.Fields) == 0 {
+		return validation.ValidateStruct(n)
+	}
+
+	fields, err := maskedFields(n.Fields, NotificationUpdateFields)
+	if err != nil {
+		return err
+	}
+	return validation.ValidateStructPartial(n, append(fields, "ID", "ExpectedVersion")...)
 }
 
 type ListNotificationsParams struct {
-	Limit  int32  `json:"limit"`
-	Offset int32  `json:"offset"`
-	UserID *int32 `json:"user_id,omitempty"` // Filter by user
-	Read   *bool  `json:"read,omitempty"`    // Filter by read status
+	Limit          int32  `json:"limit"`
+	Offset         int32  `json:"offset"`
+	UserID         *int32 `json:"user_id,omitempty"`         // Filter by user
+	Read           *bool  `json:"read,omitempty"`            // Filter by read status
+	Archived       bool   `json:"archived,omitempty"`        // List the archive instead of the inbox
+	IncludeSnoozed bool   `json:"include_snoozed,omitempty"` // Include currently snoozed notifications
+
+	// Keyset pagination on (created_at, id), replaces Offset when set
+	Cursor    *pagination.Cursor `json:"-"`
+	SkipTotal bool               `json:"skip_total,omitempty"` // skip the COUNT(*), total is reported as -1
+}
+
+// MaxSnoozeDuration limits how far into the future a notification can be snoozed
+const MaxSnoozeDuration = 365 * 24 * time.Hour
+
+type SnoozeNotificationParams struct {
+	ID     int32     `json:"id" validate:"required,min=1"`
+	UserID int32     `json:"user_id" validate:"required,min=1"`
+	Until  time.Time `json:"until" validate:"required"`
+}
+
+// Notification target types
+const (
+	TargetAll      = "all"      // broadcast to every connected client
+	TargetUser     = "user"     // a single user
+	TargetUsers    = "users"    // an explicit list of user IDs
+	TargetRole     = "role"     // all users with a role, e.g. "admin"
+	TargetGroup    = "group"    // all clients in a socket group
+	TargetAudience = "audience" // a saved audience filter
+)
+
+// NotificationTarget describes who receives a notification
+type NotificationTarget struct {
+	Type       string  `json:"type" validate:"required,oneof=all user users role group audience"`
+	UserID     *int32  `json:"user_id,omitempty" validate:"omitempty,min=1"`
+	UserIDs    []int32 `json:"user_ids,omitempty" validate:"omitempty,max=10000,dive,min=1"`
+	Role       string  `json:"role,omitempty" validate:"omitempty,oneof=admin user moderator"`
+	Group      string  `json:"group,omitempty" validate:"omitempty,max=100"`
+	AudienceID int32   `json:"audience_id,omitempty" validate:"omitempty,min=1"`
+}
+
+// Validate checks that the field required by the target type is set
+func (t *NotificationTarget) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(t); err != nil {
+		return err
+	}
+
+	switch t.Type {
+	case TargetUser:
+		if t.UserID == nil {
+			return fmt.Errorf("user_id is required for target type %q", t.Type)
+		}
+	case TargetUsers:
+		if len(t.UserIDs) == 0 {
+			return fmt.Errorf("user_ids is required for target type %q", t.Type)
+		}
+	case TargetRole:
+		if t.Role == "" {
+			return fmt.Errorf("role is required for target type %q", t.Type)
+		}
+	case TargetGroup:
+		if t.Group == "" {
+			return fmt.Errorf("group is required for target type %q", t.Type)
+		}
+	case TargetAudience:
+		if t.AudienceID == 0 {
+			return fmt.Errorf("audience_id is required for target type %q", t.Type)
+		}
+	}
+
+	return nil
 }
 
 type MarkNotificationReadParams struct {
@@ -62,7 +167,41 @@ type CreateRealtimeNotificationParams struct {
 	Data    map[string]interface{} `json:"data,omitempty"`
 }
 
+// Validate uses the shared validator so the notification_type tag is available
 func (n *CreateNotificationParams) Validate() error {
-	validate := validator.New()
-	return validate.Struct(n)
+	return validation.ValidateStruct(n)
+}
+
+// DefaultNotificationLanguage is the text search configuration used when a
+// notification does not specify a language
+const DefaultNotificationLanguage = "english"
+
+// Notification search sort options
+const (
+	SortByRelevance = "relevance"
+	SortByCreatedAt = "created_at"
+)
+
+// SearchNotificationsParams describes a full-text search over notifications.
+// An empty Query matches every notification and only applies the filters.
+type SearchNotificationsParams struct {
+	Query         string     `json:"query" validate:"max=500"`
+	Language      string     `json:"language,omitempty" validate:"omitempty,oneof=simple english german french spanish italian dutch portuguese"`
+	UserID        *int32     `json:"user_id,omitempty" validate:"omitempty,min=1"`
+	Types         []string   `json:"types,omitempty" validate:"omitempty,dive,notification_type"`
+	CreatedAfter  *time.Time `json:"created_after,omitempty"`
+	CreatedBefore *time.Time `json:"created_before,omitempty"`
+	Persistent    *bool      `json:"persistent,omitempty"`
+	DataKeys      []string   `json:"data_keys,omitempty" validate:"omitempty,dive,min=1,max=100"`
+	SortBy        string     `json:"sort_by,omitempty" validate:"omitempty,oneof=relevance created_at"`
+	SortOrder     string     `json:"sort_order,omitempty" validate:"omitempty,oneof=asc desc"`
+	Limit         int32      `json:"limit" validate:"min=0,max=1000"`
+	Offset        int32      `json:"offset" validate:"min=0"`
+}
+
+// NotificationSearchResult is a single search hit
+type NotificationSearchResult struct {
+	Notification *Notification `json:"notification"`
+	Snippet      string        `json:"snippet"` // HTML-escaped message with matches wrapped in <mark></mark>
+	Rank         float32       `json:"rank"`
 }
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"backend-grpc-server/internal/validation"
+)
+
+// MaxBatchSize limits the number of items in a single batch request
+const MaxBatchSize = 1000
+
+// NotificationFilter selects a user's notifications for a batch operation
+type NotificationFilter struct {
+	Read          *bool      `json:"read,omitempty"`
+	Types         []string   `json:"types,omitempty" validate:"omitempty,max=50,dive,notification_type"`
+	CreatedAfter  *time.Time `json:"created_after,omitempty"`
+	CreatedBefore *time.Time `json:"created_before,omitempty"`
+	Archived      *bool      `json:"archived,omitempty"`
+}
+
+// BatchNotificationParams selects notifications by ID list or by filter.
+// Exactly one of IDs and Filter must be set.
+type BatchNotificationParams struct {
+	UserID int32               `json:"user_id" validate:"required,min=1"`
+	IDs    []int32             `json:"ids,omitempty" validate:"omitempty,max=1000,dive,min=1"`
+	Filter *NotificationFilter `json:"filter,omitempty"`
+}
+
+// Validate checks that exactly one selector is used
+func (p *BatchNotificationParams) Validate() error {
+	if err := validation.ValidateStruct(p); err != nil {
+		return err
+	}
+
+	if len(p.IDs) == 0 && p.Filter == nil {
+		return fmt.Errorf("either ids or filter is required")
+	}
+	if len(p.IDs) > 0 && p.Filter != nil {
+		return fmt.Errorf("ids and filter cannot be combined")
+	}
+
+	if p.Filter != nil {
+		if err := validation.ValidateStruct(p.Filter); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BatchItemResult reports the outcome of one item in a batch operation.
+// Index refers to the position in the request for bulk creates.
+type BatchItemResult struct {
+	Index   int32  `json:"index"`
+	ID      int32  `json:"id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
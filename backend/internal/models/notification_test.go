@@ -0,0 +1,109 @@
+package models
+
+import (
+	"testing"
+
+	"backend-grpc-server/internal/notificationtypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationTarget_Validate(t *testing.T) {
+	userID := int32(1)
+
+	tests := []struct {
+		name    string
+		target  NotificationTarget
+		wantErr bool
+	}{
+		{name: "all", target: NotificationTarget{Type: TargetAll}, wantErr: false},
+		{name: "user", target: NotificationTarget{Type: TargetUser, UserID: &userID}, wantErr: false},
+		{name: "user without ID", target: NotificationTarget{Type: TargetUser}, wantErr: true},
+		{name: "users", target: NotificationTarget{Type: TargetUsers, UserIDs: []int32{1, 2}}, wantErr: false},
+		{name: "users with invalid ID", target: NotificationTarget{Type: TargetUsers, UserIDs: []int32{1, 0}}, wantErr: true},
+		{name: "users without IDs", target: NotificationTarget{Type: TargetUsers}, wantErr: true},
+		{name: "role", target: NotificationTarget{Type: TargetRole, Role: "admin"}, wantErr: false},
+		{name: "unknown role", target: NotificationTarget{Type: TargetRole, Role: "root"}, wantErr: true},
+		{name: "group", target: NotificationTarget{Type: TargetGroup, Group: "support"}, wantErr: false},
+		{name: "group without name", target: NotificationTarget{Type: TargetGroup}, wantErr: true},
+		{name: "audience", target: NotificationTarget{Type: TargetAudience, AudienceID: 3}, wantErr: false},
+		{name: "audience without ID", target: NotificationTarget{Type: TargetAudience}, wantErr: true},
+		{name: "unknown type", target: NotificationTarget{Type: "everyone"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.target.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCreateNotificationParams_Validate_Type(t *testing.T) {
+	// Register into a fresh default registry so reruns start without the type
+	previous := notificationtypes.Default
+	notificationtypes.Default = notificationtypes.NewRegistry()
+	t.Cleanup(func() { notificationtypes.Default = previous })
+
+	params := &CreateNotificationParams{Message: "Hello", Type: "info"}
+	assert.NoError(t, params.Validate())
+
+	params.Type = "survey_reminder"
+	assert.Error(t, params.Validate())
+
+	notificationtypes.MustRegister(notificationtypes.Type{
+		Name:     "survey_reminder",
+		Severity: notificationtypes.SeverityInfo,
+		Channels: []string{notificationtypes.ChannelInApp},
+	})
+	assert.NoError(t, params.Validate())
+}
+
+func TestBatchNotificationParams_Validate(t *testing.T) {
+	read := true
+
+	tooMany := make([]int32, MaxBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = int32(i + 1)
+	}
+
+	tests := []struct {
+		name    string
+		params  BatchNotificationParams
+		wantErr bool
+	}{
+		{name: "ids", params: BatchNotificationParams{UserID: 1, IDs: []int32{1, 2}}, wantErr: false},
+		{name: "filter", params: BatchNotificationParams{UserID: 1, Filter: &NotificationFilter{Read: &read}}, wantErr: false},
+		{name: "no selector", params: BatchNotificationParams{UserID: 1}, wantErr: true},
+		{name: "both selectors", params: BatchNotificationParams{UserID: 1, IDs: []int32{1}, Filter: &NotificationFilter{}}, wantErr: true},
+		{name: "missing user", params: BatchNotificationParams{IDs: []int32{1}}, wantErr: true},
+		{name: "invalid ID", params: BatchNotificationParams{UserID: 1, IDs: []int32{0}}, wantErr: true},
+		{name: "unknown type", params: BatchNotificationParams{UserID: 1, Filter: &NotificationFilter{Types: []string{"nope"}}}, wantErr: true},
+		{name: "too many IDs", params: BatchNotificationParams{UserID: 1, IDs: tooMany}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestUpdateNotificationParams_Validate_Mask(t *testing.T) {
+	params := &UpdateNotificationParams{ID: 1, Read: true, Fields: []string{"read"}}
+	assert.NoError(t, params.Validate())
+
+	params = &UpdateNotificationParams{ID: 1, Type: "unknown", Fields: []string{"type"}}
+	assert.Error(t, params.Validate())
+
+	params = &UpdateNotificationParams{ID: 1, Fields: []string{"user_id"}}
+	assert.Error(t, params.Validate())
+}
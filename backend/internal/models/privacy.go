@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"backend-grpc-server/internal/validation"
+)
+
+// Erasure modes
+const (
+	ErasureAnonymize = "anonymize" // scrub personal data but keep the records
+	ErasureDelete    = "delete"    // remove the records
+)
+
+// EraseUserParams describes an erasure request
+type EraseUserParams struct {
+	UserID      int32  `json:"user_id" validate:"required,min=1"`
+	Mode        string `json:"mode" validate:"required,oneof=anonymize delete"`
+	RequestedBy string `json:"requested_by" validate:"required,max=255"`
+	Reason      string `json:"reason" validate:"max=1000"`
+}
+
+func (p *EraseUserParams) Validate() error {
+	return validation.ValidateStruct(p)
+}
+
+// ErasureCertificate records that a user's data was erased. It holds no
+// personal data itself, only the user ID and how many records were affected.
+type ErasureCertificate struct {
+	ID          int32            `json:"id" db:"id"`
+	UserID      int32            `json:"user_id" db:"user_id"`
+	Mode        string           `json:"mode" db:"mode"`
+	RequestedBy string           `json:"requested_by" db:"requested_by"`
+	Reason      string           `json:"reason" db:"reason"`
+	Sources     map[string]int64 `json:"sources" db:"sources"` // affected records per source
+	Digest      string           `json:"digest" db:"digest"`   // SHA-256 over the fields above
+	ErasedAt    time.Time        `json:"erased_at" db:"erased_at"`
+}
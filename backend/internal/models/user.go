@@ -1,19 +1,37 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
+	"backend-grpc-server/internal/pagination"
+	"backend-grpc-server/internal/validation"
 	"github.com/go-playground/validator/v10"
 )
 
 type User struct {
-	ID        int32     `json:"id" db:"id"`
-	Name      string    `json:"name" db:"name" validate:"required,min=2,max=100"`
-	Email     string    `json:"email" db:"email" validate:"required,email"`
-	Age       int32     `json:"age" db:"age" validate:"required,min=1,max=150"`
-	Role      string    `json:"role" db:"role" validate:"required,oneof=admin user moderator"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID        int32      `json:"id" db:"id"`
+	Name      string     `json:"name" db:"name" validate:"required,min=2,max=100"`
+	Email     string     `json:"email" db:"email" validate:"required,email"`
+	Age       int32      `json:"age" db:"age" validate:"required,min=1,max=150"`
+	Role      string     `json:"role" db:"role" validate:"required,oneof=admin user moderator"`
+	Version   int32      `json:"version" db:"version"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"` // set while soft deleted
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+
+	// EmailVerified is reset whenever the address changes outside the
+	// confirmation flow
+	EmailVerified bool `json:"email_verified" db:"email_verified"`
+	// TwoFactorEnabled is set once TOTP enrollment is confirmed
+	TwoFactorEnabled bool `json:"two_factor_enabled" db:"two_factor_enabled"`
+	// LockedUntil is set while the account is locked after failed logins
+	LockedUntil *time.Time `json:"locked_until,omitempty" db:"locked_until"`
+}
+
+// Locked reports whether logins to the account are refused at the given time
+func (u *User) Locked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
 }
 
 type CreateUserParams struct {
@@ -29,20 +47,125 @@ type UpdateUserParams struct {
 	Email string `json:"email" validate:"required,email"`
 	Age   int32  `json:"age" validate:"required,min=1,max=150"`
 	Role  string `json:"role" validate:"required,oneof=admin user moderator"`
+
+	// Fields holds the update mask paths, empty updates every field
+	Fields []string `json:"fields,omitempty"`
+
+	// ExpectedVersion must match the stored version, 0 skips the check
+	ExpectedVersion int32 `json:"expected_version,omitempty" validate:"min=0"`
+}
+
+// UserUpdateFields maps update mask paths to UpdateUserParams fields
+var UserUpdateFields = map[string]string{
+	"name":  "Name",
+	"email": "Email",
+	"age":   "Age",
+	"role":  "Role",
 }
 
 type ListUsersParams struct {
 	Limit  int32 `json:"limit"`
 	Offset int32 `json:"offset"`
+
+	// Filters, zero values are ignored
+	Roles         []string   `json:"roles,omitempty" validate:"omitempty,max=3,dive,oneof=admin user moderator"`
+	Search        string     `json:"search,omitempty" validate:"max=100"` // matches name or email, case-insensitive
+	SearchMode    string     `json:"search_mode,omitempty" validate:"omitempty,oneof=contains prefix"`
+	MinAge        int32      `json:"min_age,omitempty" validate:"min=0,max=150"`
+	MaxAge        int32      `json:"max_age,omitempty" validate:"min=0,max=150"`
+	CreatedAfter  *time.Time `json:"created_after,omitempty"`
+	CreatedBefore *time.Time `json:"created_before,omitempty"`
+
+	// IncludeDeleted also lists soft deleted users, for admins
+	IncludeDeleted bool `json:"include_deleted,omitempty"`
+
+	// Sorting, defaults to created_at desc
+	SortBy    string `json:"sort_by,omitempty" validate:"omitempty,oneof=id name email age role created_at updated_at"`
+	SortOrder string `json:"sort_order,omitempty" validate:"omitempty,oneof=asc desc"`
+
+	// Keyset pagination, replaces Offset when set. Only valid for created_at sorting.
+	Cursor    *pagination.Cursor `json:"-"`
+	SkipTotal bool               `json:"skip_total,omitempty"` // skip the COUNT(*), total is reported as -1
 }
 
+// SortsByCreatedAt reports whether the list is ordered by creation time,
+// which is the order page tokens are built on
+func (p *ListUsersParams) SortsByCreatedAt() bool {
+	return p.SortBy == "" || p.SortBy == "created_at"
+}
+
+// User search modes
+const (
+	SearchContains = "contains"
+	SearchPrefix   = "prefix"
+)
+
 // Validation functions
 func (u *CreateUserParams) Validate() error {
 	validate := validator.New()
 	return validate.Struct(u)
 }
 
+// Validate checks the ID, the expected version and the fields selected by the update mask
 func (u *UpdateUserParams) Validate() error {
-	validate := validator.New()
-	return validate.Struct(u)
+	if len(u.Fields) == 0 {
+		return validation.ValidateStruct(u)
+	}
+
+	fields, err := maskedFields(u.Fields, UserUpdateFields)
+	if err != nil {
+		return err
+	}
+	return validation.ValidateStructPartial(u, append(fields, "ID", "ExpectedVersion")...)
+}
+
+// Updates reports whether the update writes the field with the given mask path
+func (u *UpdateUserParams) Updates(path string) bool {
+	if len(u.Fields) == 0 {
+		return true
+	}
+	for _, field := range u.Fields {
+		if field == path {
+			return true
+		}
+	}
+	return false
+}
+
+// maskedFields translates update mask paths into struct field names
+func maskedFields(paths []string, allowed map[string]string) ([]string, error) {
+	fields := make([]string, 0, len(paths))
+	for _, path := range paths {
+		field, ok := allowed[path]
+		if !ok {
+			return nil, fmt.Errorf("field %q cannot be updated", path)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// Validate checks the allowlisted filter and sort values and that the ranges are ordered
+func (p *ListUsersParams) Validate() error {
+	if err := validation.ValidateStruct(p); err != nil {
+		return err
+	}
+
+	if p.MinAge > 0 && p.MaxAge > 0 && p.MinAge > p.MaxAge {
+		return fmt.Errorf("min_age cannot exceed max_age")
+	}
+	if p.CreatedAfter != nil && p.CreatedBefore != nil && p.CreatedAfter.After(*p.CreatedBefore) {
+		return fmt.Errorf("created_after cannot be later than created_before")
+	}
+
+	if p.Cursor != nil {
+		if !p.SortsByCreatedAt() {
+			return fmt.Errorf("page tokens require sorting by created_at")
+		}
+		if p.Cursor.Descending != (p.SortOrder != "asc") {
+			return fmt.Errorf("page token does not match sort_order")
+		}
+	}
+
+	return nil
 }
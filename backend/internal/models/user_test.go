@@ -1,8 +1,10 @@
 package models
 
 import (
+	"backend-grpc-server/internal/pagination"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestCreateUserParams_Validate(t *testing.T) {
@@ -114,3 +116,102 @@ func TestUpdateUserParams_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestListUsersParams_Validate(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		params  ListUsersParams
+		wantErr bool
+	}{
+		{name: "empty", params: ListUsersParams{}, wantErr: false},
+		{
+			name: "all filters",
+			params: ListUsersParams{
+				Roles:         []string{"admin", "moderator"},
+				Search:        "jo",
+				SearchMode:    SearchPrefix,
+				MinAge:        18,
+				MaxAge:        65,
+				CreatedAfter:  &earlier,
+				CreatedBefore: &now,
+				SortBy:        "name",
+				SortOrder:     "asc",
+			},
+			wantErr: false,
+		},
+		{name: "unknown role", params: ListUsersParams{Roles: []string{"root"}}, wantErr: true},
+		{name: "unknown search mode", params: ListUsersParams{Search: "jo", SearchMode: "regex"}, wantErr: true},
+		{name: "unknown sort field", params: ListUsersParams{SortBy: "password"}, wantErr: true},
+		{name: "unknown sort order", params: ListUsersParams{SortOrder: "sideways"}, wantErr: true},
+		{name: "inverted age range", params: ListUsersParams{MinAge: 40, MaxAge: 20}, wantErr: true},
+		{name: "inverted date range", params: ListUsersParams{CreatedAfter: &now, CreatedBefore: &earlier}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestListUsersParams_Validate_Cursor(t *testing.T) {
+	cursor := &pagination.Cursor{CreatedAt: time.Now(), ID: 1, Descending: true}
+
+	params := ListUsersParams{Cursor: cursor}
+	assert.NoError(t, params.Validate())
+
+	params = ListUsersParams{Cursor: cursor, SortBy: "name"}
+	assert.Error(t, params.Validate())
+
+	params = ListUsersParams{Cursor: cursor, SortOrder: "asc"}
+	assert.Error(t, params.Validate())
+}
+
+func TestUpdateUserParams_Validate_Mask(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  UpdateUserParams
+		wantErr bool
+	}{
+		{name: "name only", params: UpdateUserParams{ID: 1, Name: "Jane", Fields: []string{"name"}}, wantErr: false},
+		{name: "age and role", params: UpdateUserParams{ID: 1, Age: 40, Role: "admin", Fields: []string{"age", "role"}}, wantErr: false},
+		{name: "invalid masked field", params: UpdateUserParams{ID: 1, Email: "nope", Fields: []string{"email"}}, wantErr: true},
+		{name: "unknown path", params: UpdateUserParams{ID: 1, Fields: []string{"password"}}, wantErr: true},
+		{name: "missing ID", params: UpdateUserParams{Name: "Jane", Fields: []string{"name"}}, wantErr: true},
+		{name: "no mask requires every field", params: UpdateUserParams{ID: 1, Name: "Jane"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestUpdateUserParams_Updates(t *testing.T) {
+	assert.True(t, (&UpdateUserParams{}).Updates("email"))
+	assert.True(t, (&UpdateUserParams{Fields: []string{"name", "email"}}).Updates("email"))
+	assert.False(t, (&UpdateUserParams{Fields: []string{"name"}}).Updates("email"))
+}
+
+func TestUser_Locked(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Minute)
+
+	assert.False(t, (&User{}).Locked(now))
+	assert.True(t, (&User{LockedUntil: &later}).Locked(now))
+	assert.False(t, (&User{LockedUntil: &now}).Locked(now))
+}
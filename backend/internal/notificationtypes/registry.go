@@ -0,0 +1,160 @@
+package notificationtypes
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// Delivery channels a notification type can be sent through
+const (
+	ChannelInApp    = "in_app"   // stored in the user's notification inbox
+	ChannelRealtime = "realtime" // pushed to connected sockets
+	ChannelEmail    = "email"    // reserved for mail delivery
+)
+
+// Severities map a type onto the styles the frontend knows how to render
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+	SeveritySuccess = "success"
+)
+
+var (
+	validChannels   = map[string]bool{ChannelInApp: true, ChannelRealtime: true, ChannelEmail: true}
+	validSeverities = map[string]bool{SeverityInfo: true, SeverityWarning: true, SeverityError: true, SeveritySuccess: true}
+	namePattern     = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+)
+
+// Type describes a kind of notification and how it is delivered
+type Type struct {
+	Name          string   `json:"name"`
+	Severity      string   `json:"severity"`
+	Icon          string   `json:"icon,omitempty"`
+	RetentionDays int32    `json:"retention_days"` // 0 keeps notifications forever
+	Channels      []string `json:"channels"`
+	Builtin       bool     `json:"builtin"`
+}
+
+// AllowsChannel reports whether notifications of this type may use the channel
+func (t Type) AllowsChannel(channel string) bool {
+	for _, c := range t.Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the name, severity and channels of a type
+func (t Type) Validate() error {
+	if !namePattern.MatchString(t.Name) {
+		return fmt.Errorf("name must be 2-50 lowercase letters, digits or underscores and start with a letter")
+	}
+	if !validSeverities[t.Severity] {
+		return fmt.Errorf("severity must be one of: info warning error success")
+	}
+	if t.RetentionDays < 0 {
+		return fmt.Errorf("retention_days cannot be negative")
+	}
+	if len(t.Channels) == 0 {
+		return fmt.Errorf("at least one channel is required")
+	}
+	for _, channel := range t.Channels {
+		if !validChannels[channel] {
+			return fmt.Errorf("unknown channel %q", channel)
+		}
+	}
+	return nil
+}
+
+// Builtin types are always registered and cannot be replaced
+var Builtin = []Type{
+	{Name: "info", Severity: SeverityInfo, Icon: "info", Channels: []string{ChannelInApp, ChannelRealtime}, Builtin: true},
+	{Name: "warning", Severity: SeverityWarning, Icon: "warning", Channels: []string{ChannelInApp, ChannelRealtime}, Builtin: true},
+	{Name: "error", Severity: SeverityError, Icon: "error", Channels: []string{ChannelInApp, ChannelRealtime}, Builtin: true},
+	{Name: "success", Severity: SeveritySuccess, Icon: "check_circle", Channels: []string{ChannelInApp, ChannelRealtime}, Builtin: true},
+}
+
+// Registry holds the known notification types. It is safe for concurrent use.
+type Registry struct {
+	mu    sync.RWMutex
+	types map[string]Type
+}
+
+// NewRegistry creates a registry containing the builtin types
+func NewRegistry() *Registry {
+	r := &Registry{types: make(map[string]Type)}
+	for _, t := range Builtin {
+		r.types[t.Name] = t
+	}
+	return r
+}
+
+// Register adds or replaces a type. Builtin types cannot be replaced.
+func (r *Registry) Register(t Type) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.types[t.Name]; ok && existing.Builtin {
+		return fmt.Errorf("builtin notification type %q cannot be changed", t.Name)
+	}
+	t.Builtin = false
+	r.types[t.Name] = t
+	return nil
+}
+
+// Lookup returns the type with the given name
+func (r *Registry) Lookup(name string) (Type, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.types[name]
+	return t, ok
+}
+
+// Exists reports whether a type with the given name is registered
+func (r *Registry) Exists(name string) bool {
+	_, ok := r.Lookup(name)
+	return ok
+}
+
+// List returns all registered types sorted by name
+func (r *Registry) List() []Type {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	types := make([]Type, 0, len(r.types))
+	for _, t := range r.types {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+	return types
+}
+
+// Default is the process-wide registry used by the notification_type validator
+var Default = NewRegistry()
+
+// Register adds a type to the default registry. Call it at startup to add
+// domain types such as "survey_reminder" from Go code.
+func Register(t Type) error {
+	return Default.Register(t)
+}
+
+// MustRegister is like Register but panics on error
+func MustRegister(t Type) {
+	if err := Register(t); err != nil {
+		panic(fmt.Sprintf("notificationtypes: %v", err))
+	}
+}
+
+// Lookup finds a type in the default registry
+func Lookup(name string) (Type, bool) {
+	return Default.Lookup(name)
+}
@@ -0,0 +1,79 @@
+package notificationtypes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_Builtin(t *testing.T) {
+	r := NewRegistry()
+
+	for _, name := range []string{"info", "warning", "error", "success"} {
+		notificationType, ok := r.Lookup(name)
+		require.True(t, ok, name)
+		assert.True(t, notificationType.Builtin)
+		assert.True(t, notificationType.AllowsChannel(ChannelInApp))
+		assert.True(t, notificationType.AllowsChannel(ChannelRealtime))
+	}
+
+	err := r.Register(Type{Name: "info", Severity: SeverityWarning, Channels: []string{ChannelRealtime}})
+	assert.Error(t, err)
+
+	notificationType, _ := r.Lookup("info")
+	assert.Equal(t, SeverityInfo, notificationType.Severity)
+}
+
+func TestRegistry_Register(t *testing.T) {
+	r := NewRegistry()
+
+	err := r.Register(Type{
+		Name:          "survey_reminder",
+		Severity:      SeverityInfo,
+		Icon:          "poll",
+		RetentionDays: 30,
+		Channels:      []string{ChannelInApp},
+		Builtin:       true,
+	})
+	require.NoError(t, err)
+
+	notificationType, ok := r.Lookup("survey_reminder")
+	require.True(t, ok)
+	assert.False(t, notificationType.Builtin)
+	assert.Equal(t, int32(30), notificationType.RetentionDays)
+	assert.False(t, notificationType.AllowsChannel(ChannelRealtime))
+	assert.Len(t, r.List(), 5)
+
+	// Custom types can be updated
+	require.NoError(t, r.Register(Type{Name: "survey_reminder", Severity: SeverityWarning, Channels: []string{ChannelRealtime}}))
+	notificationType, _ = r.Lookup("survey_reminder")
+	assert.Equal(t, SeverityWarning, notificationType.Severity)
+}
+
+func TestType_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		t       Type
+		wantErr bool
+	}{
+		{name: "valid", t: Type{Name: "payment", Severity: SeveritySuccess, Channels: []string{ChannelInApp}}, wantErr: false},
+		{name: "uppercase name", t: Type{Name: "Payment", Severity: SeverityInfo, Channels: []string{ChannelInApp}}, wantErr: true},
+		{name: "short name", t: Type{Name: "p", Severity: SeverityInfo, Channels: []string{ChannelInApp}}, wantErr: true},
+		{name: "unknown severity", t: Type{Name: "payment", Severity: "critical", Channels: []string{ChannelInApp}}, wantErr: true},
+		{name: "no channels", t: Type{Name: "payment", Severity: SeverityInfo}, wantErr: true},
+		{name: "unknown channel", t: Type{Name: "payment", Severity: SeverityInfo, Channels: []string{"sms"}}, wantErr: true},
+		{name: "negative retention", t: Type{Name: "payment", Severity: SeverityInfo, Channels: []string{ChannelInApp}, RetentionDays: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.t.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"math/big"
+)
+
+// jwk is an RSA signing key from the provider's key set
+type jwk struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+
+	public *rsa.PublicKey
+}
+
+// usable reports whether the key is an RSA signing key and decodes it
+func (k *jwk) usable() bool {
+	if k.KeyType != "RSA" || (k.Use != "" && k.Use != "sig") || (k.Algorithm != "" && k.Algorithm != "RS256") {
+		return false
+	}
+
+	n, err := base64.RawURLEncoding.DecodeString(k.Modulus)
+	if err != nil || len(n) == 0 {
+		return false
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.Exponent)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return false
+	}
+
+	k.public = &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}
+	return k.public.N.BitLen() >= 2048
+}
+
+// verify checks an RS256 signature
+func (k *jwk) verify(signed string, signature []byte) error {
+	sum := sha256.Sum256([]byte(signed))
+	return rsa.VerifyPKCS1v15(k.public, crypto.SHA256, sum[:], signature)
+}
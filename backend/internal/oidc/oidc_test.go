@@ -0,0 +1,112 @@
+package oidc
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"backend-grpc-server/internal/oidc/oidctest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_CodeFlow(t *testing.T) {
+	idp := oidctest.NewServer("client", "secret")
+	defer idp.Close()
+
+	provider := NewProvider(Config{
+		Issuer:       idp.Issuer(),
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:3000/auth/oidc/callback",
+	})
+	ctx := context.Background()
+
+	request, err := provider.AuthCodeURL(ctx)
+	require.NoError(t, err)
+
+	authURL, err := url.Parse(request.URL)
+	require.NoError(t, err)
+	assert.Equal(t, idp.Issuer()+"/authorize", authURL.Scheme+"://"+authURL.Host+authURL.Path)
+	assert.Equal(t, "openid email profile", authURL.Query().Get("scope"))
+	assert.Equal(t, CodeChallenge(request.CodeVerifier), authURL.Query().Get("code_challenge"))
+
+	code, state, err := idp.Authorize(request.URL, map[string]interface{}{
+		"sub":            "user-1",
+		"email":          "jane@example.com",
+		"email_verified": true,
+		"name":           "Jane Doe",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, request.State, state)
+
+	// The code is bound to the verifier
+	_, err = provider.Exchange(ctx, code, "wrong verifier", request.Nonce)
+	assert.Error(t, err)
+
+	code, _, err = idp.Authorize(request.URL, map[string]interface{}{"sub": "user-1", "email": "jane@example.com", "email_verified": true, "name": "Jane Doe"})
+	require.NoError(t, err)
+	claims, err := provider.Exchange(ctx, code, request.CodeVerifier, request.Nonce)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims.Subject)
+	assert.Equal(t, "jane@example.com", claims.Email)
+	assert.True(t, claims.EmailVerified)
+	assert.Equal(t, "Jane Doe", claims.Name)
+}
+
+func TestProvider_VerifyIDToken(t *testing.T) {
+	idp := oidctest.NewServer("client", "secret")
+	defer idp.Close()
+
+	provider := NewProvider(Config{Issuer: idp.Issuer(), ClientID: "client", ClientSecret: "secret"})
+	ctx := context.Background()
+	now := time.Now()
+
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss":   idp.Issuer(),
+			"sub":   "user-1",
+			"aud":   []string{"client"},
+			"exp":   now.Add(time.Hour).Unix(),
+			"iat":   now.Unix(),
+			"nonce": "nonce",
+		}
+		for name, value := range overrides {
+			c[name] = value
+		}
+		return c
+	}
+
+	_, err := provider.VerifyIDToken(ctx, idp.SignIDToken(claims(nil)), "nonce", now)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		overrides map[string]interface{}
+	}{
+		{"other issuer", map[string]interface{}{"iss": "https://evil.example.com"}},
+		{"other audience", map[string]interface{}{"aud": "other"}},
+		{"shared audience without azp", map[string]interface{}{"aud": []string{"client", "other"}}},
+		{"expired", map[string]interface{}{"exp": now.Add(-2 * time.Minute).Unix()}},
+		{"other nonce", map[string]interface{}{"nonce": "replayed"}},
+		{"no subject", map[string]interface{}{"sub": ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := provider.VerifyIDToken(ctx, idp.SignIDToken(claims(tt.overrides)), "nonce", now)
+			assert.ErrorIs(t, err, ErrInvalidIDToken)
+		})
+	}
+
+	// Signatures from another key and unsigned tokens are rejected
+	other := oidctest.NewServer("client", "secret")
+	defer other.Close()
+	_, err = provider.VerifyIDToken(ctx, other.SignIDToken(claims(nil)), "nonce", now)
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+
+	unsigned := "eyJhbGciOiJub25lIn0." + "e30."
+	_, err = provider.VerifyIDToken(ctx, unsigned, "nonce", now)
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+}
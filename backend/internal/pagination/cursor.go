@@ -0,0 +1,67 @@
+// Package pagination implements opaque page tokens for keyset pagination
+// over (created_at, id).
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidToken is returned for page tokens that cannot be decoded
+var ErrInvalidToken = errors.New("invalid page token")
+
+// Cursor points at the last row of a page. The next page starts right after it.
+type Cursor struct {
+	CreatedAt  time.Time `json:"t"`
+	ID         int32     `json:"id"`
+	Descending bool      `json:"d"`
+}
+
+// Encode returns the opaque page token for the cursor
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode parses a page token. An empty token returns a nil cursor.
+func Decode(token string) (*Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID <= 0 || c.CreatedAt.IsZero() {
+		return nil, ErrInvalidToken
+	}
+
+	return &c, nil
+}
+
+// Condition returns a WHERE condition selecting the rows after the cursor.
+// Placeholders start after argOffset.
+func (c *Cursor) Condition(argOffset int) (string, []interface{}) {
+	operator := ">"
+	if c.Descending {
+		operator = "<"
+	}
+
+	condition := fmt.Sprintf("(created_at, id) %s ($%d, $%d)", operator, argOffset+1, argOffset+2)
+	return condition, []interface{}{c.CreatedAt, c.ID}
+}
+
+// NextToken returns the token for the page after a row, or an empty string
+// when there are no more rows
+func NextToken(hasMore bool, createdAt time.Time, id int32, descending bool) string {
+	if !hasMore {
+		return ""
+	}
+	return Cursor{CreatedAt: createdAt, ID: id, Descending: descending}.Encode()
+}
@@ -0,0 +1,48 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	createdAt := time.Date(2026, 10, 19, 12, 30, 45, 123456000, time.UTC)
+	token := Cursor{CreatedAt: createdAt, ID: 42, Descending: true}.Encode()
+
+	cursor, err := Decode(token)
+	require.NoError(t, err)
+	assert.True(t, createdAt.Equal(cursor.CreatedAt))
+	assert.Equal(t, int32(42), cursor.ID)
+	assert.True(t, cursor.Descending)
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	cursor, err := Decode("")
+	assert.NoError(t, err)
+	assert.Nil(t, cursor)
+
+	for _, token := range []string{"not base64!", "bm90IGpzb24", Cursor{ID: 1}.Encode(), Cursor{CreatedAt: time.Now()}.Encode()} {
+		_, err := Decode(token)
+		assert.ErrorIs(t, err, ErrInvalidToken, token)
+	}
+}
+
+func TestCursor_Condition(t *testing.T) {
+	cursor := &Cursor{CreatedAt: time.Now(), ID: 7, Descending: true}
+
+	condition, args := cursor.Condition(2)
+	assert.Equal(t, "(created_at, id) < ($3, $4)", condition)
+	assert.Len(t, args, 2)
+
+	cursor.Descending = false
+	condition, _ = cursor.Condition(0)
+	assert.Equal(t, "(created_at, id) > ($1, $2)", condition)
+}
+
+func TestNextToken(t *testing.T) {
+	assert.Empty(t, NextToken(false, time.Now(), 1, true))
+	assert.NotEmpty(t, NextToken(true, time.Now(), 1, true))
+}
@@ -0,0 +1,81 @@
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Export formats
+const (
+	FormatJSON = "json" // a single JSON document
+	FormatZIP  = "zip"  // one JSON file per section plus a manifest
+)
+
+// WriteJSON writes the export as one indented JSON document
+func (e *Export) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(e)
+}
+
+// WriteZIP writes the export as a ZIP archive with manifest.json and one
+// <section>.json file per source
+func (e *Export) WriteZIP(w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	manifest := map[string]interface{}{
+		"user_id":     e.UserID,
+		"exported_at": e.ExportedAt,
+		"sections":    e.order,
+	}
+	if err := writeZIPEntry(archive, "manifest.json", manifest); err != nil {
+		return err
+	}
+
+	for _, name := range e.order {
+		if err := writeZIPEntry(archive, name+".json", e.Sections[name]); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// Encode renders the export in the given format and returns the content with
+// a suggested file name and content type
+func (e *Export) Encode(format string) ([]byte, string, string, error) {
+	var buf bytes.Buffer
+	base := fmt.Sprintf("user-%d-export-%s", e.UserID, e.ExportedAt.Format("20060102T150405Z"))
+
+	switch format {
+	case "", FormatJSON:
+		if err := e.WriteJSON(&buf); err != nil {
+			return nil, "", "", fmt.Errorf("failed to encode export: %w", err)
+		}
+		return buf.Bytes(), base + ".json", "application/json", nil
+	case FormatZIP:
+		if err := e.WriteZIP(&buf); err != nil {
+			return nil, "", "", fmt.Errorf("failed to encode export: %w", err)
+		}
+		return buf.Bytes(), base + ".zip", "application/zip", nil
+	default:
+		return nil, "", "", fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+func writeZIPEntry(archive *zip.Writer, name string, data interface{}) error {
+	entry, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
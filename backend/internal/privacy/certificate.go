@@ -0,0 +1,39 @@
+package privacy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"backend-grpc-server/internal/models"
+)
+
+// Digest returns the SHA-256 over the certified fields of a certificate.
+// The ID and the digest itself are not covered.
+func Digest(certificate *models.ErasureCertificate) string {
+	// encoding/json sorts map keys, so the encoding is stable
+	data, _ := json.Marshal(struct {
+		UserID      int32            `json:"user_id"`
+		Mode        string           `json:"mode"`
+		RequestedBy string           `json:"requested_by"`
+		Reason      string           `json:"reason"`
+		Sources     map[string]int64 `json:"sources"`
+		ErasedAt    string           `json:"erased_at"`
+	}{
+		UserID:      certificate.UserID,
+		Mode:        certificate.Mode,
+		RequestedBy: certificate.RequestedBy,
+		Reason:      certificate.Reason,
+		Sources:     certificate.Sources,
+		ErasedAt:    certificate.ErasedAt.UTC().Format(time.RFC3339Nano),
+	})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify reports whether a certificate still matches its digest
+func Verify(certificate *models.ErasureCertificate) bool {
+	return certificate.Digest == Digest(certificate)
+}
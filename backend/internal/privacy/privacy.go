@@ -0,0 +1,144 @@
+// Package privacy implements the GDPR data export and erasure workflow.
+//
+// Every store that holds personal data contributes a Source. The profile,
+// notifications, invitations, email verifications, credentials, linked
+// identities, sessions and the audit log are covered; preferences and survey
+// responses are not stored by this backend yet and plug in as further sources
+// once they are.
+package privacy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/storage"
+)
+
+// Source exports and erases one kind of personal data
+type Source interface {
+	// Name identifies the source in exports and erasure certificates
+	Name() string
+
+	// Export returns the user's data in a JSON encodable form
+	Export(userID int32) (interface{}, error)
+
+	// Erase anonymizes or deletes the user's data according to mode and
+	// returns the number of affected records. Running it twice is harmless.
+	Erase(userID int32, mode string) (int64, error)
+}
+
+// Export holds everything the sources know about a user
+type Export struct {
+	UserID     int32                  `json:"user_id"`
+	ExportedAt time.Time              `json:"exported_at"`
+	Sections   map[string]interface{} `json:"sections"`
+
+	// order keeps the sections in registration order for archives
+	order []string
+}
+
+// Service runs exports and erasures over the registered sources
+type Service struct {
+	mu           sync.RWMutex
+	sources      []Source
+	certificates storage.PrivacyStore
+}
+
+func NewService(certificates storage.PrivacyStore) *Service {
+	return &Service{
+		certificates: certificates,
+	}
+}
+
+// Register adds a source. Names must be unique.
+func (s *Service) Register(source Source) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.sources {
+		if existing.Name() == source.Name() {
+			return fmt.Errorf("privacy source %q is already registered", source.Name())
+		}
+	}
+
+	s.sources = append(s.sources, source)
+	return nil
+}
+
+// Sources returns the names of the registered sources in registration order
+func (s *Service) Sources() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.sources))
+	for _, source := range s.sources {
+		names = append(names, source.Name())
+	}
+	return names
+}
+
+// Export collects the user's data from every source
+func (s *Service) Export(userID int32) (*Export, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	export := &Export{
+		UserID:     userID,
+		ExportedAt: time.Now().UTC(),
+		Sections:   make(map[string]interface{}, len(s.sources)),
+	}
+
+	for _, source := range s.sources {
+		data, err := source.Export(userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", source.Name(), err)
+		}
+		export.Sections[source.Name()] = data
+		export.order = append(export.order, source.Name())
+	}
+
+	return export, nil
+}
+
+// Erase runs every source in reverse registration order, so data that depends
+// on the profile goes before the profile itself, and stores a certificate.
+// A failed erasure is not certified and can simply be retried. The
+// certificate is attributed to the request in ctx.
+func (s *Service) Erase(ctx context.Context, params *models.EraseUserParams) (*models.ErasureCertificate, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	affected := make(map[string]int64, len(s.sources))
+	for i := len(s.sources) - 1; i >= 0; i-- {
+		source := s.sources[i]
+		count, err := source.Erase(params.UserID, params.Mode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to erase %s: %w", source.Name(), err)
+		}
+		affected[source.Name()] = count
+	}
+
+	certificate := &models.ErasureCertificate{
+		UserID:      params.UserID,
+		Mode:        params.Mode,
+		RequestedBy: params.RequestedBy,
+		Reason:      params.Reason,
+		Sources:     affected,
+		// Postgres keeps microseconds, the digest has to survive the round trip
+		ErasedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	certificate.Digest = Digest(certificate)
+
+	if err := s.certificates.WithContext(ctx).SaveErasureCertificate(certificate); err != nil {
+		return nil, err
+	}
+
+	return certificate, nil
+}
@@ -0,0 +1,159 @@
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSource struct {
+	name   string
+	data   interface{}
+	err    error
+	erased *[]string
+}
+
+func (s *fakeSource) Name() string { return s.name }
+
+func (s *fakeSource) Export(userID int32) (interface{}, error) { return s.data, s.err }
+
+func (s *fakeSource) Erase(userID int32, mode string) (int64, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	*s.erased = append(*s.erased, s.name)
+	return 2, nil
+}
+
+type fakeCertificateStore struct {
+	saved []*models.ErasureCertificate
+}
+
+func (s *fakeCertificateStore) WithContext(ctx context.Context) storage.PrivacyStore {
+	return s
+}
+
+func (s *fakeCertificateStore) SaveErasureCertificate(certificate *models.ErasureCertificate) error {
+	certificate.ID = int32(len(s.saved) + 1)
+	s.saved = append(s.saved, certificate)
+	return nil
+}
+
+func (s *fakeCertificateStore) GetErasureCertificate(id int32) (*models.ErasureCertificate, bool) {
+	return nil, false
+}
+
+func (s *fakeCertificateStore) ListErasureCertificates(userID int32) ([]*models.ErasureCertificate, error) {
+	return s.saved, nil
+}
+
+func newTestService(t *testing.T, erased *[]string) (*Service, *fakeCertificateStore) {
+	store := &fakeCertificateStore{}
+	service := NewService(store)
+	require.NoError(t, service.Register(&fakeSource{name: "profile", data: map[string]string{"name": "Jane"}, erased: erased}))
+	require.NoError(t, service.Register(&fakeSource{name: "notifications", data: []string{"hello"}, erased: erased}))
+	return service, store
+}
+
+func TestService_Register(t *testing.T) {
+	var erased []string
+	service, _ := newTestService(t, &erased)
+
+	err := service.Register(&fakeSource{name: "profile", erased: &erased})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"profile", "notifications"}, service.Sources())
+}
+
+func TestService_Export(t *testing.T) {
+	var erased []string
+	service, _ := newTestService(t, &erased)
+
+	export, err := service.Export(7)
+	require.NoError(t, err)
+
+	// JSON holds every section
+	data, filename, contentType, err := export.Encode(FormatJSON)
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", contentType)
+	assert.Contains(t, filename, "user-7-export-")
+
+	var decoded struct {
+		UserID   int32                      `json:"user_id"`
+		Sections map[string]json.RawMessage `json:"sections"`
+	}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, int32(7), decoded.UserID)
+	assert.JSONEq(t, `{"name":"Jane"}`, string(decoded.Sections["profile"]))
+	assert.JSONEq(t, `["hello"]`, string(decoded.Sections["notifications"]))
+
+	// ZIP holds a manifest and one file per section
+	data, filename, contentType, err = export.Encode(FormatZIP)
+	require.NoError(t, err)
+	assert.Equal(t, "application/zip", contentType)
+	assert.Contains(t, filename, ".zip")
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	assert.Equal(t, []string{"manifest.json", "profile.json", "notifications.json"}, names)
+
+	entry, err := archive.File[2].Open()
+	require.NoError(t, err)
+	content, err := io.ReadAll(entry)
+	require.NoError(t, err)
+	assert.JSONEq(t, `["hello"]`, string(content))
+
+	_, _, _, err = export.Encode("xml")
+	assert.Error(t, err)
+}
+
+func TestService_Erase(t *testing.T) {
+	var erased []string
+	service, store := newTestService(t, &erased)
+
+	_, err := service.Erase(context.Background(), &models.EraseUserParams{UserID: 7, Mode: "shred", RequestedBy: "admin"})
+	assert.Error(t, err)
+	assert.Empty(t, erased)
+
+	certificate, err := service.Erase(context.Background(), &models.EraseUserParams{
+		UserID:      7,
+		Mode:        models.ErasureDelete,
+		RequestedBy: "admin@example.com",
+		Reason:      "Art. 17 request",
+	})
+	require.NoError(t, err)
+
+	// Dependent data goes before the profile
+	assert.Equal(t, []string{"notifications", "profile"}, erased)
+	assert.Equal(t, map[string]int64{"profile": 2, "notifications": 2}, certificate.Sources)
+	assert.Len(t, store.saved, 1)
+	assert.True(t, Verify(certificate))
+
+	// Any change to a certified field breaks the digest
+	certificate.Sources["notifications"] = 0
+	assert.False(t, Verify(certificate))
+}
+
+func TestService_Erase_SourceError(t *testing.T) {
+	var erased []string
+	store := &fakeCertificateStore{}
+	service := NewService(store)
+	require.NoError(t, service.Register(&fakeSource{name: "profile", erased: &erased}))
+	require.NoError(t, service.Register(&fakeSource{name: "surveys", err: errors.New("boom"), erased: &erased}))
+
+	_, err := service.Erase(context.Background(), &models.EraseUserParams{UserID: 7, Mode: models.ErasureAnonymize, RequestedBy: "admin"})
+	assert.Error(t, err)
+	assert.Empty(t, store.saved)
+}
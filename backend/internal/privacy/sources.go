@@ -0,0 +1,216 @@
+package privacy
+
+import (
+	"fmt"
+
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/storage"
+)
+
+// Source names of the built-in sources
+const (
+	SourceProfile       = "profile"
+	SourceNotifications = "notifications"
+	SourceInvitations   = "invitations"
+	SourceVerifications = "email_verifications"
+	SourceCredentials   = "credentials"
+	SourceIdentities    = "identities"
+	SourceSessions      = "sessions"
+	SourceAuditLog      = "audit_log"
+)
+
+type profileSource struct {
+	store storage.UserStore
+}
+
+// NewProfileSource covers the user record itself
+func NewProfileSource(store storage.UserStore) Source {
+	return &profileSource{store: store}
+}
+
+func (s *profileSource) Name() string {
+	return SourceProfile
+}
+
+func (s *profileSource) Export(userID int32) (interface{}, error) {
+	user, exists := s.store.GetUserIncludingDeleted(userID)
+	if !exists {
+		return nil, fmt.Errorf("user with ID %d not found", userID)
+	}
+	return user, nil
+}
+
+func (s *profileSource) Erase(userID int32, mode string) (int64, error) {
+	return s.store.EraseUser(userID, mode)
+}
+
+type notificationSource struct {
+	store storage.NotificationStore
+}
+
+// NewNotificationSource covers the notifications addressed to the user.
+// Global notifications are not personal data and stay untouched.
+func NewNotificationSource(store storage.NotificationStore) Source {
+	return &notificationSource{store: store}
+}
+
+func (s *notificationSource) Name() string {
+	return SourceNotifications
+}
+
+func (s *notificationSource) Export(userID int32) (interface{}, error) {
+	notifications, err := s.store.ExportUserNotifications(userID)
+	if err != nil {
+		return nil, err
+	}
+	if notifications == nil {
+		notifications = []*models.Notification{}
+	}
+	return notifications, nil
+}
+
+func (s *notificationSource) Erase(userID int32, mode string) (int64, error) {
+	return s.store.EraseUserNotifications(userID, mode)
+}
+
+type invitationSource struct {
+	store storage.InvitationStore
+}
+
+// NewInvitationSource covers the invitations sent to the user. They are
+// deleted in both erasure modes, as they only matter until accepted.
+func NewInvitationSource(store storage.InvitationStore) Source {
+	return &invitationSource{store: store}
+}
+
+func (s *invitationSource) Name() string {
+	return SourceInvitations
+}
+
+func (s *invitationSource) Export(userID int32) (interface{}, error) {
+	return s.store.ListUserInvitations(userID)
+}
+
+func (s *invitationSource) Erase(userID int32, mode string) (int64, error) {
+	return s.store.DeleteUserInvitations(userID)
+}
+
+type verificationSource struct {
+	store storage.EmailVerificationStore
+}
+
+// NewVerificationSource covers the email verifications of the user, which
+// hold pending addresses. They are deleted in both erasure modes.
+func NewVerificationSource(store storage.EmailVerificationStore) Source {
+	return &verificationSource{store: store}
+}
+
+func (s *verificationSource) Name() string {
+	return SourceVerifications
+}
+
+func (s *verificationSource) Export(userID int32) (interface{}, error) {
+	return s.store.ListUserEmailVerifications(userID)
+}
+
+func (s *verificationSource) Erase(userID int32, mode string) (int64, error) {
+	return s.store.DeleteUserEmailVerifications(userID)
+}
+
+type credentialSource struct {
+	store storage.AuthStore
+}
+
+// NewCredentialSource covers the password and logins of the user. Only the
+// password reset requests are exported, hashes and tokens are not personal
+// data worth handing out. Everything is deleted in both erasure modes.
+func NewCredentialSource(store storage.AuthStore) Source {
+	return &credentialSource{store: store}
+}
+
+func (s *credentialSource) Name() string {
+	return SourceCredentials
+}
+
+func (s *credentialSource) Export(userID int32) (interface{}, error) {
+	return s.store.ListUserPasswordResets(userID)
+}
+
+func (s *credentialSource) Erase(userID int32, mode string) (int64, error) {
+	return s.store.DeleteUserCredentials(userID)
+}
+
+type identitySource struct {
+	store storage.IdentityStore
+}
+
+// NewIdentitySource covers the external accounts linked to the user. They are
+// unlinked in both erasure modes.
+func NewIdentitySource(store storage.IdentityStore) Source {
+	return &identitySource{store: store}
+}
+
+func (s *identitySource) Name() string {
+	return SourceIdentities
+}
+
+func (s *identitySource) Export(userID int32) (interface{}, error) {
+	return s.store.ListUserIdentities(userID)
+}
+
+func (s *identitySource) Erase(userID int32, mode string) (int64, error) {
+	return s.store.DeleteUserIdentities(userID)
+}
+
+type sessionSource struct {
+	store storage.AuthStore
+}
+
+// NewSessionSource covers the logins of the user with their devices and IP
+// addresses. They are deleted in both erasure modes.
+func NewSessionSource(store storage.AuthStore) Source {
+	return &sessionSource{store: store}
+}
+
+func (s *sessionSource) Name() string {
+	return SourceSessions
+}
+
+func (s *sessionSource) Export(userID int32) (interface{}, error) {
+	return s.store.ListSessions(userID, false)
+}
+
+func (s *sessionSource) Erase(userID int32, mode string) (int64, error) {
+	return s.store.DeleteUserSessions(userID)
+}
+
+type auditSource struct {
+	store storage.AuditStore
+}
+
+// NewAuditSource covers the audit events about the user and their records.
+// The events are kept in both erasure modes, only the recorded values are
+// redacted. Register it first, so it runs after the other sources have
+// written their erasure to the log.
+func NewAuditSource(store storage.AuditStore) Source {
+	return &auditSource{store: store}
+}
+
+func (s *auditSource) Name() string {
+	return SourceAuditLog
+}
+
+func (s *auditSource) Export(userID int32) (interface{}, error) {
+	events, err := s.store.ListUserAuditEvents(userID)
+	if err != nil {
+		return nil, err
+	}
+	if events == nil {
+		events = []*models.AuditEvent{}
+	}
+	return events, nil
+}
+
+func (s *auditSource) Erase(userID int32, mode string) (int64, error) {
+	return s.store.RedactUserAuditEvents(userID)
+}
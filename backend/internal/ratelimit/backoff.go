@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Backoff counts failures in a row per key and makes every failure past the
+// free ones double the wait before the next attempt, up to a maximum. A key
+// is forgotten once it hasn't failed for the forget duration.
+type Backoff struct {
+	mu        sync.Mutex
+	free      int
+	base      time.Duration
+	max       time.Duration
+	forget    time.Duration
+	failures  map[string]*failures
+	lastSweep time.Time
+}
+
+type failures struct {
+	count int
+	last  time.Time
+}
+
+// NewBackoff creates a backoff that waits base after the first failure past
+// the free ones
+func NewBackoff(free int, base, max, forget time.Duration) *Backoff {
+	return &Backoff{
+		free:     free,
+		base:     base,
+		max:      max,
+		forget:   forget,
+		failures: make(map[string]*failures),
+	}
+}
+
+// Wait returns how long key has to wait before its next attempt, zero if
+// it may try right away
+func (b *Backoff) Wait(key string, now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.sweep(now)
+
+	f, ok := b.failures[key]
+	if !ok {
+		return 0
+	}
+	return b.remaining(f.count, f.last, now)
+}
+
+// Fail counts a failure for key and returns the number of failures in a row
+func (b *Backoff) Fail(key string, now time.Time) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.sweep(now)
+
+	f, ok := b.failures[key]
+	if !ok || !now.Before(f.last.Add(b.forget)) {
+		f = &failures{}
+		b.failures[key] = f
+	}
+	f.count++
+	f.last = now
+	return f.count
+}
+
+// Reset forgets the failures of key
+func (b *Backoff) Reset(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.failures, key)
+}
+
+// delay returns the wait after count failures in a row
+func (b *Backoff) delay(count int) time.Duration {
+	if count <= b.free {
+		return 0
+	}
+
+	delay := b.base
+	for i := b.free + 1; i < count && delay < b.max; i++ {
+		delay *= 2
+	}
+	if delay > b.max {
+		delay = b.max
+	}
+	return delay
+}
+
+// remaining returns how much of the wait after count failures, the last one
+// at last, is left
+func (b *Backoff) remaining(count int, last time.Time, now time.Time) time.Duration {
+	if !now.Before(last.Add(b.forget)) {
+		return 0
+	}
+
+	remaining := last.Add(b.delay(count)).Sub(now)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// sweep drops forgotten keys once per forget duration
+func (b *Backoff) sweep(now time.Time) {
+	if now.Sub(b.lastSweep) < b.forget {
+		return
+	}
+	b.lastSweep = now
+
+	for key, f := range b.failures {
+		if !now.Before(f.last.Add(b.forget)) {
+			delete(b.failures, key)
+		}
+	}
+}
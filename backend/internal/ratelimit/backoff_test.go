@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	backoff := NewBackoff(2, time.Second, 10*time.Second, time.Hour)
+	now := time.Unix(1700000000, 0)
+
+	// The free failures don't slow down
+	backoff.Fail("a", now)
+	backoff.Fail("a", now)
+	assert.Zero(t, backoff.Wait("a", now))
+
+	// Every further one doubles the wait
+	assert.Equal(t, 3, backoff.Fail("a", now))
+	assert.Equal(t, time.Second, backoff.Wait("a", now))
+	assert.Equal(t, 500*time.Millisecond, backoff.Wait("a", now.Add(500*time.Millisecond)))
+	backoff.Fail("a", now)
+	assert.Equal(t, 2*time.Second, backoff.Wait("a", now))
+	backoff.Fail("a", now)
+	assert.Equal(t, 4*time.Second, backoff.Wait("a", now))
+
+	// Keys are counted separately
+	assert.Zero(t, backoff.Wait("b", now))
+
+	// Up to the maximum
+	for i := 0; i < 10; i++ {
+		backoff.Fail("a", now)
+	}
+	assert.Equal(t, 10*time.Second, backoff.Wait("a", now))
+
+	// Until they are reset or forgotten
+	backoff.Reset("a")
+	assert.Zero(t, backoff.Wait("a", now))
+	backoff.Fail("b", now)
+	backoff.Fail("b", now)
+	backoff.Fail("b", now)
+	assert.Zero(t, backoff.Wait("b", now.Add(time.Hour)))
+	assert.Equal(t, 1, backoff.Fail("b", now.Add(time.Hour)))
+}
+
+func TestBackoff_Delay(t *testing.T) {
+	backoff := NewBackoff(3, time.Second, time.Minute, time.Hour)
+
+	assert.Zero(t, backoff.delay(0))
+	assert.Zero(t, backoff.delay(3))
+	assert.Equal(t, time.Second, backoff.delay(4))
+	assert.Equal(t, 8*time.Second, backoff.delay(7))
+	assert.Equal(t, time.Minute, backoff.delay(100))
+
+	// Old failures are forgotten
+	now := time.Unix(1700000000, 0)
+	assert.Equal(t, 7*time.Second, backoff.remaining(7, now.Add(-time.Second), now))
+	assert.Zero(t, backoff.remaining(7, now.Add(-time.Hour), now))
+}
@@ -0,0 +1,68 @@
+// Package ratelimit counts attempts per key in fixed time windows, and
+// failures per key with growing delays. State is kept in memory, so every
+// server instance enforces its own limits.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter allows up to limit attempts per key and window
+type Limiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	windows   map[string]*window
+	lastSweep time.Time
+}
+
+type window struct {
+	start time.Time
+	count int
+}
+
+// New creates a limiter allowing limit attempts per key within every window
+func New(limit int, windowLength time.Duration) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		window:  windowLength,
+		windows: make(map[string]*window),
+	}
+}
+
+// Allow counts an attempt for key and reports whether it is within the limit.
+// When it isn't, it also returns how long until attempts are allowed again.
+func (l *Limiter) Allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	w, ok := l.windows[key]
+	if !ok || !now.Before(w.start.Add(l.window)) {
+		w = &window{start: now}
+		l.windows[key] = w
+	}
+
+	if w.count >= l.limit {
+		return false, w.start.Add(l.window).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// sweep drops expired windows once per window length, so keys that are never
+// seen again don't pile up
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+
+	for key, w := range l.windows {
+		if !now.Before(w.start.Add(l.window)) {
+			delete(l.windows, key)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	limiter := New(2, time.Minute)
+	now := time.Unix(1700000000, 0)
+
+	allowed, _ := limiter.Allow("a", now)
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow("a", now.Add(time.Second))
+	assert.True(t, allowed)
+
+	allowed, wait := limiter.Allow("a", now.Add(10*time.Second))
+	assert.False(t, allowed)
+	assert.Equal(t, 50*time.Second, wait)
+
+	// Keys are counted separately
+	allowed, _ = limiter.Allow("b", now.Add(10*time.Second))
+	assert.True(t, allowed)
+
+	// A new window starts once the old one is over
+	allowed, _ = limiter.Allow("a", now.Add(time.Minute))
+	assert.True(t, allowed)
+}
+
+func TestLimiter_Sweep(t *testing.T) {
+	limiter := New(1, time.Minute)
+	now := time.Unix(1700000000, 0)
+
+	limiter.Allow("a", now)
+	limiter.Allow("b", now.Add(30*time.Second))
+	limiter.Allow("c", now.Add(2*time.Minute))
+
+	assert.Len(t, limiter.windows, 1)
+}
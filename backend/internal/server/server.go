@@ -1,17 +1,23 @@
 package server
 
 import (
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
+	"backend-grpc-server/internal/auth"
 	"backend-grpc-server/internal/database"
 	"backend-grpc-server/internal/handlers"
+	"backend-grpc-server/internal/mail"
 	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/oidc"
+	"backend-grpc-server/internal/privacy"
 	"backend-grpc-server/internal/storage"
 	pb "backend-grpc-server/pb"
 
@@ -41,20 +47,120 @@ func NewServer() *Server {
 	// Create stores
 	userStore := storage.NewPostgresUserStore(db)
 	notificationStore := storage.NewPostgresNotificationStore(db)
+	privacyStore := storage.NewPostgresPrivacyStore(db)
+	invitationStore := storage.NewPostgresInvitationStore(db)
+	verificationStore := storage.NewPostgresEmailVerificationStore(db)
+	authStore := storage.NewPostgresAuthStore(db)
+	identityStore := storage.NewPostgresIdentityStore(db)
+	apiKeyStore := storage.NewPostgresAPIKeyStore(db)
+	impersonationStore := storage.NewPostgresImpersonationStore(db)
+	auditStore := storage.NewPostgresAuditStore(db)
+
+	// Every store holding personal data takes part in exports and erasures
+	privacyService := privacy.NewService(privacyStore)
+	// Erased last, after the other sources wrote their erasure to the log
+	privacyService.Register(privacy.NewAuditSource(auditStore))
+	privacyService.Register(privacy.NewProfileSource(userStore))
+	privacyService.Register(privacy.NewNotificationSource(notificationStore))
+	privacyService.Register(privacy.NewInvitationSource(invitationStore))
+	privacyService.Register(privacy.NewVerificationSource(verificationStore))
+	privacyService.Register(privacy.NewCredentialSource(authStore))
+	privacyService.Register(privacy.NewIdentitySource(identityStore))
+	privacyService.Register(privacy.NewSessionSource(authStore))
+
+	mailer := mail.NewSenderFromEnv()
 
 	// Create socket handler
 	socketHandler := handlers.NewSocketHandler()
 
+	signer := auth.NewSigner(signingSecretFromEnv("JWT_SECRET"), durationFromEnv("ACCESS_TOKEN_TTL", 15*time.Minute))
+	socketHandler.AuthenticateWith(signer, authStore)
+
 	// Create handlers
 	userHandler := handlers.NewUserHandler(userStore, socketHandler)
 	notificationHandler := handlers.NewNotificationHandler(notificationStore, socketHandler)
+	privacyHandler := handlers.NewPrivacyHandler(privacyService, userStore, privacyStore, socketHandler)
+	invitationHandler := handlers.NewInvitationHandler(
+		invitationStore,
+		userStore,
+		mailer,
+		socketHandler,
+		os.Getenv("BASE_URL"),
+		durationFromEnv("INVITATION_VALIDITY", 7*24*time.Hour),
+	)
+	authHandler := handlers.NewAuthHandler(
+		userStore,
+		authStore,
+		verificationStore,
+		mailer,
+		socketHandler,
+		signer,
+		handlers.AuthConfig{
+			BaseURL:               os.Getenv("BASE_URL"),
+			RefreshTokenTTL:       durationFromEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+			PasswordResetValidity: durationFromEnv("PASSWORD_RESET_VALIDITY", time.Hour),
+			VerificationValidity:  durationFromEnv("EMAIL_VERIFICATION_VALIDITY", 24*time.Hour),
+			ResendInterval:        durationFromEnv("EMAIL_RESEND_INTERVAL", time.Minute),
+			TOTPIssuer:            os.Getenv("TOTP_ISSUER"),
+			OIDCProvision:         os.Getenv("OIDC_AUTO_PROVISION") != "false",
+			OIDCDefaultRole:       envOrDefault("OIDC_DEFAULT_ROLE", "user"),
+			LockoutDuration:       durationFromEnv("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		},
+	)
+	authHandler.NotifyThrough(notificationHandler)
+
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyStore, socketHandler)
+	impersonationHandler := handlers.NewImpersonationHandler(
+		impersonationStore,
+		userStore,
+		signer,
+		durationFromEnv("IMPERSONATION_TTL", 10*time.Minute),
+	)
+	auditHandler := handlers.NewAuditHandler(auditStore)
+
+	// Single sign-on is only offered with a configured identity provider
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		authHandler.EnableOIDC(oidc.NewProvider(oidc.Config{
+			Issuer:       issuer,
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  envOrDefault("OIDC_REDIRECT_URL", strings.TrimRight(os.Getenv("BASE_URL"), "/")+"/auth/oidc/callback"),
+		}), identityStore)
+	}
+
+	// Register custom notification types stored in the database
+	if err := notificationHandler.LoadNotificationTypes(); err != nil {
+		log.Printf("Failed to load notification types: %v", err)
+	}
+
+	// Re-surface snoozed notifications once their snooze expires
+	notificationHandler.StartSnoozeWorker(durationFromEnv("SNOOZE_CHECK_INTERVAL", 30*time.Second))
 
-	// Create gRPC server
-	grpcServer := grpc.NewServer()
+	// Delete notifications past their type's retention period
+	notificationHandler.StartRetentionWorker(durationFromEnv("RETENTION_CHECK_INTERVAL", time.Hour))
+
+	// Hard delete soft deleted users after the grace period
+	userHandler.StartPurgeWorker(
+		durationFromEnv("USER_PURGE_CHECK_INTERVAL", time.Hour),
+		durationFromEnv("USER_PURGE_GRACE_PERIOD", 30*24*time.Hour),
+	)
+
+	// Create gRPC server, every call passes the authenticator first
+	authenticator := handlers.NewAuthenticator(apiKeyStore, impersonationStore, authStore, signer)
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(authenticator.UnaryInterceptor()),
+		grpc.ChainStreamInterceptor(authenticator.StreamInterceptor()),
+	)
 
 	// Register services
 	pb.RegisterUserServiceServer(grpcServer, userHandler)
 	pb.RegisterNotificationServiceServer(grpcServer, notificationHandler)
+	pb.RegisterPrivacyServiceServer(grpcServer, privacyHandler)
+	pb.RegisterInvitationServiceServer(grpcServer, invitationHandler)
+	pb.RegisterAuthServiceServer(grpcServer, authHandler)
+	pb.RegisterAPIKeyServiceServer(grpcServer, apiKeyHandler)
+	pb.RegisterImpersonationServiceServer(grpcServer, impersonationHandler)
+	pb.RegisterAuditServiceServer(grpcServer, auditHandler)
 
 	// Enable reflection for grpcurl
 	reflection.Register(grpcServer)
@@ -108,8 +214,8 @@ func (s *Server) CreateHTTPHandler() http.HandlerFunc {
 		// CORS Headers for gRPC-Web
 		resp.Header().Set("Access-Control-Allow-Origin", "*")
 		resp.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-		resp.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-User-Agent, X-Grpc-Web, grpc-timeout")
-		resp.Header().Set("Access-Control-Expose-Headers", "grpc-status, grpc-message")
+		resp.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Api-Key, X-Request-Id, X-User-Agent, X-Grpc-Web, grpc-timeout")
+		resp.Header().Set("Access-Control-Expose-Headers", "grpc-status, grpc-message, x-impersonated-by, x-request-id")
 
 		if req.Method == "OPTIONS" {
 			return
@@ -192,6 +298,10 @@ func (s *Server) GetSocketHandler() *handlers.SocketHandler {
 func (s *Server) Shutdown() {
 	log.Println("Shutting down server...")
 
+	// Stop background workers
+	s.userHandler.Shutdown()
+	s.notificationHandler.Shutdown()
+
 	// Stop socket handler
 	s.socketHandler.Shutdown()
 
@@ -206,6 +316,44 @@ func (s *Server) Shutdown() {
 	log.Println("Server shutdown complete")
 }
 
+// durationFromEnv reads a duration such as "30s" from the environment
+func durationFromEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Printf("Invalid %s %q, using %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+// envOrDefault reads an environment variable, or returns the default if it is empty
+func envOrDefault(key string, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// signingSecretFromEnv reads a token signing secret. Without one a random
+// secret is used, which signs everyone out when the server restarts.
+func signingSecretFromEnv(key string) []byte {
+	if value := os.Getenv(key); value != "" {
+		return []byte(value)
+	}
+
+	log.Printf("%s is not set, using a random secret", key)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatalf("Failed to generate %s: %v", key, err)
+	}
+	return secret
+}
+
 // Example usage in other parts of your application:
 
 // Background Jobs / Scheduled Tasks
@@ -255,7 +403,8 @@ func (s *Server) OnUserRegistered(user *models.User) {
 	)
 
 	// Notify admins about new user (real-time only)
-	s.notificationHandler.NotifyAll(
+	s.notificationHandler.NotifyRole(
+		"admin",
 		fmt.Sprintf("New user registered: %s", user.Name),
 		"info",
 		false,
@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"backend-grpc-server/internal/database"
+	"backend-grpc-server/internal/models"
+	"github.com/lib/pq"
+)
+
+type PostgresAPIKeyStore struct {
+	db *database.DB
+}
+
+func NewPostgresAPIKeyStore(db *database.DB) APIKeyStore {
+	return &PostgresAPIKeyStore{
+		db: db,
+	}
+}
+
+// WithContext returns the store bound to the audit context of ctx
+func (s *PostgresAPIKeyStore) WithContext(ctx context.Context) APIKeyStore {
+	return &PostgresAPIKeyStore{db: s.db.WithContext(ctx)}
+}
+
+// apiKeyColumns lists the columns read by scanAPIKey, in order
+const apiKeyColumns = `id, name, prefix, scopes, created_by, expires_at, last_used_at, revoked_at, created_at`
+
+// CreateAPIKey stores a key under its lookup prefix and hash
+func (s *PostgresAPIKeyStore) CreateAPIKey(params *models.CreateAPIKeyParams, prefix string, keyHash string) (*models.APIKey, error) {
+	var key *models.APIKey
+	err := s.db.Transaction(func(tx *sql.Tx) error {
+		var err error
+		key, err = scanAPIKey(tx.QueryRow(`
+			INSERT INTO api_keys (name, prefix, key_hash, scopes, created_by, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING `+apiKeyColumns,
+			params.Name, prefix, keyHash, pq.Array(params.Scopes), params.CreatedBy, params.ExpiresAt))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return key, nil
+}
+
+// GetAPIKeyByPrefix returns the key with the prefix together with its hash,
+// including revoked and expired keys
+func (s *PostgresAPIKeyStore) GetAPIKeyByPrefix(prefix string) (*models.APIKey, string, bool) {
+	var keyHash string
+	key, err := scanAPIKey(&appendScanner{
+		row:   s.db.QueryRow(`SELECT `+apiKeyColumns+`, key_hash FROM api_keys WHERE prefix = $1`, prefix),
+		extra: []interface{}{&keyHash},
+	})
+	if err != nil {
+		if err != sql.ErrNoRows {
+			fmt.Printf("Error getting API key: %v\n", err)
+		}
+		return nil, "", false
+	}
+
+	return key, keyHash, true
+}
+
+// TouchAPIKey records that the key was used. The time is kept to the minute,
+// so busy integrations don't write on every request.
+func (s *PostgresAPIKeyStore) TouchAPIKey(id int32) error {
+	_, err := s.db.Exec(`
+		UPDATE api_keys
+		SET last_used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to update API key usage: %w", err)
+	}
+
+	return nil
+}
+
+// ListAPIKeys returns all keys, or only the usable ones, newest first
+func (s *PostgresAPIKeyStore) ListAPIKeys(activeOnly bool) ([]*models.APIKey, error) {
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+	`
+	if activeOnly {
+		query += " WHERE revoked_at IS NULL AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)"
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*models.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey invalidates a key for good
+func (s *PostgresAPIKeyStore) RevokeAPIKey(id int32) error {
+	result, err := s.db.Exec(`
+		UPDATE api_keys
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND revoked_at IS NULL
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("active API key with ID %d not found", id)
+	}
+
+	return nil
+}
+
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	err := row.Scan(
+		&key.ID,
+		&key.Name,
+		&key.Prefix,
+		pq.Array(&key.Scopes),
+		&key.CreatedBy,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
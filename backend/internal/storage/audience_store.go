@@ -0,0 +1,287 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"backend-grpc-server/internal/models"
+	"github.com/lib/pq"
+)
+
+// Audience Operations
+
+func (s *PostgresNotificationStore) CreateAudience(params *models.CreateAudienceParams) (*models.Audience, error) {
+	filter, err := json.Marshal(params.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audience filter: %w", err)
+	}
+
+	query := `
+		INSERT INTO notification_audiences (name, description, filter)
+		VALUES ($1, $2, $3)
+		RETURNING id, name, description, filter, created_at, updated_at
+	`
+
+	var audience *models.Audience
+	err = s.db.Transaction(func(tx *sql.Tx) error {
+		var err error
+		audience, err = scanAudience(tx.QueryRow(query, params.Name, params.Description, filter))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audience: %w", err)
+	}
+
+	return audience, nil
+}
+
+func (s *PostgresNotificationStore) GetAudience(id int32) (*models.Audience, bool) {
+	query := `
+		SELECT id, name, description, filter, created_at, updated_at
+		FROM notification_audiences
+		WHERE id = $1
+	`
+
+	audience, err := scanAudience(s.db.QueryRow(query, id))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			fmt.Printf("Error getting audience: %v\n", err)
+		}
+		return nil, false
+	}
+
+	return audience, true
+}
+
+func (s *PostgresNotificationStore) ListAudiences() ([]*models.Audience, error) {
+	query := `
+		SELECT id, name, description, filter, created_at, updated_at
+		FROM notification_audiences
+		ORDER BY name
+	`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audiences: %w", err)
+	}
+	defer rows.Close()
+
+	var audiences []*models.Audience
+	for rows.Next() {
+		audience, err := scanAudience(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audience: %w", err)
+		}
+		audiences = append(audiences, audience)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audiences: %w", err)
+	}
+
+	return audiences, nil
+}
+
+func (s *PostgresNotificationStore) DeleteAudience(id int32) error {
+	result, err := s.db.Exec(`DELETE FROM notification_audiences WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete audience: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("audience with ID %d not found", id)
+	}
+
+	return nil
+}
+
+// ResolveAudience returns the IDs of all users matching the filter
+func (s *PostgresNotificationStore) ResolveAudience(filter *models.AudienceFilter) ([]int32, error) {
+	whereClause, args := buildAudienceWhere(filter, 0)
+	query := fmt.Sprintf("SELECT id FROM users %s ORDER BY id", whereClause)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve audience: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan user ID: %w", err)
+		}
+		userIDs = append(userIDs, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audience: %w", err)
+	}
+
+	return userIDs, nil
+}
+
+// Fan-out Operations
+
+// CreateNotificationsForUsers stores one notification per recipient in a single
+// statement. Unknown and deleted user IDs are skipped.
+func (s *PostgresNotificationStore) CreateNotificationsForUsers(params *models.CreateNotificationParams, userIDs []int32) ([]*models.Notification, error) {
+	if !params.Persistent {
+		return nil, fmt.Errorf("non-persistent notifications should not be stored in database")
+	}
+
+	query := `
+		INSERT INTO notifications (message, type, user_id, read, persistent, data, language)
+		SELECT $1, $2, u.id, false, $3, $4, $5
+		FROM users u
+		WHERE u.id = ANY($6) AND u.deleted_at IS NULL
+		RETURNING ` + notificationColumns + `
+	`
+
+	data, err := encodeNotificationData(params.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.fanOut(query, params.Message, params.Type, params.Persistent, data, notificationLanguage(params.Language), pq.Array(userIDs))
+}
+
+// CreateNotificationsForAudience stores one notification per user matching the
+// filter. Recipients are selected inside the database, so large audiences never
+// round-trip through the application.
+func (s *PostgresNotificationStore) CreateNotificationsForAudience(params *models.CreateNotificationParams, filter *models.AudienceFilter) ([]*models.Notification, error) {
+	if !params.Persistent {
+		return nil, fmt.Errorf("non-persistent notifications should not be stored in database")
+	}
+
+	data, err := encodeNotificationData(params.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	whereClause, args := buildAudienceWhere(filter, 5)
+	query := fmt.Sprintf(`
+		INSERT INTO notifications (message, type, user_id, read, persistent, data, language)
+		SELECT $1, $2, id, false, $3, $4, $5
+		FROM users
+		%s
+		RETURNING %s
+	`, whereClause, notificationColumns)
+
+	args = append([]interface{}{params.Message, params.Type, params.Persistent, data, notificationLanguage(params.Language)}, args...)
+	return s.fanOut(query, args...)
+}
+
+func (s *PostgresNotificationStore) fanOut(query string, args ...interface{}) ([]*models.Notification, error) {
+	var notifications []*models.Notification
+	err := s.db.Transaction(func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to create notifications: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			notification, err := scanNotification(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan notification: %w", err)
+			}
+			notifications = append(notifications, notification)
+		}
+
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("error iterating notifications: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+// Helper functions
+
+// buildAudienceWhere builds a WHERE clause over the users table. Placeholders
+// start after argOffset so the clause can be appended to other parameters.
+// Soft deleted users never match.
+func buildAudienceWhere(filter *models.AudienceFilter, argOffset int) (string, []interface{}) {
+	conditions := []string{activeUserCondition}
+	if filter == nil {
+		return whereClause(conditions), nil
+	}
+
+	var args []interface{}
+	argCount := argOffset
+
+	addCondition := func(format string, value interface{}) {
+		argCount++
+		conditions = append(conditions, fmt.Sprintf(format, argCount))
+		args = append(args, value)
+	}
+
+	if len(filter.Roles) > 0 {
+		addCondition("role = ANY($%d)", pq.Array(filter.Roles))
+	}
+	if filter.MinAge > 0 {
+		addCondition("age >= $%d", filter.MinAge)
+	}
+	if filter.MaxAge > 0 {
+		addCondition("age <= $%d", filter.MaxAge)
+	}
+	if filter.EmailDomain != "" {
+		addCondition("LOWER(email) LIKE '%%@' || $%d", escapeLike(strings.ToLower(strings.TrimPrefix(filter.EmailDomain, "@"))))
+	}
+	if filter.NameContains != "" {
+		addCondition("name ILIKE '%%' || $%d || '%%'", escapeLike(filter.NameContains))
+	}
+	if filter.CreatedAfter != nil {
+		addCondition("created_at >= $%d", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		addCondition("created_at < $%d", *filter.CreatedBefore)
+	}
+
+	return whereClause(conditions), args
+}
+
+// escapeLike escapes LIKE wildcards so user input is matched literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAudience(row rowScanner) (*models.Audience, error) {
+	audience := &models.Audience{}
+	var filter []byte
+
+	err := row.Scan(
+		&audience.ID,
+		&audience.Name,
+		&audience.Description,
+		&filter,
+		&audience.CreatedAt,
+		&audience.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(filter, &audience.Filter); err != nil {
+		return nil, fmt.Errorf("failed to decode audience filter: %w", err)
+	}
+
+	return audience, nil
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"testing"
+
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createAudienceTestUsers(t *testing.T, userStore UserStore) []*models.User {
+	params := []*models.CreateUserParams{
+		{Name: "Alice Admin", Email: "alice@corp.example.com", Age: 40, Role: "admin"},
+		{Name: "Bob Admin", Email: "bob@other.example.com", Age: 22, Role: "admin"},
+		{Name: "Carol User", Email: "carol@corp.example.com", Age: 31, Role: "user"},
+	}
+
+	var users []*models.User
+	for _, p := range params {
+		user, err := userStore.CreateUser(p)
+		require.NoError(t, err)
+		users = append(users, user)
+	}
+	return users
+}
+
+func TestPostgresNotificationStore_ResolveAudience(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	userStore := NewPostgresUserStore(db)
+	notificationStore := NewPostgresNotificationStore(db)
+	users := createAudienceTestUsers(t, userStore)
+
+	admins, err := notificationStore.ResolveAudience(&models.AudienceFilter{Roles: []string{"admin"}})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int32{users[0].ID, users[1].ID}, admins)
+
+	corp, err := notificationStore.ResolveAudience(&models.AudienceFilter{EmailDomain: "corp.example.com", MinAge: 30})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int32{users[0].ID, users[2].ID}, corp)
+}
+
+func TestPostgresNotificationStore_CreateNotificationsForAudience(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	userStore := NewPostgresUserStore(db)
+	notificationStore := NewPostgresNotificationStore(db)
+	users := createAudienceTestUsers(t, userStore)
+
+	params := &models.CreateNotificationParams{
+		Message:    "Admins only",
+		Type:       "warning",
+		Persistent: true,
+	}
+
+	created, err := notificationStore.CreateNotificationsForAudience(params, &models.AudienceFilter{Roles: []string{"admin"}})
+	require.NoError(t, err)
+	require.Len(t, created, 2)
+
+	var recipients []int32
+	for _, notification := range created {
+		require.NotNil(t, notification.UserID)
+		recipients = append(recipients, *notification.UserID)
+		assert.Equal(t, params.Message, notification.Message)
+		assert.False(t, notification.Read)
+	}
+	assert.ElementsMatch(t, []int32{users[0].ID, users[1].ID}, recipients)
+}
+
+func TestPostgresNotificationStore_CreateNotificationsForUsers(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	userStore := NewPostgresUserStore(db)
+	notificationStore := NewPostgresNotificationStore(db)
+	users := createAudienceTestUsers(t, userStore)
+
+	params := &models.CreateNotificationParams{
+		Message:    "Selected users",
+		Type:       "info",
+		Persistent: true,
+	}
+
+	// Unknown IDs are skipped
+	created, err := notificationStore.CreateNotificationsForUsers(params, []int32{users[0].ID, users[2].ID, 99999})
+	require.NoError(t, err)
+	assert.Len(t, created, 2)
+}
+
+func TestPostgresNotificationStore_Audiences(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	store := NewPostgresNotificationStore(db)
+
+	audience, err := store.CreateAudience(&models.CreateAudienceParams{
+		Name:        "Senior admins",
+		Description: "Admins over 30",
+		Filter:      models.AudienceFilter{Roles: []string{"admin"}, MinAge: 30},
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, audience.ID)
+
+	fetched, exists := store.GetAudience(audience.ID)
+	require.True(t, exists)
+	assert.Equal(t, []string{"admin"}, fetched.Filter.Roles)
+	assert.Equal(t, int32(30), fetched.Filter.MinAge)
+
+	audiences, err := store.ListAudiences()
+	require.NoError(t, err)
+	assert.Len(t, audiences, 1)
+
+	require.NoError(t, store.DeleteAudience(audience.ID))
+	_, exists = store.GetAudience(audience.ID)
+	assert.False(t, exists)
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"backend-grpc-server/internal/database"
+	"backend-grpc-server/internal/models"
+)
+
+type PostgresAuditStore struct {
+	db *database.DB
+}
+
+func NewPostgresAuditStore(db *database.DB) AuditStore {
+	return &PostgresAuditStore{
+		db: db,
+	}
+}
+
+// auditEventColumns lists the columns read by scanAuditEvent, in order
+const auditEventColumns = `id, actor_user_id, actor_api_key_id, impersonated_by, COALESCE(tenant_id, ''), action, target_type, target_id, before, after, COALESCE(request_id, ''), COALESCE(client_ip, ''), source, created_at`
+
+// QueryAuditEvents returns the matching events, newest first
+func (s *PostgresAuditStore) QueryAuditEvents(filter *models.AuditFilter) ([]*models.AuditEvent, error) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.ActorUserID != 0 {
+		add("actor_user_id = $%d", filter.ActorUserID)
+	}
+	if filter.ActorAPIKeyID != 0 {
+		add("actor_api_key_id = $%d", filter.ActorAPIKeyID)
+	}
+	if filter.TenantID != "" {
+		add("tenant_id = $%d", filter.TenantID)
+	}
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if filter.TargetType != "" {
+		add("target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		add("target_id = $%d", filter.TargetID)
+	}
+	if filter.RequestID != "" {
+		add("request_id = $%d", filter.RequestID)
+	}
+	if filter.Since != nil {
+		add("created_at >= $%d", *filter.Since)
+	}
+	if filter.Until != nil {
+		add("created_at < $%d", *filter.Until)
+	}
+	if filter.Cursor != nil {
+		condition, cursorArgs := filter.Cursor.Condition(len(args))
+		conditions = append(conditions, condition)
+		args = append(args, cursorArgs...)
+	}
+
+	query := `
+		SELECT ` + auditEventColumns + `
+		FROM audit_events
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*models.AuditEvent
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit events: %w", err)
+	}
+
+	return events, nil
+}
+
+// userEventCondition selects the events about a user and the rows owned by
+// them. $1 is the user ID.
+const userEventCondition = `
+	((target_type = 'users' AND target_id = $1::TEXT)
+		OR before ->> 'user_id' = $1::TEXT
+		OR after ->> 'user_id' = $1::TEXT)
+`
+
+// ListUserAuditEvents returns the events about a user, oldest first
+func (s *PostgresAuditStore) ListUserAuditEvents(userID int32) ([]*models.AuditEvent, error) {
+	rows, err := s.db.Query(`
+		SELECT `+auditEventColumns+`
+		FROM audit_events
+		WHERE `+userEventCondition+`
+		ORDER BY created_at, id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*models.AuditEvent
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit events: %w", err)
+	}
+
+	return events, nil
+}
+
+// RedactUserAuditEvents blanks the recorded values of the events about a
+// user. The events themselves stay, the log only loses the personal data.
+func (s *PostgresAuditStore) RedactUserAuditEvents(userID int32) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lets the append-only trigger accept the update, for this transaction only
+	if _, err := tx.Exec(`SELECT set_config('audit.redact', 'on', true)`); err != nil {
+		return 0, fmt.Errorf("failed to allow redaction: %w", err)
+	}
+
+	result, err := tx.Exec(`
+		UPDATE audit_events
+		SET before = CASE WHEN before IS NULL THEN NULL ELSE '{"redacted": true}'::JSONB END,
+			after = CASE WHEN after IS NULL THEN NULL ELSE '{"redacted": true}'::JSONB END
+		WHERE `+userEventCondition+`
+	`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to redact audit events: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit redaction: %w", err)
+	}
+
+	return affected, nil
+}
+
+func scanAuditEvent(row rowScanner) (*models.AuditEvent, error) {
+	event := &models.AuditEvent{}
+	var before, after []byte
+	err := row.Scan(
+		&event.ID,
+		&event.ActorUserID,
+		&event.ActorAPIKeyID,
+		&event.ImpersonatedBy,
+		&event.TenantID,
+		&event.Action,
+		&event.TargetType,
+		&event.TargetID,
+		&before,
+		&after,
+		&event.RequestID,
+		&event.ClientIP,
+		&event.Source,
+		&event.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	event.Before = before
+	event.After = after
+	return event, nil
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-grpc-server/internal/database"
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresAuditStore_RedactUserAuditEvents(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	userStore := NewPostgresUserStore(db)
+	store := NewPostgresAuditStore(db)
+
+	user, err := userStore.CreateUser(&models.CreateUserParams{Name: "Customer", Email: "customer@example.com", Age: 30, Role: "user"})
+	require.NoError(t, err)
+	other, err := userStore.CreateUser(&models.CreateUserParams{Name: "Other", Email: "other@example.com", Age: 30, Role: "user"})
+	require.NoError(t, err)
+
+	redacted, err := store.RedactUserAuditEvents(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), redacted)
+
+	events, err := store.ListUserAuditEvents(user.ID)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.JSONEq(t, `{"redacted": true}`, string(events[0].After))
+
+	events, err = store.ListUserAuditEvents(other.ID)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Contains(t, string(events[0].After), "other@example.com")
+}
+
+func TestPostgresAuditStore_AuditedTables(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	userStore := NewPostgresUserStore(db)
+	store := NewPostgresAuditStore(db)
+
+	admin, err := userStore.CreateUser(&models.CreateUserParams{Name: "Admin", Email: "admin@example.com", Age: 40, Role: "admin"})
+	require.NoError(t, err)
+	user, err := userStore.CreateUser(&models.CreateUserParams{Name: "Customer", Email: "customer@example.com", Age: 30, Role: "user"})
+	require.NoError(t, err)
+
+	ctx := database.WithAuditContext(context.Background(), &database.AuditContext{ActorUserID: admin.ID, RequestID: "audited-tables"})
+
+	_, _, err = NewPostgresAuthStore(db).WithContext(ctx).StartSession(&models.StartSessionParams{UserID: user.ID}, strings.Repeat("a", 64), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	_, err = NewPostgresImpersonationStore(db).WithContext(ctx).StartImpersonation(&models.StartImpersonationParams{
+		ActorID:   admin.ID,
+		UserID:    user.ID,
+		Reason:    "Support ticket",
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.NoError(t, NewPostgresPrivacyStore(db).WithContext(ctx).SaveErasureCertificate(&models.ErasureCertificate{
+		UserID:      user.ID,
+		Mode:        models.ErasureAnonymize,
+		RequestedBy: "admin@example.com",
+		Sources:     map[string]int64{"profile": 1},
+		Digest:      strings.Repeat("0", 64),
+		ErasedAt:    time.Now().UTC(),
+	}))
+	_, err = NewPostgresNotificationStore(db).WithContext(ctx).CreateNotification(&models.CreateNotificationParams{Message: "Hello", Type: "info", UserID: &user.ID})
+	require.NoError(t, err)
+
+	events, err := store.QueryAuditEvents(&models.AuditFilter{RequestID: "audited-tables", Limit: 50})
+	require.NoError(t, err)
+	actions := make(map[string]*models.AuditEvent)
+	for _, event := range events {
+		actions[event.Action] = event
+		require.NotNil(t, event.ActorUserID)
+		assert.Equal(t, admin.ID, *event.ActorUserID)
+	}
+	for _, action := range []string{"sessions.insert", "refresh_tokens.insert", "impersonations.insert", "erasure_certificates.insert", "notifications.insert"} {
+		require.Contains(t, actions, action)
+	}
+
+	// Secrets and derived columns stay out of the snapshots
+	assert.NotContains(t, string(actions["refresh_tokens.insert"].After), "token_hash")
+	assert.NotContains(t, string(actions["notifications.insert"].After), "search_vector")
+}
@@ -0,0 +1,805 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"backend-grpc-server/internal/database"
+	"backend-grpc-server/internal/models"
+)
+
+type PostgresAuthStore struct {
+	db *database.DB
+}
+
+func NewPostgresAuthStore(db *database.DB) AuthStore {
+	return &PostgresAuthStore{
+		db: db,
+	}
+}
+
+// WithContext returns the store bound to the audit context of ctx
+func (s *PostgresAuthStore) WithContext(ctx context.Context) AuthStore {
+	return &PostgresAuthStore{db: s.db.WithContext(ctx)}
+}
+
+// refreshTokenColumns lists the columns read by scanRefreshToken, in order.
+// Tokens revoked before sessions existed have none.
+const refreshTokenColumns = `id, user_id, COALESCE(session_id, 0), expires_at, revoked_at, created_at`
+
+// sessionColumns lists the columns read by scanSession, in order
+const sessionColumns = `id, user_id, device, user_agent, client_ip, expires_at, last_seen_at, revoked_at, created_at`
+
+// passwordResetColumns lists the columns read by scanPasswordReset, in order
+const passwordResetColumns = `id, user_id, requested_ip, expires_at, used_at, created_at`
+
+// validRefreshTokenCondition matches refresh tokens that can still be used
+const validRefreshTokenCondition = `revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP`
+
+// GetUserCredentials returns the active user with the email address and its
+// password hash, which is empty if the user never set a password
+func (s *PostgresAuthStore) GetUserCredentials(email string) (*models.User, string, bool) {
+	var passwordHash sql.NullString
+	user, err := scanUser(&appendScanner{
+		row: s.db.QueryRow(`
+			SELECT `+userColumns+`, password_hash
+			FROM users
+			WHERE email = $1 AND `+activeUserCondition,
+			email),
+		extra: []interface{}{&passwordHash},
+	})
+	if err != nil {
+		if err != sql.ErrNoRows {
+			fmt.Printf("Error getting user credentials: %v\n", err)
+		}
+		return nil, "", false
+	}
+
+	return user, passwordHash.String, true
+}
+
+// StartSession stores a new session for a login together with its first
+// refresh token
+func (s *PostgresAuthStore) StartSession(params *models.StartSessionParams, tokenHash string, expiresAt time.Time) (*models.Session, *models.RefreshToken, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	session, err := scanSession(tx.QueryRow(`
+		INSERT INTO sessions (user_id, device, user_agent, client_ip, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+sessionColumns,
+		params.UserID, models.DeviceName(params.UserAgent), params.UserAgent, params.ClientIP, expiresAt))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	token, err := scanRefreshToken(tx.QueryRow(`
+		INSERT INTO refresh_tokens (user_id, session_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+refreshTokenColumns,
+		params.UserID, session.ID, tokenHash, expiresAt))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit session: %w", err)
+	}
+
+	return session, token, nil
+}
+
+// RotateRefreshToken revokes a valid refresh token and stores its
+// replacement in the same session, in one transaction. The session is
+// extended and remembers the client IP. It returns the owner, who must be
+// active.
+func (s *PostgresAuthStore) RotateRefreshToken(tokenHash string, newTokenHash string, expiresAt time.Time, clientIP string) (*models.User, *models.RefreshToken, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID int32
+	var sessionID sql.NullInt32
+	err = tx.QueryRow(`
+		UPDATE refresh_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1 AND `+validRefreshTokenCondition+`
+		RETURNING user_id, session_id
+	`, tokenHash).Scan(&userID, &sessionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, ErrRefreshTokenInvalid
+		}
+		return nil, nil, fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	if !sessionID.Valid {
+		return nil, nil, ErrRefreshTokenInvalid
+	}
+
+	result, err := tx.Exec(`
+		UPDATE sessions
+		SET expires_at = $2, last_seen_at = CURRENT_TIMESTAMP, client_ip = COALESCE(NULLIF($3, ''), client_ip)
+		WHERE id = $1 AND revoked_at IS NULL
+	`, sessionID.Int32, expiresAt, clientIP)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to extend session: %w", err)
+	}
+	if extended, err := result.RowsAffected(); err != nil || extended == 0 {
+		return nil, nil, ErrRefreshTokenInvalid
+	}
+
+	user, err := scanUser(tx.QueryRow(`
+		SELECT `+userColumns+`
+		FROM users
+		WHERE id = $1 AND `+activeUserCondition,
+		userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, ErrRefreshTokenInvalid
+		}
+		return nil, nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	token, err := scanRefreshToken(tx.QueryRow(`
+		INSERT INTO refresh_tokens (user_id, session_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+refreshTokenColumns,
+		userID, sessionID.Int32, newTokenHash, expiresAt))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit refresh token: %w", err)
+	}
+
+	return user, token, nil
+}
+
+// RevokeRefreshToken revokes a valid refresh token and ends its session. It
+// returns the ID of the session.
+func (s *PostgresAuthStore) RevokeRefreshToken(tokenHash string) (int32, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var sessionID sql.NullInt32
+	err = tx.QueryRow(`
+		UPDATE refresh_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1 AND `+validRefreshTokenCondition+`
+		RETURNING session_id
+	`, tokenHash).Scan(&sessionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrRefreshTokenInvalid
+		}
+		return 0, fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+
+	if sessionID.Valid {
+		_, err = tx.Exec(`UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND revoked_at IS NULL`, sessionID.Int32)
+		if err != nil {
+			return 0, fmt.Errorf("failed to end session: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit logout: %w", err)
+	}
+
+	return sessionID.Int32, nil
+}
+
+// RevokeUserRefreshTokens revokes all valid refresh tokens and sessions of
+// the user and returns how many tokens there were
+func (s *PostgresAuthStore) RevokeUserRefreshTokens(userID int32) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE refresh_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND `+validRefreshTokenCondition, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	revoked, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if _, err := tx.Exec(`UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL`, userID); err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit revocation: %w", err)
+	}
+
+	return revoked, nil
+}
+
+// GetSession returns a session, including revoked and expired ones
+func (s *PostgresAuthStore) GetSession(id int32) (*models.Session, bool) {
+	session, err := scanSession(s.db.QueryRow(`SELECT `+sessionColumns+` FROM sessions WHERE id = $1`, id))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			fmt.Printf("Error getting session: %v\n", err)
+		}
+		return nil, false
+	}
+
+	return session, true
+}
+
+// ListSessions returns the sessions of a user, or only the usable ones, most
+// recently used first
+func (s *PostgresAuthStore) ListSessions(userID int32, activeOnly bool) ([]*models.Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE user_id = $1
+	`
+	if activeOnly {
+		query += " AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP"
+	}
+	query += " ORDER BY last_seen_at DESC, id DESC"
+
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []*models.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession ends an active session and revokes its refresh tokens
+func (s *PostgresAuthStore) RevokeSession(id int32) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("active session with ID %d not found", id)
+	}
+
+	if _, err := tx.Exec(`UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE session_id = $1 AND revoked_at IS NULL`, id); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit session revocation: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteUserSessions removes the sessions of the user together with their
+// refresh tokens
+func (s *PostgresAuthStore) DeleteUserSessions(userID int32) (int64, error) {
+	result, err := s.db.Exec(`DELETE FROM sessions WHERE user_id = $1`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// CreatePasswordReset stores a password reset for the token hash. Earlier
+// unused resets of the user stop working, so only the latest link is valid.
+func (s *PostgresAuthStore) CreatePasswordReset(userID int32, tokenHash string, expiresAt time.Time, requestedIP string) (*models.PasswordReset, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM password_resets WHERE user_id = $1 AND used_at IS NULL`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete previous password reset: %w", err)
+	}
+
+	reset, err := scanPasswordReset(tx.QueryRow(`
+		INSERT INTO password_resets (user_id, token_hash, expires_at, requested_ip)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+passwordResetColumns,
+		userID, tokenHash, expiresAt, requestedIP))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create password reset: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit password reset: %w", err)
+	}
+
+	return reset, nil
+}
+
+// ResetPassword uses a password reset token to replace the password hash of
+// its user. All refresh tokens and sessions of the user are revoked in the
+// same transaction, which signs out every existing login.
+func (s *PostgresAuthStore) ResetPassword(tokenHash string, passwordHash string) (*models.User, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID int32
+	err = tx.QueryRow(`
+		UPDATE password_resets
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		RETURNING user_id
+	`, tokenHash).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPasswordResetInvalid
+		}
+		return nil, fmt.Errorf("failed to use password reset: %w", err)
+	}
+
+	user, err := scanUser(tx.QueryRow(`
+		UPDATE users
+		SET password_hash = $2, locked_until = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND `+activeUserCondition+`
+		RETURNING `+userColumns,
+		userID, passwordHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPasswordResetInvalid
+		}
+		return nil, fmt.Errorf("failed to update password: %w", err)
+	}
+
+	// The reset proves the account belongs to the user, so it unlocks it
+	if _, err := tx.Exec(`DELETE FROM failed_logins WHERE user_id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("failed to clear failed logins: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE refresh_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	_, err = tx.Exec(`UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit password reset: %w", err)
+	}
+
+	return user, nil
+}
+
+// GetTwoFactor returns the TOTP state of an active user
+func (s *PostgresAuthStore) GetTwoFactor(userID int32) (*models.TwoFactor, bool) {
+	twoFactor := &models.TwoFactor{}
+	var secret sql.NullString
+	err := s.db.QueryRow(`
+		SELECT id, totp_secret, totp_enabled_at, totp_last_step
+		FROM users
+		WHERE id = $1 AND `+activeUserCondition,
+		userID).Scan(&twoFactor.UserID, &secret, &twoFactor.EnabledAt, &twoFactor.LastStep)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			fmt.Printf("Error getting two-factor state: %v\n", err)
+		}
+		return nil, false
+	}
+
+	twoFactor.Secret = secret.String
+	return twoFactor, true
+}
+
+// StartTOTPEnrollment stores a new secret for a user without two-factor
+// authentication. It replaces an earlier enrollment that wasn't confirmed.
+func (s *PostgresAuthStore) StartTOTPEnrollment(userID int32, secret string) error {
+	result, err := s.db.Exec(`
+		UPDATE users
+		SET totp_secret = $2, totp_last_step = NULL
+		WHERE id = $1 AND totp_enabled_at IS NULL AND `+activeUserCondition,
+		userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to start enrollment: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrTwoFactorState
+	}
+
+	return nil
+}
+
+// EnableTOTP confirms a pending enrollment with the time step of the first
+// valid code and replaces the recovery codes, in one transaction
+func (s *PostgresAuthStore) EnableTOTP(userID int32, step int64, recoveryCodeHashes []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE users
+		SET totp_enabled_at = CURRENT_TIMESTAMP, totp_last_step = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL AND `+activeUserCondition,
+		userID, step)
+	if err != nil {
+		return fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrTwoFactorState
+	}
+
+	if err := replaceRecoveryCodes(tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit two-factor enrollment: %w", err)
+	}
+
+	return nil
+}
+
+// UseTOTPStep records a time step whose code was accepted. It reports false
+// if the step or a later one was used before, which means the code is replayed.
+func (s *PostgresAuthStore) UseTOTPStep(userID int32, step int64) (bool, error) {
+	result, err := s.db.Exec(`
+		UPDATE users
+		SET totp_last_step = $2
+		WHERE id = $1 AND totp_enabled_at IS NOT NULL AND (totp_last_step IS NULL OR totp_last_step < $2)
+	`, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to use TOTP code: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// UseRecoveryCode marks an unused recovery code of the user as used and
+// reports whether there was one
+func (s *PostgresAuthStore) UseRecoveryCode(userID int32, codeHash string) (bool, error) {
+	result, err := s.db.Exec(`
+		UPDATE recovery_codes
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// CountRecoveryCodes returns how many unused recovery codes the user has left
+func (s *PostgresAuthStore) CountRecoveryCodes(userID int32) (int32, error) {
+	var count int32
+	err := s.db.QueryRow(`
+		SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL
+	`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+
+	return count, nil
+}
+
+// ResetTwoFactor turns two-factor authentication off and deletes the
+// recovery codes, so the user can enroll again
+func (s *PostgresAuthStore) ResetTwoFactor(userID int32) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE users
+		SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND `+activeUserCondition,
+		userID)
+	if err != nil {
+		return fmt.Errorf("failed to reset two-factor authentication: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user with ID %d not found", userID)
+	}
+
+	if err := replaceRecoveryCodes(tx, userID, nil); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit two-factor reset: %w", err)
+	}
+
+	return nil
+}
+
+// replaceRecoveryCodes deletes all recovery codes of the user and stores the new hashes
+func replaceRecoveryCodes(tx *sql.Tx, userID int32, codeHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	for _, hash := range codeHashes {
+		_, err := tx.Exec(`INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash)
+		if err != nil {
+			return fmt.Errorf("failed to store recovery code: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ListUserPasswordResets returns the password resets of the user, oldest first
+func (s *PostgresAuthStore) ListUserPasswordResets(userID int32) ([]*models.PasswordReset, error) {
+	rows, err := s.db.Query(`
+		SELECT `+passwordResetColumns+`
+		FROM password_resets
+		WHERE user_id = $1
+		ORDER BY created_at, id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list password resets: %w", err)
+	}
+	defer rows.Close()
+
+	resets := []*models.PasswordReset{}
+	for rows.Next() {
+		reset, err := scanPasswordReset(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan password reset: %w", err)
+		}
+		resets = append(resets, reset)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating password resets: %w", err)
+	}
+
+	return resets, nil
+}
+
+// DeleteUserCredentials removes the password, two-factor secret, recovery
+// codes, refresh tokens and password resets of the user and returns the
+// number of affected records
+func (s *PostgresAuthStore) DeleteUserCredentials(userID int32) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var total int64
+	for _, statement := range []string{
+		`UPDATE users SET password_hash = NULL WHERE id = $1 AND password_hash IS NOT NULL`,
+		`UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL WHERE id = $1 AND totp_secret IS NOT NULL`,
+		`DELETE FROM recovery_codes WHERE user_id = $1`,
+		`DELETE FROM refresh_tokens WHERE user_id = $1`,
+		`DELETE FROM password_resets WHERE user_id = $1`,
+		`DELETE FROM failed_logins WHERE user_id = $1`,
+	} {
+		result, err := tx.Exec(statement, userID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete credentials: %w", err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		total += affected
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit credential deletion: %w", err)
+	}
+
+	return total, nil
+}
+
+// RecordFailedLogin counts a failed login of a user. Failures before since
+// are forgotten. Once lockAfter failures are reached the account is locked
+// until lockedUntil, the count starts over and the second return value is
+// true. An account that is already locked isn't locked again.
+func (s *PostgresAuthStore) RecordFailedLogin(userID int32, since time.Time, lockAfter int32, lockedUntil time.Time) (*models.FailedLogins, bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	failed := &models.FailedLogins{}
+	err = tx.QueryRow(`
+		INSERT INTO failed_logins (user_id, attempts, last_failed_at)
+		VALUES ($1, 1, CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id) DO UPDATE
+		SET attempts = CASE WHEN failed_logins.last_failed_at < $2 THEN 1 ELSE failed_logins.attempts + 1 END,
+			last_failed_at = CURRENT_TIMESTAMP
+		RETURNING user_id, attempts, last_failed_at
+	`, userID, since).Scan(&failed.UserID, &failed.Attempts, &failed.LastFailedAt)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to record failed login: %w", err)
+	}
+
+	locked := false
+	if failed.Attempts >= lockAfter {
+		result, err := tx.Exec(`
+			UPDATE users
+			SET locked_until = $2
+			WHERE id = $1 AND (locked_until IS NULL OR locked_until <= CURRENT_TIMESTAMP)
+		`, userID, lockedUntil)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to lock user: %w", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		locked = rowsAffected > 0
+
+		if _, err := tx.Exec(`DELETE FROM failed_logins WHERE user_id = $1`, userID); err != nil {
+			return nil, false, fmt.Errorf("failed to clear failed logins: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("failed to commit failed login: %w", err)
+	}
+
+	return failed, locked, nil
+}
+
+// ClearFailedLogins starts the count over after a successful login
+func (s *PostgresAuthStore) ClearFailedLogins(userID int32) error {
+	_, err := s.db.Exec(`DELETE FROM failed_logins WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to clear failed logins: %w", err)
+	}
+	return nil
+}
+
+// UnlockUser lifts the lock of an account and forgets its failed logins
+func (s *PostgresAuthStore) UnlockUser(userID int32) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE users SET locked_until = NULL WHERE id = $1 AND locked_until IS NOT NULL`, userID); err != nil {
+		return fmt.Errorf("failed to unlock user: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM failed_logins WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to clear failed logins: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit unlock: %w", err)
+	}
+
+	return nil
+}
+
+func scanRefreshToken(row rowScanner) (*models.RefreshToken, error) {
+	token := &models.RefreshToken{}
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.SessionID,
+		&token.ExpiresAt,
+		&token.RevokedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func scanSession(row rowScanner) (*models.Session, error) {
+	session := &models.Session{}
+	err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.Device,
+		&session.UserAgent,
+		&session.ClientIP,
+		&session.ExpiresAt,
+		&session.LastSeenAt,
+		&session.RevokedAt,
+		&session.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func scanPasswordReset(row rowScanner) (*models.PasswordReset, error) {
+	reset := &models.PasswordReset{}
+	err := row.Scan(
+		&reset.ID,
+		&reset.UserID,
+		&reset.RequestedIP,
+		&reset.ExpiresAt,
+		&reset.UsedAt,
+		&reset.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return reset, nil
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"backend-grpc-server/internal/database"
+	"backend-grpc-server/internal/models"
+)
+
+type PostgresEmailVerificationStore struct {
+	db *database.DB
+}
+
+func NewPostgresEmailVerificationStore(db *database.DB) EmailVerificationStore {
+	return &PostgresEmailVerificationStore{
+		db: db,
+	}
+}
+
+// WithContext returns the store bound to the audit context of ctx
+func (s *PostgresEmailVerificationStore) WithContext(ctx context.Context) EmailVerificationStore {
+	return &PostgresEmailVerificationStore{db: s.db.WithContext(ctx)}
+}
+
+// emailVerificationColumns lists the columns read by scanEmailVerification, in order
+const emailVerificationColumns = `id, user_id, email, purpose, expires_at, consumed_at, created_at`
+
+// CreateEmailVerification stores a verification for the token hash. Earlier
+// unused verifications of the user with the same purpose stop working, so only
+// the latest link is valid.
+func (s *PostgresEmailVerificationStore) CreateEmailVerification(params *models.CreateEmailVerificationParams, tokenHash string) (*models.EmailVerification, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if params.Purpose == models.EmailChange {
+		var taken bool
+		err = tx.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM users WHERE email = $1 AND id <> $2 AND `+activeUserCondition+`)
+		`, params.Email, params.UserID).Scan(&taken)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check email: %w", err)
+		}
+		if taken {
+			return nil, ErrEmailTaken
+		}
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM email_verifications
+		WHERE user_id = $1 AND purpose = $2 AND consumed_at IS NULL
+	`, params.UserID, params.Purpose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete previous verification: %w", err)
+	}
+
+	verification, err := scanEmailVerification(tx.QueryRow(`
+		INSERT INTO email_verifications (user_id, email, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+emailVerificationColumns,
+		params.UserID, params.Email, params.Purpose, tokenHash, params.ExpiresAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create verification: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit verification: %w", err)
+	}
+
+	return verification, nil
+}
+
+// LastEmailVerificationSent returns when the latest verification of the user
+// was created, or nil if none was
+func (s *PostgresEmailVerificationStore) LastEmailVerificationSent(userID int32) (*time.Time, error) {
+	var sentAt *time.Time
+	err := s.db.QueryRow(`
+		SELECT MAX(created_at) FROM email_verifications WHERE user_id = $1
+	`, userID).Scan(&sentAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last verification: %w", err)
+	}
+
+	return sentAt, nil
+}
+
+// ConsumeEmailVerification marks the verification as used and applies it in
+// one transaction. A verify token marks the address as verified if it is
+// still the user's address, a change token replaces the address.
+func (s *PostgresEmailVerificationStore) ConsumeEmailVerification(tokenHash string) (*models.User, *models.EmailVerification, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	verification, err := scanEmailVerification(tx.QueryRow(`
+		UPDATE email_verifications
+		SET consumed_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1 AND consumed_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		RETURNING `+emailVerificationColumns,
+		tokenHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, ErrVerificationInvalid
+		}
+		return nil, nil, fmt.Errorf("failed to consume verification: %w", err)
+	}
+
+	query := `
+		UPDATE users
+		SET email_verified = TRUE, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND email = $2 AND ` + activeUserCondition + `
+		RETURNING ` + userColumns
+	if verification.Purpose == models.EmailChange {
+		query = `
+			UPDATE users
+			SET email = $2, email_verified = TRUE, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND ` + activeUserCondition + `
+			RETURNING ` + userColumns
+	}
+
+	user, err := scanUser(tx.QueryRow(query, verification.UserID, verification.Email))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, ErrVerificationInvalid
+		}
+		if isUniqueViolation(err) {
+			return nil, nil, ErrEmailTaken
+		}
+		return nil, nil, fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit verification: %w", err)
+	}
+
+	return user, verification, nil
+}
+
+// ListUserEmailVerifications returns the verifications of the user, oldest first
+func (s *PostgresEmailVerificationStore) ListUserEmailVerifications(userID int32) ([]*models.EmailVerification, error) {
+	rows, err := s.db.Query(`
+		SELECT `+emailVerificationColumns+`
+		FROM email_verifications
+		WHERE user_id = $1
+		ORDER BY created_at, id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list email verifications: %w", err)
+	}
+	defer rows.Close()
+
+	verifications := []*models.EmailVerification{}
+	for rows.Next() {
+		verification, err := scanEmailVerification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan email verification: %w", err)
+		}
+		verifications = append(verifications, verification)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating email verifications: %w", err)
+	}
+
+	return verifications, nil
+}
+
+// DeleteUserEmailVerifications deletes all verifications of the user
+func (s *PostgresEmailVerificationStore) DeleteUserEmailVerifications(userID int32) (int64, error) {
+	result, err := s.db.Exec(`DELETE FROM email_verifications WHERE user_id = $1`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete email verifications: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+func scanEmailVerification(row rowScanner) (*models.EmailVerification, error) {
+	verification := &models.EmailVerification{}
+	err := row.Scan(
+		&verification.ID,
+		&verification.UserID,
+		&verification.Email,
+		&verification.Purpose,
+		&verification.ExpiresAt,
+		&verification.ConsumedAt,
+		&verification.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return verification, nil
+}
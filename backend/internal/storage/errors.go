@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"backend-grpc-server/internal/database"
+	"github.com/lib/pq"
+)
+
+// ErrVersionConflict is returned when an update or delete carries an expected
+// version that no longer matches the stored row
+var ErrVersionConflict = errors.New("version conflict")
+
+// VersionConflictError describes a failed optimistic concurrency check.
+// It matches ErrVersionConflict with errors.Is.
+type VersionConflictError struct {
+	Entity          string
+	ID              int32
+	ExpectedVersion int32
+	CurrentVersion  int32
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("%s with ID %d was modified: expected version %d, current version %d",
+		e.Entity, e.ID, e.ExpectedVersion, e.CurrentVersion)
+}
+
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+// versionError is called when a versioned write matched no row. It reports a
+// conflict if the row still exists, otherwise a not found error. A non-empty
+// scope further restricts which rows count as existing.
+func versionError(db *database.DB, table string, scope string, entity string, id int32, expectedVersion int32) error {
+	query := fmt.Sprintf("SELECT version FROM %s WHERE id = $1", table)
+	if scope != "" {
+		query += " AND " + scope
+	}
+
+	var current int32
+	err := db.QueryRow(query, id).Scan(&current)
+	if err == sql.ErrNoRows || (err == nil && expectedVersion == 0) {
+		return fmt.Errorf("%s with ID %d not found", entity, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check %s version: %w", entity, err)
+	}
+
+	return &VersionConflictError{
+		Entity:          entity,
+		ID:              id,
+		ExpectedVersion: expectedVersion,
+		CurrentVersion:  current,
+	}
+}
+
+// ErrInvitationInvalid is returned for invitation tokens that are unknown,
+// expired, revoked or already used
+var ErrInvitationInvalid = errors.New("invitation is invalid or has expired")
+
+// ErrEmailTaken is returned when an active user already has the email address
+var ErrEmailTaken = errors.New("email address is already in use")
+
+// isUniqueViolation reports whether err is a Postgres unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// ErrVerificationInvalid is returned for email verification tokens that are
+// unknown, expired, already used or no longer match the user's address
+var ErrVerificationInvalid = errors.New("verification link is invalid or has expired")
+
+// ErrRefreshTokenInvalid is returned for refresh tokens that are unknown,
+// expired or revoked
+var ErrRefreshTokenInvalid = errors.New("refresh token is invalid or has expired")
+
+// ErrPasswordResetInvalid is returned for password reset tokens that are
+// unknown, expired or already used
+var ErrPasswordResetInvalid = errors.New("password reset link is invalid or has expired")
+
+// ErrTwoFactorState is returned when two-factor enrollment is started while
+// already enabled, or confirmed without being started
+var ErrTwoFactorState = errors.New("two-factor authentication is not in the expected state")
+
+// ErrLoginStateInvalid is returned for OIDC login states that are unknown,
+// expired or already used
+var ErrLoginStateInvalid = errors.New("login has expired, please start again")
+
+// ErrNoMatchingUser is returned when an external login matches no user and
+// provisioning is off
+var ErrNoMatchingUser = errors.New("no user matches the external account")
+
+// ErrImpersonationEnded is returned for impersonations that were ended or
+// have expired
+var ErrImpersonationEnded = errors.New("impersonation has ended")
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"backend-grpc-server/internal/database"
+	"backend-grpc-server/internal/models"
+)
+
+type PostgresIdentityStore struct {
+	db *database.DB
+}
+
+func NewPostgresIdentityStore(db *database.DB) IdentityStore {
+	return &PostgresIdentityStore{
+		db: db,
+	}
+}
+
+// WithContext returns the store bound to the audit context of ctx
+func (s *PostgresIdentityStore) WithContext(ctx context.Context) IdentityStore {
+	return &PostgresIdentityStore{db: s.db.WithContext(ctx)}
+}
+
+// userIdentityColumns lists the columns read by scanUserIdentity, in order
+const userIdentityColumns = `id, user_id, issuer, subject, email, created_at, last_login_at`
+
+// SaveLoginState stores a started OIDC login under the hash of its state.
+// Expired logins are cleaned up on the way.
+func (s *PostgresIdentityStore) SaveLoginState(stateHash string, codeVerifier string, nonce string, expiresAt time.Time) error {
+	if _, err := s.db.Exec(`DELETE FROM oidc_login_states WHERE expires_at <= CURRENT_TIMESTAMP`); err != nil {
+		return fmt.Errorf("failed to delete expired login states: %w", err)
+	}
+
+	_, err := s.db.Exec(`
+		INSERT INTO oidc_login_states (state_hash, code_verifier, nonce, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`, stateHash, codeVerifier, nonce, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to save login state: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeLoginState removes a login state and returns its PKCE verifier and nonce
+func (s *PostgresIdentityStore) ConsumeLoginState(stateHash string) (string, string, error) {
+	var codeVerifier, nonce string
+	err := s.db.QueryRow(`
+		DELETE FROM oidc_login_states
+		WHERE state_hash = $1 AND expires_at > CURRENT_TIMESTAMP
+		RETURNING code_verifier, nonce
+	`, stateHash).Scan(&codeVerifier, &nonce)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", "", ErrLoginStateInvalid
+		}
+		return "", "", fmt.Errorf("failed to consume login state: %w", err)
+	}
+
+	return codeVerifier, nonce, nil
+}
+
+// ResolveExternalLogin finds the user for an external account, in one
+// transaction. A linked identity wins, then an active user with the same
+// verified email address is linked, then a user is provisioned if allowed.
+// It reports whether the user was created.
+func (s *PostgresIdentityStore) ResolveExternalLogin(params *models.ExternalLoginParams) (*models.User, bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID int32
+	err = tx.QueryRow(`
+		UPDATE user_identities
+		SET last_login_at = CURRENT_TIMESTAMP, email = $3
+		WHERE issuer = $1 AND subject = $2
+		RETURNING user_id
+	`, params.Issuer, params.Subject, params.Email).Scan(&userID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, false, fmt.Errorf("failed to find identity: %w", err)
+	}
+
+	var user *models.User
+	created := false
+
+	switch {
+	case err == nil:
+		user, err = scanUser(tx.QueryRow(`SELECT `+userColumns+` FROM users WHERE id = $1 AND `+activeUserCondition, userID))
+		if err == sql.ErrNoRows {
+			return nil, false, ErrNoMatchingUser
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get user: %w", err)
+		}
+
+	default:
+		if params.Email != "" && params.EmailVerified {
+			user, err = scanUser(tx.QueryRow(`SELECT `+userColumns+` FROM users WHERE email = $1 AND `+activeUserCondition, params.Email))
+			if err != nil && err != sql.ErrNoRows {
+				return nil, false, fmt.Errorf("failed to get user: %w", err)
+			}
+		}
+
+		if user == nil {
+			if !params.Provision || params.Email == "" {
+				return nil, false, ErrNoMatchingUser
+			}
+
+			user, err = scanUser(tx.QueryRow(`
+				INSERT INTO users (name, email, age, role, email_verified)
+				VALUES ($1, $2, 0, $3, $4)
+				RETURNING `+userColumns,
+				params.Name, params.Email, params.Role, params.EmailVerified))
+			if err != nil {
+				if isUniqueViolation(err) {
+					return nil, false, ErrEmailTaken
+				}
+				return nil, false, fmt.Errorf("failed to create user: %w", err)
+			}
+			created = true
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO user_identities (user_id, issuer, subject, email)
+			VALUES ($1, $2, $3, $4)
+		`, user.ID, params.Issuer, params.Subject, params.Email)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to link identity: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("failed to commit external login: %w", err)
+	}
+
+	return user, created, nil
+}
+
+// ListUserIdentities returns the external accounts linked to the user
+func (s *PostgresIdentityStore) ListUserIdentities(userID int32) ([]*models.UserIdentity, error) {
+	rows, err := s.db.Query(`
+		SELECT `+userIdentityColumns+`
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY created_at, id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user identities: %w", err)
+	}
+	defer rows.Close()
+
+	identities := []*models.UserIdentity{}
+	for rows.Next() {
+		identity, err := scanUserIdentity(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user identity: %w", err)
+		}
+		identities = append(identities, identity)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user identities: %w", err)
+	}
+
+	return identities, nil
+}
+
+// DeleteUserIdentities unlinks all external accounts of the user
+func (s *PostgresIdentityStore) DeleteUserIdentities(userID int32) (int64, error) {
+	result, err := s.db.Exec(`DELETE FROM user_identities WHERE user_id = $1`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete user identities: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+func scanUserIdentity(row rowScanner) (*models.UserIdentity, error) {
+	identity := &models.UserIdentity{}
+	err := row.Scan(
+		&identity.ID,
+		&identity.UserID,
+		&identity.Issuer,
+		&identity.Subject,
+		&identity.Email,
+		&identity.CreatedAt,
+		&identity.LastLoginAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
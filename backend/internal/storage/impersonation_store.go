@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"backend-grpc-server/internal/database"
+	"backend-grpc-server/internal/models"
+)
+
+type PostgresImpersonationStore struct {
+	db *database.DB
+}
+
+func NewPostgresImpersonationStore(db *database.DB) ImpersonationStore {
+	return &PostgresImpersonationStore{
+		db: db,
+	}
+}
+
+// WithContext returns the store bound to the audit context of ctx
+func (s *PostgresImpersonationStore) WithContext(ctx context.Context) ImpersonationStore {
+	return &PostgresImpersonationStore{db: s.db.WithContext(ctx)}
+}
+
+// impersonationColumns lists the columns read by scanImpersonation, in order
+const impersonationColumns = `id, actor_id, user_id, reason, client_ip, started_at, expires_at, ended_at,
+	(SELECT COUNT(*) FROM impersonation_log WHERE impersonation_id = impersonations.id) AS request_count`
+
+// openImpersonationCondition matches impersonations whose tokens still work
+const openImpersonationCondition = `ended_at IS NULL AND expires_at > CURRENT_TIMESTAMP`
+
+// StartImpersonation records an admin starting to act as a user
+func (s *PostgresImpersonationStore) StartImpersonation(params *models.StartImpersonationParams) (*models.Impersonation, error) {
+	var impersonation *models.Impersonation
+	err := s.db.Transaction(func(tx *sql.Tx) error {
+		var err error
+		impersonation, err = scanImpersonation(tx.QueryRow(`
+			INSERT INTO impersonations (actor_id, user_id, reason, client_ip, expires_at)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING `+impersonationColumns,
+			params.ActorID, params.UserID, params.Reason, params.ClientIP, params.ExpiresAt))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start impersonation: %w", err)
+	}
+
+	return impersonation, nil
+}
+
+// LogImpersonatedRequest records a request made under an impersonation. It
+// returns ErrImpersonationEnded once the impersonation was ended or expired.
+func (s *PostgresImpersonationStore) LogImpersonatedRequest(id int32, method string) error {
+	result, err := s.db.Exec(`
+		INSERT INTO impersonation_log (impersonation_id, method)
+		SELECT id, $2
+		FROM impersonations
+		WHERE id = $1 AND `+openImpersonationCondition, id, method)
+	if err != nil {
+		return fmt.Errorf("failed to log impersonated request: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrImpersonationEnded
+	}
+
+	return nil
+}
+
+// EndImpersonation invalidates the tokens of an impersonation before they expire
+func (s *PostgresImpersonationStore) EndImpersonation(id int32) error {
+	result, err := s.db.Exec(`
+		UPDATE impersonations
+		SET ended_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND `+openImpersonationCondition, id)
+	if err != nil {
+		return fmt.Errorf("failed to end impersonation: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrImpersonationEnded
+	}
+
+	return nil
+}
+
+func (s *PostgresImpersonationStore) GetImpersonation(id int32) (*models.Impersonation, bool) {
+	query := `
+		SELECT ` + impersonationColumns + `
+		FROM impersonations
+		WHERE id = $1
+	`
+
+	impersonation, err := scanImpersonation(s.db.QueryRow(query, id))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			fmt.Printf("Error getting impersonation: %v\n", err)
+		}
+		return nil, false
+	}
+
+	return impersonation, true
+}
+
+// ListImpersonations returns the impersonation log, newest first
+func (s *PostgresImpersonationStore) ListImpersonations(filter *models.ImpersonationFilter) ([]*models.Impersonation, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.ActorID != 0 {
+		args = append(args, filter.ActorID)
+		conditions = append(conditions, fmt.Sprintf("actor_id = $%d", len(args)))
+	}
+	if filter.UserID != 0 {
+		args = append(args, filter.UserID)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+
+	query := `
+		SELECT ` + impersonationColumns + `
+		FROM impersonations
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY started_at DESC, id DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list impersonations: %w", err)
+	}
+	defer rows.Close()
+
+	var impersonations []*models.Impersonation
+	for rows.Next() {
+		impersonation, err := scanImpersonation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan impersonation: %w", err)
+		}
+		impersonations = append(impersonations, impersonation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating impersonations: %w", err)
+	}
+
+	return impersonations, nil
+}
+
+func scanImpersonation(row rowScanner) (*models.Impersonation, error) {
+	impersonation := &models.Impersonation{}
+	err := row.Scan(
+		&impersonation.ID,
+		&impersonation.ActorID,
+		&impersonation.UserID,
+		&impersonation.Reason,
+		&impersonation.ClientIP,
+		&impersonation.StartedAt,
+		&impersonation.ExpiresAt,
+		&impersonation.EndedAt,
+		&impersonation.RequestCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return impersonation, nil
+}
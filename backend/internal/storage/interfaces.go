@@ -1,46 +1,230 @@
 package storage
 
-import "backend-grpc-server/internal/models"
+import (
+	"context"
+	"time"
+
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/notificationtypes"
+)
 
 // UserStore interface - bleibt gleich
 type UserStore interface {
+	// WithContext binds the writes to the audit context of a request
+	WithContext(ctx context.Context) UserStore
+
 	GetUser(id int32) (*models.User, bool)
+	GetUserIncludingDeleted(id int32) (*models.User, bool)
 	CreateUser(params *models.CreateUserParams) (*models.User, error)
 	UpdateUser(params *models.UpdateUserParams) (*models.User, error)
-	DeleteUser(id int32) error
+	DeleteUser(id int32, expectedVersion int32) error
 	ListUsers(params *models.ListUsersParams) ([]*models.User, int32, error)
+	RestoreUser(id int32) (*models.User, error)
+	PurgeDeletedUsers(cutoff time.Time) ([]int32, error)
+	EraseUser(id int32, mode string) (int64, error)
+
+	// Bulk import
+	GetUserByEmail(email string) (*models.User, bool)
+	UpsertUserByEmail(params *models.CreateUserParams) (*models.User, bool, error)
 }
 
 // Enhanced NotificationStore interface mit vollständigen CRUD Operations
 type NotificationStore interface {
+	// WithContext binds the writes to the audit context of a request
+	WithContext(ctx context.Context) NotificationStore
+
 	// Basic CRUD
 	GetNotification(id int32) (*models.Notification, bool)
 	CreateNotification(params *models.CreateNotificationParams) (*models.Notification, error)
 	UpdateNotification(params *models.UpdateNotificationParams) (*models.Notification, error)
-	DeleteNotification(id int32) error
+	DeleteNotification(id int32, expectedVersion int32) error
 
 	// Advanced listing with filters
 	ListNotifications(params *models.ListNotificationsParams) ([]*models.Notification, int32, error)
 	ListNotificationsByUser(userID int32, params *models.ListNotificationsParams) ([]*models.Notification, int32, error)
+	SearchNotifications(params *models.SearchNotificationsParams) ([]*models.NotificationSearchResult, int32, error)
 
 	// Mark as read/unread functionality
 	MarkAsRead(id int32, userID int32) error
 	MarkAsUnread(id int32, userID int32) error
 	MarkAllAsRead(userID int32) error
 
+	// Archive and snooze
+	ArchiveNotification(id int32, userID int32) error
+	UnarchiveNotification(id int32, userID int32) error
+	SnoozeNotification(params *models.SnoozeNotificationParams) error
+	UnsnoozeNotification(id int32, userID int32) error
+	ReleaseExpiredSnoozes() ([]*models.Notification, error)
+
 	// Batch operations
 	DeleteReadNotifications(userID int32) error
 	DeleteOldNotifications(olderThanDays int) error
+	BatchSetRead(params *models.BatchNotificationParams, read bool) ([]models.BatchItemResult, error)
+	BatchDelete(params *models.BatchNotificationParams) ([]models.BatchItemResult, error)
+	CreateNotificationsBatch(params []*models.CreateNotificationParams) ([]*models.Notification, error)
 
 	// Statistics
 	GetUnreadCount(userID int32) (int32, error)
+	GetUnreadCounts(userIDs []int32) (map[int32]int32, error)
 	GetNotificationStats(userID int32) (*NotificationStats, error)
+
+	// Audience targeting and fan-out
+	CreateAudience(params *models.CreateAudienceParams) (*models.Audience, error)
+	GetAudience(id int32) (*models.Audience, bool)
+	ListAudiences() ([]*models.Audience, error)
+	DeleteAudience(id int32) error
+	ResolveAudience(filter *models.AudienceFilter) ([]int32, error)
+	CreateNotificationsForUsers(params *models.CreateNotificationParams, userIDs []int32) ([]*models.Notification, error)
+	CreateNotificationsForAudience(params *models.CreateNotificationParams, filter *models.AudienceFilter) ([]*models.Notification, error)
+
+	// Notification type registry and retention
+	ListNotificationTypes() ([]notificationtypes.Type, error)
+	SaveNotificationType(t notificationtypes.Type) error
+	PurgeNotificationsBefore(notificationType string, cutoff time.Time) (int64, error)
+
+	// Personal data export and erasure
+	ExportUserNotifications(userID int32) ([]*models.Notification, error)
+	EraseUserNotifications(userID int32, mode string) (int64, error)
+}
+
+// InvitationStore manages user invitations. Tokens are only passed as hashes.
+type InvitationStore interface {
+	// WithContext binds the writes to the audit context of a request
+	WithContext(ctx context.Context) InvitationStore
+
+	CreateInvitation(params *models.CreateInvitationParams, tokenHash string) (*models.Invitation, error)
+	GetInvitation(id int32) (*models.Invitation, bool)
+	ListInvitations(pendingOnly bool) ([]*models.Invitation, error)
+	RevokeInvitation(id int32) error
+	AcceptInvitation(tokenHash string, params *models.AcceptInvitationParams, passwordHash string) (*models.User, error)
+
+	// Personal data export and erasure
+	ListUserInvitations(userID int32) ([]*models.Invitation, error)
+	DeleteUserInvitations(userID int32) (int64, error)
+}
+
+// EmailVerificationStore manages email verification and change tokens.
+// Tokens are only passed as hashes.
+type EmailVerificationStore interface {
+	// WithContext binds the writes to the audit context of a request
+	WithContext(ctx context.Context) EmailVerificationStore
+
+	CreateEmailVerification(params *models.CreateEmailVerificationParams, tokenHash string) (*models.EmailVerification, error)
+	LastEmailVerificationSent(userID int32) (*time.Time, error)
+	ConsumeEmailVerification(tokenHash string) (*models.User, *models.EmailVerification, error)
+
+	// Personal data export and erasure
+	ListUserEmailVerifications(userID int32) ([]*models.EmailVerification, error)
+	DeleteUserEmailVerifications(userID int32) (int64, error)
+}
+
+// AuthStore manages credentials: password hashes, refresh tokens, password
+// resets and two-factor secrets. Tokens and codes are only passed as hashes.
+type AuthStore interface {
+	// WithContext binds the writes to the audit context of a request
+	WithContext(ctx context.Context) AuthStore
+
+	GetUserCredentials(email string) (*models.User, string, bool)
+
+	// Sessions and their refresh tokens
+	StartSession(params *models.StartSessionParams, tokenHash string, expiresAt time.Time) (*models.Session, *models.RefreshToken, error)
+	RotateRefreshToken(tokenHash string, newTokenHash string, expiresAt time.Time, clientIP string) (*models.User, *models.RefreshToken, error)
+	RevokeRefreshToken(tokenHash string) (int32, error)
+	RevokeUserRefreshTokens(userID int32) (int64, error)
+	GetSession(id int32) (*models.Session, bool)
+	ListSessions(userID int32, activeOnly bool) ([]*models.Session, error)
+	RevokeSession(id int32) error
+
+	// Failed logins and lockouts
+	RecordFailedLogin(userID int32, since time.Time, lockAfter int32, lockedUntil time.Time) (*models.FailedLogins, bool, error)
+	ClearFailedLogins(userID int32) error
+	UnlockUser(userID int32) error
+
+	// Password resets
+	CreatePasswordReset(userID int32, tokenHash string, expiresAt time.Time, requestedIP string) (*models.PasswordReset, error)
+	ResetPassword(tokenHash string, passwordHash string) (*models.User, error)
+
+	// Two-factor authentication
+	GetTwoFactor(userID int32) (*models.TwoFactor, bool)
+	StartTOTPEnrollment(userID int32, secret string) error
+	EnableTOTP(userID int32, step int64, recoveryCodeHashes []string) error
+	UseTOTPStep(userID int32, step int64) (bool, error)
+	UseRecoveryCode(userID int32, codeHash string) (bool, error)
+	CountRecoveryCodes(userID int32) (int32, error)
+	ResetTwoFactor(userID int32) error
+
+	// Personal data export and erasure
+	ListUserPasswordResets(userID int32) ([]*models.PasswordReset, error)
+	DeleteUserCredentials(userID int32) (int64, error)
+	DeleteUserSessions(userID int32) (int64, error)
+}
+
+// IdentityStore links users to external identity providers and keeps the
+// logins in progress. States are only passed as hashes.
+type IdentityStore interface {
+	// WithContext binds the writes to the audit context of a request
+	WithContext(ctx context.Context) IdentityStore
+
+	SaveLoginState(stateHash string, codeVerifier string, nonce string, expiresAt time.Time) error
+	ConsumeLoginState(stateHash string) (string, string, error)
+	ResolveExternalLogin(params *models.ExternalLoginParams) (*models.User, bool, error)
+
+	// Personal data export and erasure
+	ListUserIdentities(userID int32) ([]*models.UserIdentity, error)
+	DeleteUserIdentities(userID int32) (int64, error)
+}
+
+// APIKeyStore manages the API keys of integrations. Keys are only passed as
+// their lookup prefix and hash.
+type APIKeyStore interface {
+	// WithContext binds the writes to the audit context of a request
+	WithContext(ctx context.Context) APIKeyStore
+
+	CreateAPIKey(params *models.CreateAPIKeyParams, prefix string, keyHash string) (*models.APIKey, error)
+	GetAPIKeyByPrefix(prefix string) (*models.APIKey, string, bool)
+	TouchAPIKey(id int32) error
+	ListAPIKeys(activeOnly bool) ([]*models.APIKey, error)
+	RevokeAPIKey(id int32) error
+}
+
+// ImpersonationStore keeps the log of admins acting as users
+type ImpersonationStore interface {
+	// WithContext binds the writes to the audit context of a request
+	WithContext(ctx context.Context) ImpersonationStore
+
+	StartImpersonation(params *models.StartImpersonationParams) (*models.Impersonation, error)
+	LogImpersonatedRequest(id int32, method string) error
+	EndImpersonation(id int32) error
+	GetImpersonation(id int32) (*models.Impersonation, bool)
+	ListImpersonations(filter *models.ImpersonationFilter) ([]*models.Impersonation, error)
+}
+
+// AuditStore reads the audit log. Events are written by the database itself.
+type AuditStore interface {
+	QueryAuditEvents(filter *models.AuditFilter) ([]*models.AuditEvent, error)
+
+	// Personal data export and erasure
+	ListUserAuditEvents(userID int32) ([]*models.AuditEvent, error)
+	RedactUserAuditEvents(userID int32) (int64, error)
+}
+
+// PrivacyStore keeps the erasure certificates
+type PrivacyStore interface {
+	// WithContext binds the writes to the audit context of a request
+	WithContext(ctx context.Context) PrivacyStore
+
+	SaveErasureCertificate(certificate *models.ErasureCertificate) error
+	GetErasureCertificate(id int32) (*models.ErasureCertificate, bool)
+	ListErasureCertificates(userID int32) ([]*models.ErasureCertificate, error)
 }
 
 // NotificationStats provides statistics about notifications
 type NotificationStats struct {
-	Total    int32 `json:"total"`
-	Unread   int32 `json:"unread"`
-	Read     int32 `json:"read"`
+	Total    int32            `json:"total"`
+	Unread   int32            `json:"unread"`
+	Read     int32            `json:"read"`
+	Inbox    int32            `json:"inbox"` // neither archived nor currently snoozed
+	Archived int32            `json:"archived"`
+	Snoozed  int32            `json:"snoozed"` // snoozed until a future time
 	ByType   map[string]int32 `json:"by_type"`
 }
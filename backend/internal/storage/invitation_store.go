@@ -0,0 +1,264 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"backend-grpc-server/internal/database"
+	"backend-grpc-server/internal/models"
+)
+
+type PostgresInvitationStore struct {
+	db *database.DB
+}
+
+func NewPostgresInvitationStore(db *database.DB) InvitationStore {
+	return &PostgresInvitationStore{
+		db: db,
+	}
+}
+
+// WithContext returns the store bound to the audit context of ctx
+func (s *PostgresInvitationStore) WithContext(ctx context.Context) InvitationStore {
+	return &PostgresInvitationStore{db: s.db.WithContext(ctx)}
+}
+
+// invitationColumns lists the columns read by scanInvitation, in order
+const invitationColumns = `id, email, role, invited_by, expires_at, accepted_at, revoked_at, user_id, created_at`
+
+// openInvitationCondition matches invitations that were neither accepted nor revoked
+const openInvitationCondition = `accepted_at IS NULL AND revoked_at IS NULL`
+
+// CreateInvitation stores an invitation for the token hash. An open
+// invitation for the same address is revoked, so inviting again resends.
+func (s *PostgresInvitationStore) CreateInvitation(params *models.CreateInvitationParams, tokenHash string) (*models.Invitation, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var taken bool
+	err = tx.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(email) = LOWER($1) AND `+activeUserCondition+`)
+	`, params.Email).Scan(&taken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check email: %w", err)
+	}
+	if taken {
+		return nil, ErrEmailTaken
+	}
+
+	_, err = tx.Exec(`
+		UPDATE invitations
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE LOWER(email) = LOWER($1) AND `+openInvitationCondition, params.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke previous invitation: %w", err)
+	}
+
+	invitation, err := scanInvitation(tx.QueryRow(`
+		INSERT INTO invitations (email, role, token_hash, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+invitationColumns,
+		params.Email, params.Role, tokenHash, params.InvitedBy, params.ExpiresAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit invitation: %w", err)
+	}
+
+	return invitation, nil
+}
+
+func (s *PostgresInvitationStore) GetInvitation(id int32) (*models.Invitation, bool) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM invitations
+		WHERE id = $1
+	`
+
+	invitation, err := scanInvitation(s.db.QueryRow(query, id))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			fmt.Printf("Error getting invitation: %v\n", err)
+		}
+		return nil, false
+	}
+
+	return invitation, true
+}
+
+// ListInvitations returns all invitations, or only the ones that can still be
+// accepted, newest first
+func (s *PostgresInvitationStore) ListInvitations(pendingOnly bool) ([]*models.Invitation, error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM invitations
+	`
+	if pendingOnly {
+		query += " WHERE " + openInvitationCondition + " AND expires_at > CURRENT_TIMESTAMP"
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+	defer rows.Close()
+
+	var invitations []*models.Invitation
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invitation: %w", err)
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating invitations: %w", err)
+	}
+
+	return invitations, nil
+}
+
+// RevokeInvitation invalidates an open invitation
+func (s *PostgresInvitationStore) RevokeInvitation(id int32) error {
+	result, err := s.db.Exec(`
+		UPDATE invitations
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND `+openInvitationCondition, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke invitation: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("open invitation with ID %d not found", id)
+	}
+
+	return nil
+}
+
+// AcceptInvitation creates the invited user with the given profile and
+// password hash and marks the invitation as used, all in one transaction
+func (s *PostgresInvitationStore) AcceptInvitation(tokenHash string, params *models.AcceptInvitationParams, passwordHash string) (*models.User, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var invitationID int32
+	var email, role string
+	err = tx.QueryRow(`
+		SELECT id, email, role
+		FROM invitations
+		WHERE token_hash = $1 AND `+openInvitationCondition+` AND expires_at > CURRENT_TIMESTAMP
+		FOR UPDATE
+	`, tokenHash).Scan(&invitationID, &email, &role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvitationInvalid
+		}
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+
+	user, err := scanUser(tx.QueryRow(`
+		INSERT INTO users (name, email, age, role, password_hash, email_verified)
+		VALUES ($1, $2, $3, $4, $5, TRUE)
+		RETURNING `+userColumns,
+		params.Name, email, params.Age, role, passwordHash))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrEmailTaken
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE invitations
+		SET accepted_at = CURRENT_TIMESTAMP, user_id = $2
+		WHERE id = $1
+	`, invitationID, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to accept invitation: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit invitation: %w", err)
+	}
+
+	return user, nil
+}
+
+// ListUserInvitations returns the invitations the user accepted
+func (s *PostgresInvitationStore) ListUserInvitations(userID int32) ([]*models.Invitation, error) {
+	rows, err := s.db.Query(`
+		SELECT `+invitationColumns+`
+		FROM invitations
+		WHERE user_id = $1
+		ORDER BY created_at, id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user invitations: %w", err)
+	}
+	defer rows.Close()
+
+	invitations := []*models.Invitation{}
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invitation: %w", err)
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating invitations: %w", err)
+	}
+
+	return invitations, nil
+}
+
+// DeleteUserInvitations deletes the invitations the user accepted together
+// with any other invitation sent to the same address
+func (s *PostgresInvitationStore) DeleteUserInvitations(userID int32) (int64, error) {
+	result, err := s.db.Exec(`
+		DELETE FROM invitations
+		WHERE user_id = $1
+		OR LOWER(email) IN (SELECT LOWER(email) FROM invitations WHERE user_id = $1)
+	`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete user invitations: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+func scanInvitation(row rowScanner) (*models.Invitation, error) {
+	invitation := &models.Invitation{}
+	err := row.Scan(
+		&invitation.ID,
+		&invitation.Email,
+		&invitation.Role,
+		&invitation.InvitedBy,
+		&invitation.ExpiresAt,
+		&invitation.AcceptedAt,
+		&invitation.RevokedAt,
+		&invitation.UserID,
+		&invitation.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"backend-grpc-server/internal/models"
+	"github.com/lib/pq"
+)
+
+// Bulk Operations
+
+// BatchSetRead marks the selected notifications of a user as read or unread
+func (s *PostgresNotificationStore) BatchSetRead(params *models.BatchNotificationParams, read bool) ([]models.BatchItemResult, error) {
+	statement := `UPDATE notifications SET read = $1, updated_at = CURRENT_TIMESTAMP`
+	return s.batchForUser(statement, []interface{}{read}, params, "update")
+}
+
+// BatchDelete deletes the selected notifications of a user
+func (s *PostgresNotificationStore) BatchDelete(params *models.BatchNotificationParams) ([]models.BatchItemResult, error) {
+	return s.batchForUser(`DELETE FROM notifications`, nil, params, "delete")
+}
+
+// CreateNotificationsBatch stores all notifications in one transaction. If any
+// insert fails nothing is stored.
+func (s *PostgresNotificationStore) CreateNotificationsBatch(params []*models.CreateNotificationParams) ([]*models.Notification, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO notifications (message, type, user_id, read, persistent, data, language)
+		VALUES ($1, $2, $3, false, true, $4, $5)
+		RETURNING ` + notificationColumns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	notifications := make([]*models.Notification, 0, len(params))
+	for i, p := range params {
+		data, err := encodeNotificationData(p.Data)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+
+		notification, err := scanNotification(stmt.QueryRow(p.Message, p.Type, p.UserID, data, notificationLanguage(p.Language)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create notification %d: %w", i, err)
+		}
+		notifications = append(notifications, notification)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit notifications: %w", err)
+	}
+
+	return notifications, nil
+}
+
+// batchForUser runs statement against the notifications selected by params,
+// always scoped to the user, and reports which notifications were affected
+func (s *PostgresNotificationStore) batchForUser(statement string, args []interface{}, params *models.BatchNotificationParams, action string) ([]models.BatchItemResult, error) {
+	args = append(args, params.UserID)
+	conditions := []string{fmt.Sprintf("user_id = $%d", len(args))}
+
+	if len(params.IDs) > 0 {
+		args = append(args, pq.Array(params.IDs))
+		conditions = append(conditions, fmt.Sprintf("id = ANY($%d)", len(args)))
+	} else {
+		filterConditions, filterArgs := buildNotificationFilter(params.Filter, len(args))
+		conditions = append(conditions, filterConditions...)
+		args = append(args, filterArgs...)
+	}
+
+	query := fmt.Sprintf("%s WHERE %s RETURNING id", statement, strings.Join(conditions, " AND "))
+
+	affected := make(map[int32]bool)
+	err := s.db.Transaction(func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to %s notifications: %w", action, err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id int32
+			if err := rows.Scan(&id); err != nil {
+				return fmt.Errorf("failed to scan notification ID: %w", err)
+			}
+			affected[id] = true
+		}
+
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("error iterating notifications: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return batchResults(params.IDs, affected, params.UserID), nil
+}
+
+// batchResults reports one result per requested ID, or one per affected
+// notification when the selection was made by filter
+func batchResults(ids []int32, affected map[int32]bool, userID int32) []models.BatchItemResult {
+	if len(ids) == 0 {
+		results := make([]models.BatchItemResult, 0, len(affected))
+		for id := range affected {
+			results = append(results, models.BatchItemResult{ID: id, Success: true})
+		}
+		sort.Slice(results, func(i, j int) bool { return results[i].ID < results[j].ID })
+		for i := range results {
+			results[i].Index = int32(i)
+		}
+		return results
+	}
+
+	results := make([]models.BatchItemResult, len(ids))
+	for i, id := range ids {
+		results[i] = models.BatchItemResult{Index: int32(i), ID: id, Success: affected[id]}
+		if !affected[id] {
+			results[i].Error = fmt.Sprintf("notification with ID %d not found for user %d", id, userID)
+		}
+	}
+	return results
+}
+
+// buildNotificationFilter builds conditions over the notifications table.
+// Placeholders start after argOffset.
+func buildNotificationFilter(filter *models.NotificationFilter, argOffset int) ([]string, []interface{}) {
+	if filter == nil {
+		return nil, nil
+	}
+
+	var conditions []string
+	var args []interface{}
+	argCount := argOffset
+
+	addCondition := func(format string, value interface{}) {
+		argCount++
+		conditions = append(conditions, fmt.Sprintf(format, argCount))
+		args = append(args, value)
+	}
+
+	if filter.Read != nil {
+		addCondition("read = $%d", *filter.Read)
+	}
+	if len(filter.Types) > 0 {
+		addCondition("type = ANY($%d)", pq.Array(filter.Types))
+	}
+	if filter.CreatedAfter != nil {
+		addCondition("created_at >= $%d", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		addCondition("created_at <= $%d", *filter.CreatedBefore)
+	}
+	if filter.Archived != nil {
+		if *filter.Archived {
+			conditions = append(conditions, "archived_at IS NOT NULL")
+		} else {
+			conditions = append(conditions, "archived_at IS NULL")
+		}
+	}
+
+	return conditions, args
+}
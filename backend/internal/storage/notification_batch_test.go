@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"testing"
+
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresNotificationStore_BatchOperations(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	userStore := NewPostgresUserStore(db)
+	user, err := userStore.CreateUser(&models.CreateUserParams{
+		Name:  "Batch User",
+		Email: "batch@example.com",
+		Age:   28,
+		Role:  "user",
+	})
+	require.NoError(t, err)
+
+	store := NewPostgresNotificationStore(db)
+
+	var params []*models.CreateNotificationParams
+	for _, notificationType := range []string{"info", "info", "warning", "error"} {
+		params = append(params, &models.CreateNotificationParams{
+			Message:    "Batch " + notificationType,
+			Type:       notificationType,
+			UserID:     &user.ID,
+			Persistent: true,
+		})
+	}
+
+	created, err := store.CreateNotificationsBatch(params)
+	require.NoError(t, err)
+	require.Len(t, created, 4)
+
+	// Mark by ID list, reporting unknown IDs
+	results, err := store.BatchSetRead(&models.BatchNotificationParams{
+		UserID: user.ID,
+		IDs:    []int32{created[0].ID, created[1].ID, 999999},
+	}, true)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.True(t, results[0].Success)
+	assert.True(t, results[1].Success)
+	assert.False(t, results[2].Success)
+	assert.NotEmpty(t, results[2].Error)
+
+	unread, err := store.GetUnreadCount(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), unread)
+
+	// Delete by filter
+	read := true
+	results, err = store.BatchDelete(&models.BatchNotificationParams{
+		UserID: user.ID,
+		Filter: &models.NotificationFilter{Read: &read, Types: []string{"info"}},
+	})
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	// Other users' notifications are never touched
+	results, err = store.BatchDelete(&models.BatchNotificationParams{
+		UserID: user.ID + 1,
+		IDs:    []int32{created[2].ID},
+	})
+	require.NoError(t, err)
+	assert.False(t, results[0].Success)
+
+	_, total, err := store.ListNotifications(&models.ListNotificationsParams{Limit: 10, UserID: &user.ID})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), total)
+}
+
+func TestPostgresNotificationStore_CreateNotificationsBatch_Rollback(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	store := NewPostgresNotificationStore(db)
+
+	missingUser := int32(999999)
+	_, err := store.CreateNotificationsBatch([]*models.CreateNotificationParams{
+		{Message: "Stored", Type: "info", Persistent: true},
+		{Message: "Unknown user", Type: "info", UserID: &missingUser, Persistent: true},
+	})
+	assert.Error(t, err)
+
+	_, total, err := store.ListNotifications(&models.ListNotificationsParams{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), total)
+}
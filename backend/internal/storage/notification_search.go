@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"backend-grpc-server/internal/models"
+	"github.com/lib/pq"
+)
+
+// ts_headline marks matches with control characters instead of tags, so the
+// message can be HTML-escaped before the <mark> tags go in. Messages are
+// stripped of them first.
+const (
+	searchMatchStart      = "\x01"
+	searchMatchStop       = "\x02"
+	searchHeadlineOptions = `StartSel="` + searchMatchStart + `", StopSel="` + searchMatchStop + `", MaxWords=35, MinWords=15, MaxFragments=2`
+)
+
+// searchSnippetReplacer turns the marked matches of an escaped snippet into tags
+var searchSnippetReplacer = strings.NewReplacer(searchMatchStart, "<mark>", searchMatchStop, "</mark>")
+
+// SearchNotifications runs a full-text search with filters. The query is parsed
+// with websearch syntax ("quoted phrases", -exclusions, OR) using the requested
+// language configuration.
+func (s *PostgresNotificationStore) SearchNotifications(params *models.SearchNotificationsParams) ([]*models.NotificationSearchResult, int32, error) {
+	var conditions []string
+	var args []interface{}
+	argCount := 0
+
+	addCondition := func(format string, value interface{}) {
+		argCount++
+		conditions = append(conditions, fmt.Sprintf(format, argCount))
+		args = append(args, value)
+	}
+
+	// Language and query always take the first two placeholders
+	args = append(args, notificationLanguage(params.Language), params.Query)
+	argCount = 2
+	hasQuery := strings.TrimSpace(params.Query) != ""
+
+	if hasQuery {
+		conditions = append(conditions, "search_vector @@ q.query")
+	}
+	if params.UserID != nil {
+		addCondition("user_id = $%d", *params.UserID)
+	}
+	if len(params.Types) > 0 {
+		addCondition("type = ANY($%d)", pq.Array(params.Types))
+	}
+	if params.CreatedAfter != nil {
+		addCondition("created_at >= $%d", *params.CreatedAfter)
+	}
+	if params.CreatedBefore != nil {
+		addCondition("created_at < $%d", *params.CreatedBefore)
+	}
+	if params.Persistent != nil {
+		addCondition("persistent = $%d", *params.Persistent)
+	}
+	if len(params.DataKeys) > 0 {
+		addCondition("data ?& $%d", pq.Array(params.DataKeys))
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	fromClause := "FROM notifications, (SELECT websearch_to_tsquery(notification_search_config($1), $2) AS query) q"
+
+	// Get total count
+	countQuery := fmt.Sprintf("SELECT COUNT(*) %s %s", fromClause, whereClause)
+	var total int32
+	if err := s.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count search results: %w", err)
+	}
+
+	// Default pagination
+	limit := params.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	offset := params.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	args = append(args, limit, offset)
+	limitArg := fmt.Sprintf("$%d", argCount+1)
+	offsetArg := fmt.Sprintf("$%d", argCount+2)
+
+	snippetExpr := "message"
+	rankExpr := "0::real"
+	if hasQuery {
+		snippetExpr = fmt.Sprintf("ts_headline(notification_search_config(language), translate(message, chr(1) || chr(2), ''), q.query, '%s')", searchHeadlineOptions)
+		rankExpr = "ts_rank(search_vector, q.query)"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s, %s AS snippet, %s AS rank
+		%s
+		%s
+		ORDER BY %s
+		LIMIT %s OFFSET %s
+	`, notificationColumns, snippetExpr, rankExpr, fromClause, whereClause, searchOrderBy(params, hasQuery), limitArg, offsetArg)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*models.NotificationSearchResult
+	for rows.Next() {
+		result := &models.NotificationSearchResult{}
+		notification, err := scanNotification(&searchRowScanner{rows: rows, snippet: &result.Snippet, rank: &result.Rank})
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		result.Notification = notification
+		result.Snippet = highlightSnippet(result.Snippet)
+		results = append(results, result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating search results: %w", err)
+	}
+
+	return results, total, nil
+}
+
+// highlightSnippet HTML-escapes a snippet and wraps its matches in <mark>
+// tags, so clients can render it as HTML
+func highlightSnippet(snippet string) string {
+	return searchSnippetReplacer.Replace(html.EscapeString(snippet))
+}
+
+// searchOrderBy maps the validated sort options to an ORDER BY clause
+func searchOrderBy(params *models.SearchNotificationsParams, hasQuery bool) string {
+	direction := "DESC"
+	if params.SortOrder == "asc" {
+		direction = "ASC"
+	}
+
+	sortBy := params.SortBy
+	if sortBy == "" {
+		sortBy = models.SortByCreatedAt
+		if hasQuery {
+			sortBy = models.SortByRelevance
+		}
+	}
+
+	if sortBy == models.SortByRelevance && hasQuery {
+		return fmt.Sprintf("rank %s, created_at DESC, id DESC", direction)
+	}
+	return fmt.Sprintf("created_at %s, id %s", direction, direction)
+}
+
+// searchRowScanner appends the snippet and rank columns to a notification scan
+type searchRowScanner struct {
+	rows    rowScanner
+	snippet *string
+	rank    *float32
+}
+
+func (s *searchRowScanner) Scan(dest ...interface{}) error {
+	return s.rows.Scan(append(dest, s.snippet, s.rank)...)
+}
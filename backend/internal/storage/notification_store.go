@@ -1,14 +1,19 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
-	"strings"
 
 	"backend-grpc-server/internal/database"
 	"backend-grpc-server/internal/models"
+	"github.com/lib/pq"
 )
 
+// notificationColumns lists the columns read by scanNotification, in order
+const notificationColumns = `id, message, type, user_id, read, persistent, data, language, archived_at, snoozed_until, version, created_at, updated_at`
+
 type PostgresNotificationStore struct {
 	db *database.DB
 }
@@ -19,26 +24,21 @@ func NewPostgresNotificationStore(db *database.DB) NotificationStore {
 	}
 }
 
+// WithContext returns the store bound to the audit context of ctx
+func (s *PostgresNotificationStore) WithContext(ctx context.Context) NotificationStore {
+	return &PostgresNotificationStore{db: s.db.WithContext(ctx)}
+}
+
 // Basic CRUD Operations
 
 func (s *PostgresNotificationStore) GetNotification(id int32) (*models.Notification, bool) {
 	query := `
-		SELECT id, message, type, user_id, read, persistent, created_at, updated_at
+		SELECT ` + notificationColumns + `
 		FROM notifications
 		WHERE id = $1
 	`
 
-	notification := &models.Notification{}
-	err := s.db.QueryRow(query, id).Scan(
-		&notification.ID,
-		&notification.Message,
-		&notification.Type,
-		&notification.UserID,
-		&notification.Read,
-		&notification.Persistent,
-		&notification.CreatedAt,
-		&notification.UpdatedAt,
-	)
+	notification, err := scanNotification(s.db.QueryRow(query, id))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -58,22 +58,22 @@ func (s *PostgresNotificationStore) CreateNotification(params *models.CreateNoti
 	}
 
 	query := `
-		INSERT INTO notifications (message, type, user_id, read, persistent)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, message, type, user_id, read, persistent, created_at, updated_at
+		INSERT INTO notifications (message, type, user_id, read, persistent, data, language)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + notificationColumns + `
 	`
 
-	notification := &models.Notification{}
-	err := s.db.QueryRow(query, params.Message, params.Type, params.UserID, false, params.Persistent).Scan(
-		&notification.ID,
-		&notification.Message,
-		&notification.Type,
-		&notification.UserID,
-		&notification.Read,
-		&notification.Persistent,
-		&notification.CreatedAt,
-		&notification.UpdatedAt,
-	)
+	data, err := encodeNotificationData(params.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	var notification *models.Notification
+	err = s.db.Transaction(func(tx *sql.Tx) error {
+		var err error
+		notification, err = scanNotification(tx.QueryRow(query, params.Message, params.Type, params.UserID, false, params.Persistent, data, notificationLanguage(params.Language)))
+		return err
+	})
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create notification: %w", err)
@@ -82,29 +82,35 @@ func (s *PostgresNotificationStore) CreateNotification(params *models.CreateNoti
 	return notification, nil
 }
 
+// UpdateNotification updates the fields listed in params.Fields, or all fields if none
+// are listed. A non-zero ExpectedVersion must match the stored version.
 func (s *PostgresNotificationStore) UpdateNotification(params *models.UpdateNotificationParams) (*models.Notification, error) {
+	setClause, args, err := buildUpdateSet(params.Fields, map[string]interface{}{
+		"message": params.Message,
+		"type":    params.Type,
+		"read":    params.Read,
+	}, 2)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
 		UPDATE notifications
-		SET message = $2, type = $3, read = $4, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $1
-		RETURNING id, message, type, user_id, read, persistent, created_at, updated_at
+		SET ` + setClause + `, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND ($2 = 0 OR version = $2)
+		RETURNING ` + notificationColumns + `
 	`
 
-	notification := &models.Notification{}
-	err := s.db.QueryRow(query, params.ID, params.Message, params.Type, params.Read).Scan(
-		&notification.ID,
-		&notification.Message,
-		&notification.Type,
-		&notification.UserID,
-		&notification.Read,
-		&notification.Persistent,
-		&notification.CreatedAt,
-		&notification.UpdatedAt,
-	)
+	var notification *models.Notification
+	err = s.db.Transaction(func(tx *sql.Tx) error {
+		var err error
+		notification, err = scanNotification(tx.QueryRow(query, append([]interface{}{params.ID, params.ExpectedVersion}, args...)...))
+		return err
+	})
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("notification with ID %d not found", params.ID)
+			return nil, versionError(s.db, "notifications", "", "notification", params.ID, params.ExpectedVersion)
 		}
 		return nil, fmt.Errorf("failed to update notification: %w", err)
 	}
@@ -112,10 +118,11 @@ func (s *PostgresNotificationStore) UpdateNotification(params *models.UpdateNoti
 	return notification, nil
 }
 
-func (s *PostgresNotificationStore) DeleteNotification(id int32) error {
-	query := `DELETE FROM notifications WHERE id = $1`
+// DeleteNotification deletes a notification. A non-zero expectedVersion must match the stored version.
+func (s *PostgresNotificationStore) DeleteNotification(id int32, expectedVersion int32) error {
+	query := `DELETE FROM notifications WHERE id = $1 AND ($2 = 0 OR version = $2)`
 
-	result, err := s.db.Exec(query, id)
+	result, err := s.db.Exec(query, id, expectedVersion)
 	if err != nil {
 		return fmt.Errorf("failed to delete notification: %w", err)
 	}
@@ -126,7 +133,7 @@ func (s *PostgresNotificationStore) DeleteNotification(id int32) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("notification with ID %d not found", id)
+		return versionError(s.db, "notifications", "", "notification", id, expectedVersion)
 	}
 
 	return nil
@@ -152,17 +159,25 @@ func (s *PostgresNotificationStore) ListNotifications(params *models.ListNotific
 		args = append(args, *params.Read)
 	}
 
-	whereClause := ""
-	if len(conditions) > 0 {
-		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	// The inbox hides archived and currently snoozed notifications
+	if params.Archived {
+		conditions = append(conditions, "archived_at IS NOT NULL")
+	} else {
+		conditions = append(conditions, "archived_at IS NULL")
+	}
+
+	if !params.IncludeSnoozed {
+		conditions = append(conditions, "(snoozed_until IS NULL OR snoozed_until <= CURRENT_TIMESTAMP)")
 	}
 
 	// Get total count
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM notifications %s", whereClause)
-	var total int32
-	err := s.db.QueryRow(countQuery, args...).Scan(&total)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count notifications: %w", err)
+	total := int32(-1)
+	if !params.SkipTotal {
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM notifications %s", whereClause(conditions))
+		err := s.db.QueryRow(countQuery, args...).Scan(&total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to count notifications: %w", err)
+		}
 	}
 
 	// Default pagination
@@ -175,6 +190,15 @@ func (s *PostgresNotificationStore) ListNotifications(params *models.ListNotific
 		offset = 0
 	}
 
+	// Page tokens continue after the cursor instead of skipping rows
+	if params.Cursor != nil {
+		condition, cursorArgs := params.Cursor.Condition(argCount)
+		conditions = append(conditions, condition)
+		args = append(args, cursorArgs...)
+		argCount += len(cursorArgs)
+		offset = 0
+	}
+
 	// Add pagination to args
 	argCount++
 	limitArg := fmt.Sprintf("$%d", argCount)
@@ -184,12 +208,12 @@ func (s *PostgresNotificationStore) ListNotifications(params *models.ListNotific
 
 	// Get notifications with pagination
 	query := fmt.Sprintf(`
-		SELECT id, message, type, user_id, read, persistent, created_at, updated_at
+		SELECT %s
 		FROM notifications
 		%s
-		ORDER BY created_at DESC
+		ORDER BY created_at DESC, id DESC
 		LIMIT %s OFFSET %s
-	`, whereClause, limitArg, offsetArg)
+	`, notificationColumns, whereClause(conditions), limitArg, offsetArg)
 
 	rows, err := s.db.Query(query, args...)
 	if err != nil {
@@ -199,17 +223,7 @@ func (s *PostgresNotificationStore) ListNotifications(params *models.ListNotific
 
 	var notifications []*models.Notification
 	for rows.Next() {
-		notification := &models.Notification{}
-		err := rows.Scan(
-			&notification.ID,
-			&notification.Message,
-			&notification.Type,
-			&notification.UserID,
-			&notification.Read,
-			&notification.Persistent,
-			&notification.CreatedAt,
-			&notification.UpdatedAt,
-		)
+		notification, err := scanNotification(rows)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan notification: %w", err)
 		}
@@ -294,6 +308,102 @@ func (s *PostgresNotificationStore) MarkAllAsRead(userID int32) error {
 	return nil
 }
 
+// Archive/Snooze Operations
+
+func (s *PostgresNotificationStore) ArchiveNotification(id int32, userID int32) error {
+	query := `
+		UPDATE notifications
+		SET archived_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2
+	`
+
+	return s.execForUser(query, "archive", id, userID)
+}
+
+func (s *PostgresNotificationStore) UnarchiveNotification(id int32, userID int32) error {
+	query := `
+		UPDATE notifications
+		SET archived_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2
+	`
+
+	return s.execForUser(query, "unarchive", id, userID)
+}
+
+func (s *PostgresNotificationStore) SnoozeNotification(params *models.SnoozeNotificationParams) error {
+	query := `
+		UPDATE notifications
+		SET snoozed_until = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2
+	`
+
+	return s.execForUser(query, "snooze", params.ID, params.UserID, params.Until)
+}
+
+func (s *PostgresNotificationStore) UnsnoozeNotification(id int32, userID int32) error {
+	query := `
+		UPDATE notifications
+		SET snoozed_until = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2
+	`
+
+	return s.execForUser(query, "unsnooze", id, userID)
+}
+
+// ReleaseExpiredSnoozes clears expired snoozes and marks those notifications
+// unread again so they re-surface in the inbox. The released notifications are
+// returned so callers can re-emit them.
+func (s *PostgresNotificationStore) ReleaseExpiredSnoozes() ([]*models.Notification, error) {
+	query := `
+		UPDATE notifications
+		SET snoozed_until = NULL, read = false, updated_at = CURRENT_TIMESTAMP
+		WHERE snoozed_until IS NOT NULL AND snoozed_until <= CURRENT_TIMESTAMP
+		RETURNING ` + notificationColumns
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to release snoozed notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var notifications []*models.Notification
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, notification)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notifications: %w", err)
+	}
+
+	return notifications, nil
+}
+
+// execForUser runs an update scoped to a user's notification and reports
+// a not found error when nothing matched
+func (s *PostgresNotificationStore) execForUser(query string, action string, id int32, userID int32, extraArgs ...interface{}) error {
+	args := append([]interface{}{id, userID}, extraArgs...)
+
+	result, err := s.db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to %s notification: %w", action, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("notification with ID %d not found for user %d", id, userID)
+	}
+
+	return nil
+}
+
 // Batch Operations
 
 func (s *PostgresNotificationStore) DeleteReadNotifications(userID int32) error {
@@ -323,8 +433,13 @@ func (s *PostgresNotificationStore) DeleteOldNotifications(olderThanDays int) er
 
 // Statistics
 
+// unreadInboxCondition matches unread notifications visible in the inbox,
+// which is what the unread badge counts
+const unreadInboxCondition = `read = false AND archived_at IS NULL
+	AND (snoozed_until IS NULL OR snoozed_until <= CURRENT_TIMESTAMP)`
+
 func (s *PostgresNotificationStore) GetUnreadCount(userID int32) (int32, error) {
-	query := `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read = false`
+	query := `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND ` + unreadInboxCondition
 
 	var count int32
 	err := s.db.QueryRow(query, userID).Scan(&count)
@@ -335,6 +450,41 @@ func (s *PostgresNotificationStore) GetUnreadCount(userID int32) (int32, error)
 	return count, nil
 }
 
+// GetUnreadCounts returns the unread inbox count of several users in one query.
+// Users without unread notifications are reported with a count of 0.
+func (s *PostgresNotificationStore) GetUnreadCounts(userIDs []int32) (map[int32]int32, error) {
+	query := `
+		SELECT user_id, COUNT(*)
+		FROM notifications
+		WHERE user_id = ANY($1) AND ` + unreadInboxCondition + `
+		GROUP BY user_id
+	`
+
+	rows, err := s.db.Query(query, pq.Array(userIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unread counts: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[int32]int32, len(userIDs))
+	for _, userID := range userIDs {
+		counts[userID] = 0
+	}
+	for rows.Next() {
+		var userID, count int32
+		if err := rows.Scan(&userID, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan unread count: %w", err)
+		}
+		counts[userID] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating unread counts: %w", err)
+	}
+
+	return counts, nil
+}
+
 func (s *PostgresNotificationStore) GetNotificationStats(userID int32) (*NotificationStats, error) {
 	// Get total counts
 	totalQuery := `SELECT COUNT(*) FROM notifications WHERE user_id = $1`
@@ -360,6 +510,21 @@ func (s *PostgresNotificationStore) GetNotificationStats(userID int32) (*Notific
 		return nil, fmt.Errorf("failed to get read count: %w", err)
 	}
 
+	// Get counts by inbox, archive and snooze state
+	stateQuery := `
+		SELECT
+			COUNT(*) FILTER (WHERE archived_at IS NULL AND (snoozed_until IS NULL OR snoozed_until <= CURRENT_TIMESTAMP)),
+			COUNT(*) FILTER (WHERE archived_at IS NOT NULL),
+			COUNT(*) FILTER (WHERE snoozed_until > CURRENT_TIMESTAMP)
+		FROM notifications
+		WHERE user_id = $1
+	`
+
+	err = s.db.QueryRow(stateQuery, userID).Scan(&stats.Inbox, &stats.Archived, &stats.Snoozed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state counts: %w", err)
+	}
+
 	// Get counts by type
 	typeQuery := `
 		SELECT type, COUNT(*)
@@ -386,3 +551,57 @@ func (s *PostgresNotificationStore) GetNotificationStats(userID int32) (*Notific
 
 	return stats, nil
 }
+
+// Helper functions
+
+func scanNotification(row rowScanner) (*models.Notification, error) {
+	notification := &models.Notification{}
+	var data []byte
+
+	err := row.Scan(
+		&notification.ID,
+		&notification.Message,
+		&notification.Type,
+		&notification.UserID,
+		&notification.Read,
+		&notification.Persistent,
+		&data,
+		&notification.Language,
+		&notification.ArchivedAt,
+		&notification.SnoozedUntil,
+		&notification.Version,
+		&notification.CreatedAt,
+		&notification.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &notification.Data); err != nil {
+			return nil, fmt.Errorf("failed to decode notification data: %w", err)
+		}
+	}
+
+	return notification, nil
+}
+
+func encodeNotificationData(data map[string]interface{}) ([]byte, error) {
+	if data == nil {
+		return []byte("{}"), nil
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode notification data: %w", err)
+	}
+	return encoded, nil
+}
+
+// notificationLanguage returns the text search language, defaulting to english
+func notificationLanguage(language string) string {
+	if language == "" {
+		return models.DefaultNotificationLanguage
+	}
+	return language
+}
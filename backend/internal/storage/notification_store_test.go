@@ -2,8 +2,10 @@ package storage
 
 import (
 	"testing"
+	"time"
 
 	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/notificationtypes"
 	"backend-grpc-server/internal/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -228,3 +230,318 @@ func TestPostgresNotificationStore_GetNotificationStats(t *testing.T) {
 	assert.Equal(t, int32(1), stats.ByType["warning"])
 	assert.Equal(t, int32(1), stats.ByType["error"])
 }
+
+func TestPostgresNotificationStore_SearchNotifications(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	store := NewPostgresNotificationStore(db)
+
+	inputs := []*models.CreateNotificationParams{
+		{Message: "Your invoice payment was received", Type: "success", Persistent: true, Data: map[string]interface{}{"invoice_id": "42"}},
+		{Message: "Payment failed, please update your card", Type: "error", Persistent: true},
+		{Message: "Die Zahlungen wurden verarbeitet", Type: "info", Persistent: true, Language: "german"},
+		{Message: "<script>alert('refund')</script> refund issued", Type: "info", Persistent: true},
+	}
+	for _, params := range inputs {
+		_, err := store.CreateNotification(params)
+		require.NoError(t, err)
+	}
+
+	// Stemming matches "payment" against "payments"
+	results, total, err := store.SearchNotifications(&models.SearchNotificationsParams{Query: "payments"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), total)
+	require.Len(t, results, 2)
+	assert.Contains(t, results[0].Snippet, "<mark>")
+
+	// Type and data key filters
+	results, total, err = store.SearchNotifications(&models.SearchNotificationsParams{
+		Query:    "payment",
+		Types:    []string{"success"},
+		DataKeys: []string{"invoice_id"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), total)
+	require.Len(t, results, 1)
+	assert.Equal(t, "42", results[0].Notification.Data["invoice_id"])
+
+	// Language-aware stemming
+	results, _, err = store.SearchNotifications(&models.SearchNotificationsParams{Query: "Zahlung", Language: "german"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "german", results[0].Notification.Language)
+
+	// Snippets are escaped, only the highlights are markup
+	results, _, err = store.SearchNotifications(&models.SearchNotificationsParams{Query: "refund"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.NotContains(t, results[0].Snippet, "<script>")
+	assert.Contains(t, results[0].Snippet, "&lt;script&gt;")
+	assert.Contains(t, results[0].Snippet, "<mark>refund</mark>")
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	assert.Equal(t, "&lt;b&gt;Payment&lt;/b&gt; <mark>failed</mark> &amp; retried",
+		highlightSnippet("<b>Payment</b> \x01failed\x02 & retried"))
+}
+
+func TestPostgresNotificationStore_ArchiveAndSnooze(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	userStore := NewPostgresUserStore(db)
+	user, err := userStore.CreateUser(&models.CreateUserParams{
+		Name:  "Inbox User",
+		Email: "inbox@example.com",
+		Age:   30,
+		Role:  "user",
+	})
+	require.NoError(t, err)
+
+	store := NewPostgresNotificationStore(db)
+
+	var ids []int32
+	for _, message := range []string{"Archive me", "Snooze me", "Keep me"} {
+		notification, err := store.CreateNotification(&models.CreateNotificationParams{
+			Message:    message,
+			Type:       "info",
+			UserID:     &user.ID,
+			Persistent: true,
+		})
+		require.NoError(t, err)
+		ids = append(ids, notification.ID)
+	}
+
+	require.NoError(t, store.ArchiveNotification(ids[0], user.ID))
+	require.NoError(t, store.MarkAsRead(ids[1], user.ID))
+	require.NoError(t, store.SnoozeNotification(&models.SnoozeNotificationParams{
+		ID:     ids[1],
+		UserID: user.ID,
+		Until:  time.Now().Add(time.Hour),
+	}))
+
+	// Archiving someone else's notification fails
+	assert.Error(t, store.ArchiveNotification(ids[2], user.ID+1))
+
+	// The inbox only holds the untouched notification
+	inbox, total, err := store.ListNotifications(&models.ListNotificationsParams{
+		Limit:  10,
+		UserID: &user.ID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), total)
+	require.Len(t, inbox, 1)
+	assert.Equal(t, ids[2], inbox[0].ID)
+
+	archived, total, err := store.ListNotifications(&models.ListNotificationsParams{
+		Limit:    10,
+		UserID:   &user.ID,
+		Archived: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), total)
+	require.Len(t, archived, 1)
+	assert.NotNil(t, archived[0].ArchivedAt)
+
+	stats, err := store.GetNotificationStats(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), stats.Inbox)
+	assert.Equal(t, int32(1), stats.Archived)
+	assert.Equal(t, int32(1), stats.Snoozed)
+
+	// Nothing has expired yet
+	released, err := store.ReleaseExpiredSnoozes()
+	require.NoError(t, err)
+	assert.Empty(t, released)
+
+	// Expire the snooze and release it as unread
+	_, err = db.Exec(`UPDATE notifications SET snoozed_until = CURRENT_TIMESTAMP - INTERVAL '1 minute' WHERE id = $1`, ids[1])
+	require.NoError(t, err)
+
+	released, err = store.ReleaseExpiredSnoozes()
+	require.NoError(t, err)
+	require.Len(t, released, 1)
+	assert.Equal(t, ids[1], released[0].ID)
+	assert.False(t, released[0].Read)
+	assert.Nil(t, released[0].SnoozedUntil)
+
+	require.NoError(t, store.UnarchiveNotification(ids[0], user.ID))
+	inbox, total, err = store.ListNotifications(&models.ListNotificationsParams{
+		Limit:  10,
+		UserID: &user.ID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), total)
+	assert.Len(t, inbox, 3)
+}
+
+func TestPostgresNotificationStore_NotificationTypes(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	store := NewPostgresNotificationStore(db)
+
+	surveyReminder := notificationtypes.Type{
+		Name:          "survey_reminder",
+		Severity:      notificationtypes.SeverityInfo,
+		Icon:          "poll",
+		RetentionDays: 30,
+		Channels:      []string{notificationtypes.ChannelInApp},
+	}
+	require.NoError(t, store.SaveNotificationType(surveyReminder))
+
+	// Builtin types cannot be overwritten
+	assert.Error(t, store.SaveNotificationType(notificationtypes.Type{
+		Name:     "info",
+		Severity: notificationtypes.SeverityError,
+		Channels: []string{notificationtypes.ChannelInApp},
+	}))
+
+	types, err := store.ListNotificationTypes()
+	require.NoError(t, err)
+
+	byName := make(map[string]notificationtypes.Type)
+	for _, notificationType := range types {
+		byName[notificationType.Name] = notificationType
+	}
+	assert.Equal(t, notificationtypes.SeverityInfo, byName["info"].Severity)
+	assert.True(t, byName["info"].Builtin)
+	assert.Equal(t, surveyReminder.Channels, byName["survey_reminder"].Channels)
+	assert.Equal(t, int32(30), byName["survey_reminder"].RetentionDays)
+
+	// Purge only removes old notifications of the given type
+	for _, notificationType := range []string{"info", "warning"} {
+		_, err := store.CreateNotification(&models.CreateNotificationParams{
+			Message:    "Old " + notificationType,
+			Type:       notificationType,
+			Persistent: true,
+		})
+		require.NoError(t, err)
+	}
+	_, err = db.Exec(`UPDATE notifications SET created_at = CURRENT_TIMESTAMP - INTERVAL '40 days'`)
+	require.NoError(t, err)
+
+	deleted, err := store.PurgeNotificationsBefore("info", time.Now().AddDate(0, 0, -30))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	_, total, err := store.ListNotifications(&models.ListNotificationsParams{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), total)
+}
+
+func TestPostgresNotificationStore_GetUnreadCounts(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	userStore := NewPostgresUserStore(db)
+	var userIDs []int32
+	for _, email := range []string{"badge1@example.com", "badge2@example.com"} {
+		user, err := userStore.CreateUser(&models.CreateUserParams{
+			Name:  "Badge User",
+			Email: email,
+			Age:   30,
+			Role:  "user",
+		})
+		require.NoError(t, err)
+		userIDs = append(userIDs, user.ID)
+	}
+
+	store := NewPostgresNotificationStore(db)
+
+	var ids []int32
+	for i := 0; i < 3; i++ {
+		notification, err := store.CreateNotification(&models.CreateNotificationParams{
+			Message:    "Badge notification",
+			Type:       "info",
+			UserID:     &userIDs[0],
+			Persistent: true,
+		})
+		require.NoError(t, err)
+		ids = append(ids, notification.ID)
+	}
+
+	// Read and archived notifications are not counted
+	require.NoError(t, store.MarkAsRead(ids[0], userIDs[0]))
+	require.NoError(t, store.ArchiveNotification(ids[1], userIDs[0]))
+
+	counts, err := store.GetUnreadCounts(userIDs)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), counts[userIDs[0]])
+	assert.Equal(t, int32(0), counts[userIDs[1]])
+
+	count, err := store.GetUnreadCount(userIDs[0])
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), count)
+}
+
+func TestPostgresNotificationStore_UpdateNotification_Partial(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	store := NewPostgresNotificationStore(db)
+
+	created, err := store.CreateNotification(&models.CreateNotificationParams{
+		Message:    "Original message",
+		Type:       "warning",
+		Persistent: true,
+	})
+	require.NoError(t, err)
+
+	updated, err := store.UpdateNotification(&models.UpdateNotificationParams{
+		ID:     created.ID,
+		Read:   true,
+		Fields: []string{"read"},
+	})
+	require.NoError(t, err)
+	assert.True(t, updated.Read)
+	assert.Equal(t, "Original message", updated.Message)
+	assert.Equal(t, "warning", updated.Type)
+}
+
+func TestPostgresNotificationStore_VersionConflict(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	store := NewPostgresNotificationStore(db)
+
+	created, err := store.CreateNotification(&models.CreateNotificationParams{
+		Message:    "Versioned",
+		Type:       "info",
+		Persistent: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), created.Version)
+
+	updated, err := store.UpdateNotification(&models.UpdateNotificationParams{
+		ID:              created.ID,
+		Read:            true,
+		Fields:          []string{"read"},
+		ExpectedVersion: created.Version,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), updated.Version)
+
+	// A stale version is rejected and reports the current one
+	_, err = store.UpdateNotification(&models.UpdateNotificationParams{
+		ID:              created.ID,
+		Message:         "Stale write",
+		Fields:          []string{"message"},
+		ExpectedVersion: created.Version,
+	})
+	require.ErrorIs(t, err, ErrVersionConflict)
+	var conflict *VersionConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, int32(2), conflict.CurrentVersion)
+
+	err = store.DeleteNotification(created.ID, created.Version)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+
+	require.NoError(t, store.DeleteNotification(created.ID, updated.Version))
+
+	// A missing row is not a conflict
+	err = store.DeleteNotification(created.ID, updated.Version)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrVersionConflict)
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"backend-grpc-server/internal/notificationtypes"
+	"github.com/lib/pq"
+)
+
+// Notification Type Operations
+
+func (s *PostgresNotificationStore) ListNotificationTypes() ([]notificationtypes.Type, error) {
+	query := `
+		SELECT name, severity, icon, retention_days, channels, builtin
+		FROM notification_types
+		ORDER BY name
+	`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notification types: %w", err)
+	}
+	defer rows.Close()
+
+	var types []notificationtypes.Type
+	for rows.Next() {
+		var t notificationtypes.Type
+		var channels pq.StringArray
+		if err := rows.Scan(&t.Name, &t.Severity, &t.Icon, &t.RetentionDays, &channels, &t.Builtin); err != nil {
+			return nil, fmt.Errorf("failed to scan notification type: %w", err)
+		}
+		t.Channels = []string(channels)
+		types = append(types, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notification types: %w", err)
+	}
+
+	return types, nil
+}
+
+// SaveNotificationType creates or updates a custom notification type. Builtin
+// types are left untouched.
+func (s *PostgresNotificationStore) SaveNotificationType(t notificationtypes.Type) error {
+	query := `
+		INSERT INTO notification_types (name, severity, icon, retention_days, channels)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (name) DO UPDATE
+		SET severity = EXCLUDED.severity,
+			icon = EXCLUDED.icon,
+			retention_days = EXCLUDED.retention_days,
+			channels = EXCLUDED.channels
+		WHERE notification_types.builtin = FALSE
+	`
+
+	result, err := s.db.Exec(query, t.Name, t.Severity, t.Icon, t.RetentionDays, pq.Array(t.Channels))
+	if err != nil {
+		return fmt.Errorf("failed to save notification type: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("builtin notification type %q cannot be changed", t.Name)
+	}
+
+	return nil
+}
+
+// PurgeNotificationsBefore deletes notifications of a type created before the cutoff
+func (s *PostgresNotificationStore) PurgeNotificationsBefore(notificationType string, cutoff time.Time) (int64, error) {
+	query := `DELETE FROM notifications WHERE type = $1 AND created_at < $2`
+
+	result, err := s.db.Exec(query, notificationType, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge notifications: %w", err)
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"backend-grpc-server/internal/database"
+	"backend-grpc-server/internal/models"
+)
+
+type PostgresPrivacyStore struct {
+	db *database.DB
+}
+
+func NewPostgresPrivacyStore(db *database.DB) PrivacyStore {
+	return &PostgresPrivacyStore{
+		db: db,
+	}
+}
+
+// WithContext returns the store bound to the audit context of ctx
+func (s *PostgresPrivacyStore) WithContext(ctx context.Context) PrivacyStore {
+	return &PostgresPrivacyStore{db: s.db.WithContext(ctx)}
+}
+
+// Erasure Certificates
+
+// SaveErasureCertificate stores a certificate and sets its ID
+func (s *PostgresPrivacyStore) SaveErasureCertificate(certificate *models.ErasureCertificate) error {
+	sources, err := json.Marshal(certificate.Sources)
+	if err != nil {
+		return fmt.Errorf("failed to encode certificate sources: %w", err)
+	}
+
+	query := `
+		INSERT INTO erasure_certificates (user_id, mode, requested_by, reason, sources, digest, erased_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+
+	err = s.db.Transaction(func(tx *sql.Tx) error {
+		return tx.QueryRow(query,
+			certificate.UserID,
+			certificate.Mode,
+			certificate.RequestedBy,
+			certificate.Reason,
+			sources,
+			certificate.Digest,
+			certificate.ErasedAt,
+		).Scan(&certificate.ID)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save erasure certificate: %w", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresPrivacyStore) GetErasureCertificate(id int32) (*models.ErasureCertificate, bool) {
+	query := `
+		SELECT ` + certificateColumns + `
+		FROM erasure_certificates
+		WHERE id = $1
+	`
+
+	certificate, err := scanCertificate(s.db.QueryRow(query, id))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			fmt.Printf("Error getting erasure certificate: %v\n", err)
+		}
+		return nil, false
+	}
+
+	return certificate, true
+}
+
+// ListErasureCertificates returns the certificates for a user, or all of them
+// if userID is 0, newest first
+func (s *PostgresPrivacyStore) ListErasureCertificates(userID int32) ([]*models.ErasureCertificate, error) {
+	query := `
+		SELECT ` + certificateColumns + `
+		FROM erasure_certificates
+		WHERE $1 = 0 OR user_id = $1
+		ORDER BY erased_at DESC, id DESC
+	`
+
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list erasure certificates: %w", err)
+	}
+	defer rows.Close()
+
+	var certificates []*models.ErasureCertificate
+	for rows.Next() {
+		certificate, err := scanCertificate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan erasure certificate: %w", err)
+		}
+		certificates = append(certificates, certificate)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating erasure certificates: %w", err)
+	}
+
+	return certificates, nil
+}
+
+// Personal Data Operations
+
+// EraseUser anonymizes and soft deletes a user, or deletes it right away
+// without waiting for the purge grace period
+func (s *PostgresUserStore) EraseUser(id int32, mode string) (int64, error) {
+	var query string
+	switch mode {
+	case models.ErasureAnonymize:
+		query = `
+			UPDATE users
+			SET name = 'Erased User',
+				email = 'erased-' || id || '@erased.invalid',
+				age = 0,
+				deleted_at = COALESCE(deleted_at, CURRENT_TIMESTAMP),
+				updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1
+		`
+	case models.ErasureDelete:
+		query = `DELETE FROM users WHERE id = $1`
+	default:
+		return 0, fmt.Errorf("unknown erasure mode %q", mode)
+	}
+
+	result, err := s.db.Exec(query, id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to erase user: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// ExportUserNotifications returns every notification addressed to the user,
+// including archived and snoozed ones
+func (s *PostgresNotificationStore) ExportUserNotifications(userID int32) ([]*models.Notification, error) {
+	query := `
+		SELECT ` + notificationColumns + `
+		FROM notifications
+		WHERE user_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var notifications []*models.Notification
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, notification)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notifications: %w", err)
+	}
+
+	return notifications, nil
+}
+
+// EraseUserNotifications scrubs the message and data of the user's
+// notifications, or deletes them
+func (s *PostgresNotificationStore) EraseUserNotifications(userID int32, mode string) (int64, error) {
+	var query string
+	switch mode {
+	case models.ErasureAnonymize:
+		query = `
+			UPDATE notifications
+			SET message = '[erased]', data = '{}'::jsonb, updated_at = CURRENT_TIMESTAMP
+			WHERE user_id = $1
+		`
+	case models.ErasureDelete:
+		query = `DELETE FROM notifications WHERE user_id = $1`
+	default:
+		return 0, fmt.Errorf("unknown erasure mode %q", mode)
+	}
+
+	result, err := s.db.Exec(query, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to erase notifications: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// certificateColumns lists the columns read by scanCertificate, in order
+const certificateColumns = `id, user_id, mode, requested_by, reason, sources, digest, erased_at`
+
+func scanCertificate(row rowScanner) (*models.ErasureCertificate, error) {
+	certificate := &models.ErasureCertificate{}
+	var sources []byte
+
+	err := row.Scan(
+		&certificate.ID,
+		&certificate.UserID,
+		&certificate.Mode,
+		&certificate.RequestedBy,
+		&certificate.Reason,
+		&sources,
+		&certificate.Digest,
+		&certificate.ErasedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(sources, &certificate.Sources); err != nil {
+		return nil, fmt.Errorf("failed to decode certificate sources: %w", err)
+	}
+
+	return certificate, nil
+}
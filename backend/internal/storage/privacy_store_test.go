@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresPrivacyStore_EraseAndCertify(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	userStore := NewPostgresUserStore(db)
+	notificationStore := NewPostgresNotificationStore(db)
+	privacyStore := NewPostgresPrivacyStore(db)
+
+	user, err := userStore.CreateUser(&models.CreateUserParams{
+		Name:  "Erase Me",
+		Email: "eraseme@example.com",
+		Age:   40,
+		Role:  "user",
+	})
+	require.NoError(t, err)
+
+	_, err = notificationStore.CreateNotification(&models.CreateNotificationParams{
+		Message:    "Hi Erase Me, your invoice is ready",
+		Type:       "info",
+		UserID:     &user.ID,
+		Persistent: true,
+		Data:       map[string]interface{}{"iban": "DE00"},
+	})
+	require.NoError(t, err)
+
+	exported, err := notificationStore.ExportUserNotifications(user.ID)
+	require.NoError(t, err)
+	require.Len(t, exported, 1)
+
+	// Anonymizing keeps the records but scrubs them
+	count, err := notificationStore.EraseUserNotifications(user.ID, models.ErasureAnonymize)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	count, err = userStore.EraseUser(user.ID, models.ErasureAnonymize)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	exported, err = notificationStore.ExportUserNotifications(user.ID)
+	require.NoError(t, err)
+	require.Len(t, exported, 1)
+	assert.Equal(t, "[erased]", exported[0].Message)
+	assert.Empty(t, exported[0].Data)
+
+	erased, exists := userStore.GetUserIncludingDeleted(user.ID)
+	require.True(t, exists)
+	assert.NotEqual(t, "eraseme@example.com", erased.Email)
+	assert.NotNil(t, erased.DeletedAt)
+
+	// Deleting removes the user right away
+	count, err = userStore.EraseUser(user.ID, models.ErasureDelete)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	_, exists = userStore.GetUserIncludingDeleted(user.ID)
+	assert.False(t, exists)
+
+	certificate := &models.ErasureCertificate{
+		UserID:      user.ID,
+		Mode:        models.ErasureDelete,
+		RequestedBy: "admin@example.com",
+		Sources:     map[string]int64{"profile": 1, "notifications": 1},
+		Digest:      "0000000000000000000000000000000000000000000000000000000000000000",
+		ErasedAt:    time.Now().UTC().Truncate(time.Microsecond),
+	}
+	require.NoError(t, privacyStore.SaveErasureCertificate(certificate))
+	assert.NotZero(t, certificate.ID)
+
+	stored, exists := privacyStore.GetErasureCertificate(certificate.ID)
+	require.True(t, exists)
+	assert.Equal(t, certificate.Sources, stored.Sources)
+	assert.True(t, certificate.ErasedAt.Equal(stored.ErasedAt))
+
+	certificates, err := privacyStore.ListErasureCertificates(user.ID)
+	require.NoError(t, err)
+	assert.Len(t, certificates, 1)
+}
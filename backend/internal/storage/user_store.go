@@ -1,11 +1,16 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"backend-grpc-server/internal/database"
 	"backend-grpc-server/internal/models"
+	"github.com/lib/pq"
 )
 
 type PostgresUserStore struct {
@@ -18,23 +23,38 @@ func NewPostgresUserStore(db *database.DB) UserStore {
 	}
 }
 
+// WithContext returns the store bound to the audit context of ctx
+func (s *PostgresUserStore) WithContext(ctx context.Context) UserStore {
+	return &PostgresUserStore{db: s.db.WithContext(ctx)}
+}
+
+// userColumns lists the columns read by scanUser, in order
+const userColumns = `id, name, email, email_verified, (totp_enabled_at IS NOT NULL) AS two_factor_enabled, age, role, version, deleted_at, created_at, updated_at, locked_until`
+
+// activeUserCondition excludes soft deleted users
+const activeUserCondition = `deleted_at IS NULL`
+
+// GetUser returns a user unless it is soft deleted
 func (s *PostgresUserStore) GetUser(id int32) (*models.User, bool) {
+	return s.getUser(id, false)
+}
+
+// GetUserIncludingDeleted returns a user even if it is soft deleted
+func (s *PostgresUserStore) GetUserIncludingDeleted(id int32) (*models.User, bool) {
+	return s.getUser(id, true)
+}
+
+func (s *PostgresUserStore) getUser(id int32, includeDeleted bool) (*models.User, bool) {
 	query := `
-		SELECT id, name, email, age, role, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users
 		WHERE id = $1
 	`
+	if !includeDeleted {
+		query += " AND " + activeUserCondition
+	}
 
-	user := &models.User{}
-	err := s.db.QueryRow(query, id).Scan(
-		&user.ID,
-		&user.Name,
-		&user.Email,
-		&user.Age,
-		&user.Role,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	user, err := scanUser(s.db.QueryRow(query, id))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -51,19 +71,15 @@ func (s *PostgresUserStore) CreateUser(params *models.CreateUserParams) (*models
 	query := `
 		INSERT INTO users (name, email, age, role)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id, name, email, age, role, created_at, updated_at
+		RETURNING ` + userColumns + `
 	`
 
-	user := &models.User{}
-	err := s.db.QueryRow(query, params.Name, params.Email, params.Age, params.Role).Scan(
-		&user.ID,
-		&user.Name,
-		&user.Email,
-		&user.Age,
-		&user.Role,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	var user *models.User
+	err := s.db.Transaction(func(tx *sql.Tx) error {
+		var err error
+		user, err = scanUser(tx.QueryRow(query, params.Name, params.Email, params.Age, params.Role))
+		return err
+	})
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
@@ -72,28 +88,91 @@ func (s *PostgresUserStore) CreateUser(params *models.CreateUserParams) (*models
 	return user, nil
 }
 
-func (s *PostgresUserStore) UpdateUser(params *models.UpdateUserParams) (*models.User, error) {
+// GetUserByEmail returns the active user with the given email address
+func (s *PostgresUserStore) GetUserByEmail(email string) (*models.User, bool) {
 	query := `
-		UPDATE users
-		SET name = $2, email = $3, age = $4, role = $5, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $1
-		RETURNING id, name, email, age, role, created_at, updated_at
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE email = $1 AND ` + activeUserCondition
+
+	user, err := scanUser(s.db.QueryRow(query, email))
+
+	if err != nil {
+		if err != sql.ErrNoRows {
+			fmt.Printf("Error getting user by email: %v\n", err)
+		}
+		return nil, false
+	}
+
+	return user, true
+}
+
+// UpsertUserByEmail creates a user, or updates the active user with the same
+// email address. It reports whether the user was created.
+func (s *PostgresUserStore) UpsertUserByEmail(params *models.CreateUserParams) (*models.User, bool, error) {
+	query := `
+		INSERT INTO users (name, email, age, role)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (email) WHERE ` + activeUserCondition + ` DO UPDATE
+		SET name = EXCLUDED.name,
+			age = EXCLUDED.age,
+			role = EXCLUDED.role,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING ` + userColumns + `, (xmax = 0) AS created
 	`
 
-	user := &models.User{}
-	err := s.db.QueryRow(query, params.ID, params.Name, params.Email, params.Age, params.Role).Scan(
-		&user.ID,
-		&user.Name,
-		&user.Email,
-		&user.Age,
-		&user.Role,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	var user *models.User
+	var created bool
+	err := s.db.Transaction(func(tx *sql.Tx) error {
+		var err error
+		user, err = scanUser(&appendScanner{row: tx.QueryRow(query, params.Name, params.Email, params.Age, params.Role), extra: []interface{}{&created}})
+		return err
+	})
+
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to upsert user: %w", err)
+	}
+
+	return user, created, nil
+}
+
+// UpdateUser updates the fields listed in params.Fields, or all fields if none
+// are listed. A non-zero ExpectedVersion must match the stored version.
+func (s *PostgresUserStore) UpdateUser(params *models.UpdateUserParams) (*models.User, error) {
+	setClause, args, err := buildUpdateSet(params.Fields, map[string]interface{}{
+		"name":  params.Name,
+		"email": params.Email,
+		"age":   params.Age,
+		"role":  params.Role,
+	}, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	// A new address has to be verified again. Column references in SET read
+	// the old row, so the flag survives only if the address is unchanged.
+	if updatesColumn(params.Fields, "email") {
+		args = append(args, params.Email)
+		setClause += fmt.Sprintf(", email_verified = (email_verified AND email = $%d)", len(args)+2)
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE users
+		SET %s, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND ($2 = 0 OR version = $2) AND `+activeUserCondition+`
+		RETURNING `+userColumns+`
+	`, setClause)
+
+	var user *models.User
+	err = s.db.Transaction(func(tx *sql.Tx) error {
+		var err error
+		user, err = scanUser(tx.QueryRow(query, append([]interface{}{params.ID, params.ExpectedVersion}, args...)...))
+		return err
+	})
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user with ID %d not found", params.ID)
+			return nil, versionError(s.db, "users", activeUserCondition, "user", params.ID, params.ExpectedVersion)
 		}
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
@@ -101,10 +180,15 @@ func (s *PostgresUserStore) UpdateUser(params *models.UpdateUserParams) (*models
 	return user, nil
 }
 
-func (s *PostgresUserStore) DeleteUser(id int32) error {
-	query := `DELETE FROM users WHERE id = $1`
+// DeleteUser soft deletes a user, keeping its notifications until the user is
+// purged. A non-zero expectedVersion must match the stored version.
+func (s *PostgresUserStore) DeleteUser(id int32, expectedVersion int32) error {
+	query := `
+		UPDATE users
+		SET deleted_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND ($2 = 0 OR version = $2) AND ` + activeUserCondition
 
-	result, err := s.db.Exec(query, id)
+	result, err := s.db.Exec(query, id, expectedVersion)
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
@@ -115,12 +199,66 @@ func (s *PostgresUserStore) DeleteUser(id int32) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("user with ID %d not found", id)
+		return versionError(s.db, "users", activeUserCondition, "user", id, expectedVersion)
 	}
 
 	return nil
 }
 
+// RestoreUser undoes a soft delete. It fails if another user took the email
+// address in the meantime.
+func (s *PostgresUserStore) RestoreUser(id int32) (*models.User, error) {
+	query := `
+		UPDATE users
+		SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING ` + userColumns
+
+	var user *models.User
+	err := s.db.Transaction(func(tx *sql.Tx) error {
+		var err error
+		user, err = scanUser(tx.QueryRow(query, id))
+		return err
+	})
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("deleted user with ID %d not found", id)
+		}
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("email address of user %d is in use by another user", id)
+		}
+		return nil, fmt.Errorf("failed to restore user: %w", err)
+	}
+
+	return user, nil
+}
+
+// PurgeDeletedUsers hard deletes users soft deleted before the cutoff, their
+// notifications cascade. It returns the IDs of the purged users.
+func (s *PostgresUserStore) PurgeDeletedUsers(cutoff time.Time) ([]int32, error) {
+	rows, err := s.db.Query(`DELETE FROM users WHERE deleted_at < $1 RETURNING id`, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to purge deleted users: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan user ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating purged users: %w", err)
+	}
+
+	return ids, nil
+}
+
 func (s *PostgresUserStore) ListUsers(params *models.ListUsersParams) ([]*models.User, int32, error) {
 	// Default values
 	limit := params.Limit
@@ -132,23 +270,38 @@ func (s *PostgresUserStore) ListUsers(params *models.ListUsersParams) ([]*models
 		offset = 0
 	}
 
+	conditions, args := buildUserFilter(params)
+	orderBy := userOrderBy(params.SortBy, params.SortOrder)
+
 	// Get total count
-	countQuery := `SELECT COUNT(*) FROM users`
-	var total int32
-	err := s.db.QueryRow(countQuery).Scan(&total)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	total := int32(-1)
+	if !params.SkipTotal {
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM users %s", whereClause(conditions))
+		err := s.db.QueryRow(countQuery, args...).Scan(&total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to count users: %w", err)
+		}
+	}
+
+	// Page tokens continue after the cursor instead of skipping rows
+	if params.Cursor != nil {
+		condition, cursorArgs := params.Cursor.Condition(len(args))
+		conditions = append(conditions, condition)
+		args = append(args, cursorArgs...)
+		offset = 0
 	}
 
 	// Get users with pagination
-	query := `
-		SELECT id, name, email, age, role, created_at, updated_at
+	query := fmt.Sprintf(`
+		SELECT %s
 		FROM users
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
-	`
+		%s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d
+	`, userColumns, whereClause(conditions), orderBy, len(args)+1, len(args)+2)
+	args = append(args, limit, offset)
 
-	rows, err := s.db.Query(query, limit, offset)
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to list users: %w", err)
 	}
@@ -156,16 +309,7 @@ func (s *PostgresUserStore) ListUsers(params *models.ListUsersParams) ([]*models
 
 	var users []*models.User
 	for rows.Next() {
-		user := &models.User{}
-		err := rows.Scan(
-			&user.ID,
-			&user.Name,
-			&user.Email,
-			&user.Age,
-			&user.Role,
-			&user.CreatedAt,
-			&user.UpdatedAt,
-		)
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
 		}
@@ -178,3 +322,157 @@ func (s *PostgresUserStore) ListUsers(params *models.ListUsersParams) ([]*models
 
 	return users, total, nil
 }
+
+// userSortColumns is the allowlist of columns users can be sorted by
+var userSortColumns = map[string]string{
+	"id":         "id",
+	"name":       "LOWER(name)",
+	"email":      "LOWER(email)",
+	"age":        "age",
+	"role":       "role",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// userOrderBy builds the ORDER BY clause, falling back to created_at DESC.
+// The ID breaks ties so pages are stable.
+func userOrderBy(sortBy string, sortOrder string) string {
+	column, ok := userSortColumns[sortBy]
+	if !ok {
+		column = "created_at"
+	}
+
+	direction := "DESC"
+	if strings.EqualFold(sortOrder, "asc") {
+		direction = "ASC"
+	}
+
+	if column == "id" {
+		return "id " + direction
+	}
+	return fmt.Sprintf("%s %s, id %s", column, direction, direction)
+}
+
+// buildUserFilter builds the conditions for the ListUsers filters
+func buildUserFilter(params *models.ListUsersParams) ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	addCondition := func(format string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if !params.IncludeDeleted {
+		conditions = append(conditions, activeUserCondition)
+	}
+	if len(params.Roles) > 0 {
+		addCondition("role = ANY($%d)", pq.Array(params.Roles))
+	}
+	if params.Search != "" {
+		pattern := "%" + escapeLike(strings.ToLower(params.Search)) + "%"
+		if params.SearchMode == models.SearchPrefix {
+			pattern = escapeLike(strings.ToLower(params.Search)) + "%"
+		}
+		addCondition("(LOWER(name) LIKE $%[1]d OR LOWER(email) LIKE $%[1]d)", pattern)
+	}
+	if params.MinAge > 0 {
+		addCondition("age >= $%d", params.MinAge)
+	}
+	if params.MaxAge > 0 {
+		addCondition("age <= $%d", params.MaxAge)
+	}
+	if params.CreatedAfter != nil {
+		addCondition("created_at >= $%d", *params.CreatedAfter)
+	}
+	if params.CreatedBefore != nil {
+		addCondition("created_at <= $%d", *params.CreatedBefore)
+	}
+
+	return conditions, args
+}
+
+func scanUser(row rowScanner) (*models.User, error) {
+	user := &models.User{}
+	err := row.Scan(
+		&user.ID,
+		&user.Name,
+		&user.Email,
+		&user.EmailVerified,
+		&user.TwoFactorEnabled,
+		&user.Age,
+		&user.Role,
+		&user.Version,
+		&user.DeletedAt,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.LockedUntil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// appendScanner scans extra columns selected after the ones a scan function knows
+type appendScanner struct {
+	row   rowScanner
+	extra []interface{}
+}
+
+func (s *appendScanner) Scan(dest ...interface{}) error {
+	return s.row.Scan(append(dest, s.extra...)...)
+}
+
+// buildUpdateSet builds the SET assignments for the given columns. values
+// holds every updatable column, an empty column list updates all of them in
+// sorted order. Placeholders start after argOffset.
+func buildUpdateSet(columns []string, values map[string]interface{}, argOffset int) (string, []interface{}, error) {
+	if len(columns) == 0 {
+		for column := range values {
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+	}
+
+	seen := make(map[string]bool, len(columns))
+	var assignments []string
+	var args []interface{}
+	for _, column := range columns {
+		if seen[column] {
+			continue
+		}
+		seen[column] = true
+
+		value, ok := values[column]
+		if !ok {
+			return "", nil, fmt.Errorf("field %q cannot be updated", column)
+		}
+		args = append(args, value)
+		assignments = append(assignments, fmt.Sprintf("%s = $%d", column, argOffset+len(args)))
+	}
+
+	return strings.Join(assignments, ", "), args, nil
+}
+
+// updatesColumn reports whether an update mask includes column. An empty mask
+// updates every column.
+func updatesColumn(fields []string, column string) bool {
+	if len(fields) == 0 {
+		return true
+	}
+	for _, field := range fields {
+		if field == column {
+			return true
+		}
+	}
+	return false
+}
+
+// whereClause joins conditions into a WHERE clause, or nothing if there are none
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conditions, " AND ")
+}
@@ -1,9 +1,12 @@
 package storage
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"backend-grpc-server/internal/models"
+	"backend-grpc-server/internal/pagination"
 	"backend-grpc-server/internal/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -109,6 +112,39 @@ func TestPostgresUserStore_UpdateUser(t *testing.T) {
 	assert.True(t, updatedUser.UpdatedAt.After(updatedUser.CreatedAt))
 }
 
+func TestPostgresUserStore_UpdateUser_Partial(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	store := NewPostgresUserStore(db)
+
+	createdUser, err := store.CreateUser(&models.CreateUserParams{
+		Name:  "John Doe",
+		Email: "john@example.com",
+		Age:   25,
+		Role:  "user",
+	})
+	require.NoError(t, err)
+
+	// Only the masked field changes, the zero values of the others are ignored
+	updatedUser, err := store.UpdateUser(&models.UpdateUserParams{
+		ID:     createdUser.ID,
+		Name:   "Johnny",
+		Fields: []string{"name"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Johnny", updatedUser.Name)
+	assert.Equal(t, createdUser.Email, updatedUser.Email)
+	assert.Equal(t, createdUser.Age, updatedUser.Age)
+	assert.Equal(t, createdUser.Role, updatedUser.Role)
+
+	_, err = store.UpdateUser(&models.UpdateUserParams{
+		ID:     createdUser.ID,
+		Fields: []string{"password"},
+	})
+	assert.Error(t, err)
+}
+
 func TestPostgresUserStore_DeleteUser(t *testing.T) {
 	db := testutil.SetupTestDB(t)
 	defer testutil.CleanupTestDB(t, db)
@@ -127,7 +163,7 @@ func TestPostgresUserStore_DeleteUser(t *testing.T) {
 	require.NoError(t, err)
 
 	// Delete the user
-	err = store.DeleteUser(createdUser.ID)
+	err = store.DeleteUser(createdUser.ID, 0)
 	require.NoError(t, err)
 
 	// Verify user is deleted
@@ -166,3 +202,254 @@ func TestPostgresUserStore_ListUsers(t *testing.T) {
 	assert.Equal(t, int32(3), total)
 	assert.Len(t, userList, 2)
 }
+
+func TestPostgresUserStore_ListUsers_FilterAndSort(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	store := NewPostgresUserStore(db)
+
+	users := []models.CreateUserParams{
+		{Name: "Alice Smith", Email: "alice@example.com", Age: 25, Role: "user"},
+		{Name: "Bob Jones", Email: "bob@corp.example", Age: 42, Role: "admin"},
+		{Name: "Carol Alison", Email: "carol@example.com", Age: 35, Role: "moderator"},
+		{Name: "Dave_Test", Email: "dave@example.com", Age: 60, Role: "user"},
+	}
+	for _, params := range users {
+		_, err := store.CreateUser(&params)
+		require.NoError(t, err)
+	}
+
+	names := func(list []*models.User) []string {
+		var result []string
+		for _, user := range list {
+			result = append(result, user.Name)
+		}
+		return result
+	}
+
+	// Substring matches name or email, case-insensitive
+	list, total, err := store.ListUsers(&models.ListUsersParams{Search: "ALI", SortBy: "name", SortOrder: "asc"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), total)
+	assert.Equal(t, []string{"Alice Smith", "Carol Alison"}, names(list))
+
+	// Prefix only matches the start
+	list, _, err = store.ListUsers(&models.ListUsersParams{Search: "ali", SearchMode: models.SearchPrefix})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Alice Smith"}, names(list))
+
+	// LIKE wildcards are matched literally
+	list, _, err = store.ListUsers(&models.ListUsersParams{Search: "_test"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Dave_Test"}, names(list))
+
+	// Roles and age range
+	list, total, err = store.ListUsers(&models.ListUsersParams{
+		Roles:     []string{"user", "moderator"},
+		MinAge:    30,
+		SortBy:    "age",
+		SortOrder: "desc",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), total)
+	assert.Equal(t, []string{"Dave_Test", "Carol Alison"}, names(list))
+
+	// Created date range excluding everything
+	future := time.Now().Add(time.Hour)
+	_, total, err = store.ListUsers(&models.ListUsersParams{CreatedAfter: &future})
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), total)
+}
+
+func TestPostgresUserStore_ListUsers_Cursor(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	store := NewPostgresUserStore(db)
+
+	for i := 1; i <= 5; i++ {
+		_, err := store.CreateUser(&models.CreateUserParams{
+			Name:  fmt.Sprintf("Page User %d", i),
+			Email: fmt.Sprintf("page%d@example.com", i),
+			Age:   20 + int32(i),
+			Role:  "user",
+		})
+		require.NoError(t, err)
+	}
+
+	// Give every user the same timestamp so the ID has to break ties
+	_, err := db.Exec(`UPDATE users SET created_at = '2026-01-01T00:00:00Z'`)
+	require.NoError(t, err)
+
+	var seen []int32
+	var cursor *pagination.Cursor
+	for page := 0; page < 3; page++ {
+		users, total, err := store.ListUsers(&models.ListUsersParams{Limit: 2, Cursor: cursor, SkipTotal: true})
+		require.NoError(t, err)
+		assert.Equal(t, int32(-1), total)
+
+		for _, user := range users {
+			seen = append(seen, user.ID)
+		}
+		if len(users) == 0 {
+			break
+		}
+
+		last := users[len(users)-1]
+		cursor = &pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID, Descending: true}
+	}
+
+	// Every user is returned exactly once, newest ID first
+	require.Len(t, seen, 5)
+	for i := 1; i < len(seen); i++ {
+		assert.Greater(t, seen[i-1], seen[i])
+	}
+}
+
+func TestPostgresUserStore_VersionConflict(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	store := NewPostgresUserStore(db)
+
+	createdUser, err := store.CreateUser(&models.CreateUserParams{
+		Name:  "Versioned User",
+		Email: "versioned@example.com",
+		Age:   25,
+		Role:  "user",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), createdUser.Version)
+
+	updatedUser, err := store.UpdateUser(&models.UpdateUserParams{
+		ID:              createdUser.ID,
+		Age:             26,
+		Fields:          []string{"age"},
+		ExpectedVersion: createdUser.Version,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), updatedUser.Version)
+
+	_, err = store.UpdateUser(&models.UpdateUserParams{
+		ID:              createdUser.ID,
+		Age:             27,
+		Fields:          []string{"age"},
+		ExpectedVersion: createdUser.Version,
+	})
+	assert.ErrorIs(t, err, ErrVersionConflict)
+
+	err = store.DeleteUser(createdUser.ID, createdUser.Version)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+
+	require.NoError(t, store.DeleteUser(createdUser.ID, updatedUser.Version))
+}
+
+func TestPostgresUserStore_SoftDelete(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	store := NewPostgresUserStore(db)
+
+	createdUser, err := store.CreateUser(&models.CreateUserParams{
+		Name:  "Soft Delete",
+		Email: "soft@example.com",
+		Age:   25,
+		Role:  "user",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, store.DeleteUser(createdUser.ID, 0))
+
+	// Hidden by default, visible to admins
+	_, exists := store.GetUser(createdUser.ID)
+	assert.False(t, exists)
+	deletedUser, exists := store.GetUserIncludingDeleted(createdUser.ID)
+	require.True(t, exists)
+	assert.NotNil(t, deletedUser.DeletedAt)
+
+	_, total, err := store.ListUsers(&models.ListUsersParams{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), total)
+	_, total, err = store.ListUsers(&models.ListUsersParams{IncludeDeleted: true})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), total)
+
+	// Deleted users can't be updated or deleted again
+	_, err = store.UpdateUser(&models.UpdateUserParams{ID: createdUser.ID, Age: 30, Fields: []string{"age"}})
+	assert.Error(t, err)
+	assert.Error(t, store.DeleteUser(createdUser.ID, 0))
+
+	restoredUser, err := store.RestoreUser(createdUser.ID)
+	require.NoError(t, err)
+	assert.Nil(t, restoredUser.DeletedAt)
+	_, err = store.RestoreUser(createdUser.ID)
+	assert.Error(t, err)
+
+	// Only users deleted before the cutoff are purged
+	require.NoError(t, store.DeleteUser(createdUser.ID, 0))
+	ids, err := store.PurgeDeletedUsers(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+
+	ids, err = store.PurgeDeletedUsers(time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []int32{createdUser.ID}, ids)
+	_, exists = store.GetUserIncludingDeleted(createdUser.ID)
+	assert.False(t, exists)
+}
+
+func TestPostgresUserStore_UpsertUserByEmail(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	store := NewPostgresUserStore(db)
+
+	params := &models.CreateUserParams{Name: "Import User", Email: "import@example.com", Age: 30, Role: "user"}
+	createdUser, created, err := store.UpsertUserByEmail(params)
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	params.Name = "Imported User"
+	params.Role = "moderator"
+	updatedUser, created, err := store.UpsertUserByEmail(params)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, createdUser.ID, updatedUser.ID)
+	assert.Equal(t, "moderator", updatedUser.Role)
+
+	found, exists := store.GetUserByEmail("import@example.com")
+	require.True(t, exists)
+	assert.Equal(t, "Imported User", found.Name)
+
+	// A soft deleted user doesn't block the address
+	require.NoError(t, store.DeleteUser(createdUser.ID, 0))
+	_, exists = store.GetUserByEmail("import@example.com")
+	assert.False(t, exists)
+	newUser, created, err := store.UpsertUserByEmail(params)
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.NotEqual(t, createdUser.ID, newUser.ID)
+}
+
+func TestPostgresUserStore_EmailCaseInsensitive(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+
+	store := NewPostgresUserStore(db)
+
+	user, err := store.CreateUser(&models.CreateUserParams{Name: "John", Email: "John@example.com", Age: 30, Role: "user"})
+	require.NoError(t, err)
+
+	_, err = store.CreateUser(&models.CreateUserParams{Name: "Other John", Email: "john@EXAMPLE.com", Age: 30, Role: "user"})
+	assert.Error(t, err)
+
+	found, exists := store.GetUserByEmail("JOHN@example.com")
+	require.True(t, exists)
+	assert.Equal(t, user.ID, found.ID)
+	assert.Equal(t, "John@example.com", found.Email)
+
+	_, created, err := store.UpsertUserByEmail(&models.CreateUserParams{Name: "Johnny", Email: "john@example.com", Age: 31, Role: "user"})
+	require.NoError(t, err)
+	assert.False(t, created)
+}
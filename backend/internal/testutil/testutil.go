@@ -2,9 +2,6 @@ package testutil
 
 import (
 	"log"
-	"os"
-	"path/filepath"
-	"runtime"
 	"testing"
 
 	"backend-grpc-server/internal/database"
@@ -12,36 +9,6 @@ import (
 	_ "github.com/lib/pq"
 )
 
-// findMigrationsDir finds the migrations directory from the current working directory
-func findMigrationsDir() string {
-	// Try different possible paths
-	paths := []string{
-		"./internal/database/migrations",         // From backend root
-		"../database/migrations",                 // From testutil directory
-		"../../database/migrations",              // From deeper nested tests
-		"./backend/internal/database/migrations", // From project root
-	}
-
-	for _, path := range paths {
-		if _, err := os.Stat(path); err == nil {
-			absPath, _ := filepath.Abs(path)
-			log.Printf("Found migrations directory at: %s", absPath)
-			return path
-		}
-	}
-
-	// If not found, try to find it relative to this file
-	_, filename, _, _ := runtime.Caller(0)
-	dir := filepath.Dir(filename)
-	migrationPath := filepath.Join(dir, "..", "database", "migrations")
-	if _, err := os.Stat(migrationPath); err == nil {
-		return migrationPath
-	}
-
-	log.Printf("Warning: Could not find migrations directory. Tried paths: %v", paths)
-	return "./internal/database/migrations" // fallback
-}
-
 // SetupTestDB creates a test database connection and runs migrations
 func SetupTestDB(t *testing.T) *database.DB {
 	// Use environment variables or default test database
@@ -50,13 +17,9 @@ func SetupTestDB(t *testing.T) *database.DB {
 		t.Fatalf("Failed to connect to test database: %v", err)
 	}
 
-	// Get the correct migrations path
-	migrationsPath := findMigrationsDir()
-	log.Printf("Using migrations path: %s", migrationsPath)
-
-	// Run migrations for test with correct path
-	if err := db.RunMigrationsFromPath(migrationsPath); err != nil {
-		t.Fatalf("Failed to run migrations from %s: %v", migrationsPath, err)
+	// The migrations are built in, so tests find them from any directory
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
 	}
 
 	return db
@@ -88,7 +51,7 @@ func SetupTestDBSimple(t *testing.T) *database.DB {
 // CleanupTestDB cleans up test database
 func CleanupTestDB(t *testing.T, db *database.DB) {
 	// Clean up tables in reverse order due to foreign keys
-	tables := []string{"notifications", "users"}
+	tables := []string{"notifications", "notification_audiences", "erasure_certificates", "invitations", "email_verifications", "refresh_tokens", "sessions", "failed_logins", "password_resets", "recovery_codes", "user_identities", "oidc_login_states", "api_keys", "impersonation_log", "impersonations", "users"}
 	for _, table := range tables {
 		_, err := db.Exec("TRUNCATE TABLE " + table + " CASCADE")
 		if err != nil {
@@ -99,6 +62,17 @@ func CleanupTestDB(t *testing.T, db *database.DB) {
 			}
 		}
 	}
+
+	// Keep the seeded builtin notification types
+	if _, err := db.Exec("DELETE FROM notification_types WHERE builtin = FALSE"); err != nil {
+		log.Printf("Failed to clean table notification_types: %v", err)
+	}
+
+	// Last, as the deletes above are audited too. Truncating skips the
+	// append-only trigger.
+	if _, err := db.Exec("TRUNCATE TABLE audit_events"); err != nil {
+		log.Printf("Failed to clean table audit_events: %v", err)
+	}
 	db.Close()
 }
 
@@ -138,8 +112,13 @@ func SetupMinimalDB(t *testing.T) *database.DB {
 			id SERIAL PRIMARY KEY,
 			name VARCHAR(255) NOT NULL,
 			email VARCHAR(255) UNIQUE NOT NULL,
+			email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+			totp_enabled_at TIMESTAMP WITH TIME ZONE,
 			age INTEGER NOT NULL CHECK (age >= 0),
 			role VARCHAR(100) NOT NULL DEFAULT 'user',
+			version INTEGER NOT NULL DEFAULT 1,
+			deleted_at TIMESTAMP WITH TIME ZONE,
+			locked_until TIMESTAMP WITH TIME ZONE,
 			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 		)`,
@@ -150,6 +129,11 @@ func SetupMinimalDB(t *testing.T) *database.DB {
 			user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
 			read BOOLEAN DEFAULT FALSE,
 			persistent BOOLEAN DEFAULT TRUE,
+			data JSONB NOT NULL DEFAULT '{}'::jsonb,
+			language VARCHAR(32) NOT NULL DEFAULT 'english',
+			archived_at TIMESTAMP WITH TIME ZONE,
+			snoozed_until TIMESTAMP WITH TIME ZONE,
+			version INTEGER NOT NULL DEFAULT 1,
 			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 		)`,
@@ -1,6 +1,7 @@
 package validation
 
 import (
+	"backend-grpc-server/internal/notificationtypes"
 	"fmt"
 	"github.com/go-playground/validator/v10"
 	"strings"
@@ -13,6 +14,7 @@ func init() {
 
 	// Custom validation functions
 	validate.RegisterValidation("username", validateUsername)
+	validate.RegisterValidation("notification_type", validateNotificationType)
 }
 
 // ValidateStruct validates any struct with validation tags
@@ -40,6 +42,21 @@ func validateUsername(fl validator.FieldLevel) bool {
 	return true
 }
 
+// ValidateStructPartial validates only the named struct fields, used for
+// partial updates where unset fields keep their stored value
+func ValidateStructPartial(s interface{}, fields ...string) error {
+	err := validate.StructPartial(s, fields...)
+	if err != nil {
+		return formatValidationError(err)
+	}
+	return nil
+}
+
+// Notification types must be registered in the notification type registry
+func validateNotificationType(fl validator.FieldLevel) bool {
+	return notificationtypes.Default.Exists(fl.Field().String())
+}
+
 // Format validation errors to be user-friendly
 func formatValidationError(err error) error {
 	var errors []string
@@ -54,6 +71,8 @@ func formatValidationError(err error) error {
 			errors = append(errors, fmt.Sprintf("%s must be at least %s characters", err.Field(), err.Param()))
 		case "max":
 			errors = append(errors, fmt.Sprintf("%s must be at most %s characters", err.Field(), err.Param()))
+		case "notification_type":
+			errors = append(errors, fmt.Sprintf("%s must be a registered notification type", err.Field()))
 		case "oneof":
 			errors = append(errors, fmt.Sprintf("%s must be one of: %s", err.Field(), err.Param()))
 		default:
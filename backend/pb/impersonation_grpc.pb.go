@@ -0,0 +1,183 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v5.29.4
+// source: impersonation.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	ImpersonationService_Impersonate_FullMethodName        = "/impersonation.ImpersonationService/Impersonate"
+	ImpersonationService_StopImpersonation_FullMethodName  = "/impersonation.ImpersonationService/StopImpersonation"
+	ImpersonationService_ListImpersonations_FullMethodName = "/impersonation.ImpersonationService/ListImpersonations"
+)
+
+// ImpersonationServiceClient is the client API for ImpersonationService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ImpersonationServiceClient interface {
+	Impersonate(ctx context.Context, in *ImpersonateRequest, opts ...grpc.CallOption) (*ImpersonateResponse, error)
+	StopImpersonation(ctx context.Context, in *StopImpersonationRequest, opts ...grpc.CallOption) (*StopImpersonationResponse, error)
+	ListImpersonations(ctx context.Context, in *ListImpersonationsRequest, opts ...grpc.CallOption) (*ListImpersonationsResponse, error)
+}
+
+type impersonationServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewImpersonationServiceClient(cc grpc.ClientConnInterface) ImpersonationServiceClient {
+	return &impersonationServiceClient{cc}
+}
+
+func (c *impersonationServiceClient) Impersonate(ctx context.Context, in *ImpersonateRequest, opts ...grpc.CallOption) (*ImpersonateResponse, error) {
+	out := new(ImpersonateResponse)
+	err := c.cc.Invoke(ctx, ImpersonationService_Impersonate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *impersonationServiceClient) StopImpersonation(ctx context.Context, in *StopImpersonationRequest, opts ...grpc.CallOption) (*StopImpersonationResponse, error) {
+	out := new(StopImpersonationResponse)
+	err := c.cc.Invoke(ctx, ImpersonationService_StopImpersonation_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *impersonationServiceClient) ListImpersonations(ctx context.Context, in *ListImpersonationsRequest, opts ...grpc.CallOption) (*ListImpersonationsResponse, error) {
+	out := new(ListImpersonationsResponse)
+	err := c.cc.Invoke(ctx, ImpersonationService_ListImpersonations_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ImpersonationServiceServer is the server API for ImpersonationService service.
+// All implementations must embed UnimplementedImpersonationServiceServer
+// for forward compatibility
+type ImpersonationServiceServer interface {
+	Impersonate(context.Context, *ImpersonateRequest) (*ImpersonateResponse, error)
+	StopImpersonation(context.Context, *StopImpersonationRequest) (*StopImpersonationResponse, error)
+	ListImpersonations(context.Context, *ListImpersonationsRequest) (*ListImpersonationsResponse, error)
+	mustEmbedUnimplementedImpersonationServiceServer()
+}
+
+// UnimplementedImpersonationServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedImpersonationServiceServer struct {
+}
+
+func (UnimplementedImpersonationServiceServer) Impersonate(context.Context, *ImpersonateRequest) (*ImpersonateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Impersonate not implemented")
+}
+func (UnimplementedImpersonationServiceServer) StopImpersonation(context.Context, *StopImpersonationRequest) (*StopImpersonationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopImpersonation not implemented")
+}
+func (UnimplementedImpersonationServiceServer) ListImpersonations(context.Context, *ListImpersonationsRequest) (*ListImpersonationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListImpersonations not implemented")
+}
+func (UnimplementedImpersonationServiceServer) mustEmbedUnimplementedImpersonationServiceServer() {}
+
+// UnsafeImpersonationServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ImpersonationServiceServer will
+// result in compilation errors.
+type UnsafeImpersonationServiceServer interface {
+	mustEmbedUnimplementedImpersonationServiceServer()
+}
+
+func RegisterImpersonationServiceServer(s grpc.ServiceRegistrar, srv ImpersonationServiceServer) {
+	s.RegisterService(&ImpersonationService_ServiceDesc, srv)
+}
+
+func _ImpersonationService_Impersonate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImpersonateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImpersonationServiceServer).Impersonate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ImpersonationService_Impersonate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImpersonationServiceServer).Impersonate(ctx, req.(*ImpersonateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ImpersonationService_StopImpersonation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StopImpersonationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImpersonationServiceServer).StopImpersonation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ImpersonationService_StopImpersonation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImpersonationServiceServer).StopImpersonation(ctx, req.(*StopImpersonationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ImpersonationService_ListImpersonations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListImpersonationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImpersonationServiceServer).ListImpersonations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ImpersonationService_ListImpersonations_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImpersonationServiceServer).ListImpersonations(ctx, req.(*ListImpersonationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ImpersonationService_ServiceDesc is the grpc.ServiceDesc for ImpersonationService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ImpersonationService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "impersonation.ImpersonationService",
+	HandlerType: (*ImpersonationServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Impersonate",
+			Handler:    _ImpersonationService_Impersonate_Handler,
+		},
+		{
+			MethodName: "StopImpersonation",
+			Handler:    _ImpersonationService_StopImpersonation_Handler,
+		},
+		{
+			MethodName: "ListImpersonations",
+			Handler:    _ImpersonationService_ListImpersonations_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "impersonation.proto",
+}
@@ -24,12 +24,26 @@ const (
 	NotificationService_UpdateNotification_FullMethodName         = "/notification.NotificationService/UpdateNotification"
 	NotificationService_DeleteNotification_FullMethodName         = "/notification.NotificationService/DeleteNotification"
 	NotificationService_ListNotifications_FullMethodName          = "/notification.NotificationService/ListNotifications"
+	NotificationService_SearchNotifications_FullMethodName        = "/notification.NotificationService/SearchNotifications"
 	NotificationService_MarkNotificationAsRead_FullMethodName     = "/notification.NotificationService/MarkNotificationAsRead"
 	NotificationService_MarkNotificationAsUnread_FullMethodName   = "/notification.NotificationService/MarkNotificationAsUnread"
 	NotificationService_MarkAllNotificationsAsRead_FullMethodName = "/notification.NotificationService/MarkAllNotificationsAsRead"
+	NotificationService_ArchiveNotification_FullMethodName        = "/notification.NotificationService/ArchiveNotification"
+	NotificationService_UnarchiveNotification_FullMethodName      = "/notification.NotificationService/UnarchiveNotification"
+	NotificationService_SnoozeNotification_FullMethodName         = "/notification.NotificationService/SnoozeNotification"
+	NotificationService_UnsnoozeNotification_FullMethodName       = "/notification.NotificationService/UnsnoozeNotification"
 	NotificationService_DeleteReadNotifications_FullMethodName    = "/notification.NotificationService/DeleteReadNotifications"
+	NotificationService_BatchMarkNotifications_FullMethodName     = "/notification.NotificationService/BatchMarkNotifications"
+	NotificationService_BatchDeleteNotifications_FullMethodName   = "/notification.NotificationService/BatchDeleteNotifications"
+	NotificationService_BatchCreateNotifications_FullMethodName   = "/notification.NotificationService/BatchCreateNotifications"
 	NotificationService_GetNotificationStats_FullMethodName       = "/notification.NotificationService/GetNotificationStats"
 	NotificationService_SendRealtimeNotification_FullMethodName   = "/notification.NotificationService/SendRealtimeNotification"
+	NotificationService_CreateAudience_FullMethodName             = "/notification.NotificationService/CreateAudience"
+	NotificationService_GetAudience_FullMethodName                = "/notification.NotificationService/GetAudience"
+	NotificationService_ListAudiences_FullMethodName              = "/notification.NotificationService/ListAudiences"
+	NotificationService_DeleteAudience_FullMethodName             = "/notification.NotificationService/DeleteAudience"
+	NotificationService_ListNotificationTypes_FullMethodName      = "/notification.NotificationService/ListNotificationTypes"
+	NotificationService_RegisterNotificationType_FullMethodName   = "/notification.NotificationService/RegisterNotificationType"
 )
 
 // NotificationServiceClient is the client API for NotificationService service.
@@ -43,16 +57,33 @@ type NotificationServiceClient interface {
 	DeleteNotification(ctx context.Context, in *DeleteNotificationRequest, opts ...grpc.CallOption) (*DeleteNotificationResponse, error)
 	// List operations
 	ListNotifications(ctx context.Context, in *ListNotificationsRequest, opts ...grpc.CallOption) (*ListNotificationsResponse, error)
+	SearchNotifications(ctx context.Context, in *SearchNotificationsRequest, opts ...grpc.CallOption) (*SearchNotificationsResponse, error)
 	// Read/Unread operations
 	MarkNotificationAsRead(ctx context.Context, in *MarkNotificationAsReadRequest, opts ...grpc.CallOption) (*MarkNotificationAsReadResponse, error)
 	MarkNotificationAsUnread(ctx context.Context, in *MarkNotificationAsUnreadRequest, opts ...grpc.CallOption) (*MarkNotificationAsUnreadResponse, error)
 	MarkAllNotificationsAsRead(ctx context.Context, in *MarkAllNotificationsAsReadRequest, opts ...grpc.CallOption) (*MarkAllNotificationsAsReadResponse, error)
+	// Archive/Snooze operations
+	ArchiveNotification(ctx context.Context, in *ArchiveNotificationRequest, opts ...grpc.CallOption) (*ArchiveNotificationResponse, error)
+	UnarchiveNotification(ctx context.Context, in *UnarchiveNotificationRequest, opts ...grpc.CallOption) (*UnarchiveNotificationResponse, error)
+	SnoozeNotification(ctx context.Context, in *SnoozeNotificationRequest, opts ...grpc.CallOption) (*SnoozeNotificationResponse, error)
+	UnsnoozeNotification(ctx context.Context, in *UnsnoozeNotificationRequest, opts ...grpc.CallOption) (*UnsnoozeNotificationResponse, error)
 	// Batch operations
 	DeleteReadNotifications(ctx context.Context, in *DeleteReadNotificationsRequest, opts ...grpc.CallOption) (*DeleteReadNotificationsResponse, error)
+	BatchMarkNotifications(ctx context.Context, in *BatchMarkNotificationsRequest, opts ...grpc.CallOption) (*BatchMarkNotificationsResponse, error)
+	BatchDeleteNotifications(ctx context.Context, in *BatchDeleteNotificationsRequest, opts ...grpc.CallOption) (*BatchDeleteNotificationsResponse, error)
+	BatchCreateNotifications(ctx context.Context, in *BatchCreateNotificationsRequest, opts ...grpc.CallOption) (*BatchCreateNotificationsResponse, error)
 	// Statistics
 	GetNotificationStats(ctx context.Context, in *GetNotificationStatsRequest, opts ...grpc.CallOption) (*GetNotificationStatsResponse, error)
 	// Real-time notifications (WebSocket only)
 	SendRealtimeNotification(ctx context.Context, in *SendRealtimeNotificationRequest, opts ...grpc.CallOption) (*SendRealtimeNotificationResponse, error)
+	// Saved audiences for targeting
+	CreateAudience(ctx context.Context, in *CreateAudienceRequest, opts ...grpc.CallOption) (*CreateAudienceResponse, error)
+	GetAudience(ctx context.Context, in *GetAudienceRequest, opts ...grpc.CallOption) (*GetAudienceResponse, error)
+	ListAudiences(ctx context.Context, in *ListAudiencesRequest, opts ...grpc.CallOption) (*ListAudiencesResponse, error)
+	DeleteAudience(ctx context.Context, in *DeleteAudienceRequest, opts ...grpc.CallOption) (*DeleteAudienceResponse, error)
+	// Notification type registry
+	ListNotificationTypes(ctx context.Context, in *ListNotificationTypesRequest, opts ...grpc.CallOption) (*ListNotificationTypesResponse, error)
+	RegisterNotificationType(ctx context.Context, in *RegisterNotificationTypeRequest, opts ...grpc.CallOption) (*RegisterNotificationTypeResponse, error)
 }
 
 type notificationServiceClient struct {
@@ -108,6 +139,15 @@ func (c *notificationServiceClient) ListNotifications(ctx context.Context, in *L
 	return out, nil
 }
 
+func (c *notificationServiceClient) SearchNotifications(ctx context.Context, in *SearchNotificationsRequest, opts ...grpc.CallOption) (*SearchNotificationsResponse, error) {
+	out := new(SearchNotificationsResponse)
+	err := c.cc.Invoke(ctx, NotificationService_SearchNotifications_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *notificationServiceClient) MarkNotificationAsRead(ctx context.Context, in *MarkNotificationAsReadRequest, opts ...grpc.CallOption) (*MarkNotificationAsReadResponse, error) {
 	out := new(MarkNotificationAsReadResponse)
 	err := c.cc.Invoke(ctx, NotificationService_MarkNotificationAsRead_FullMethodName, in, out, opts...)
@@ -135,6 +175,42 @@ func (c *notificationServiceClient) MarkAllNotificationsAsRead(ctx context.Conte
 	return out, nil
 }
 
+func (c *notificationServiceClient) ArchiveNotification(ctx context.Context, in *ArchiveNotificationRequest, opts ...grpc.CallOption) (*ArchiveNotificationResponse, error) {
+	out := new(ArchiveNotificationResponse)
+	err := c.cc.Invoke(ctx, NotificationService_ArchiveNotification_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) UnarchiveNotification(ctx context.Context, in *UnarchiveNotificationRequest, opts ...grpc.CallOption) (*UnarchiveNotificationResponse, error) {
+	out := new(UnarchiveNotificationResponse)
+	err := c.cc.Invoke(ctx, NotificationService_UnarchiveNotification_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) SnoozeNotification(ctx context.Context, in *SnoozeNotificationRequest, opts ...grpc.CallOption) (*SnoozeNotificationResponse, error) {
+	out := new(SnoozeNotificationResponse)
+	err := c.cc.Invoke(ctx, NotificationService_SnoozeNotification_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) UnsnoozeNotification(ctx context.Context, in *UnsnoozeNotificationRequest, opts ...grpc.CallOption) (*UnsnoozeNotificationResponse, error) {
+	out := new(UnsnoozeNotificationResponse)
+	err := c.cc.Invoke(ctx, NotificationService_UnsnoozeNotification_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *notificationServiceClient) DeleteReadNotifications(ctx context.Context, in *DeleteReadNotificationsRequest, opts ...grpc.CallOption) (*DeleteReadNotificationsResponse, error) {
 	out := new(DeleteReadNotificationsResponse)
 	err := c.cc.Invoke(ctx, NotificationService_DeleteReadNotifications_FullMethodName, in, out, opts...)
@@ -144,6 +220,33 @@ func (c *notificationServiceClient) DeleteReadNotifications(ctx context.Context,
 	return out, nil
 }
 
+func (c *notificationServiceClient) BatchMarkNotifications(ctx context.Context, in *BatchMarkNotificationsRequest, opts ...grpc.CallOption) (*BatchMarkNotificationsResponse, error) {
+	out := new(BatchMarkNotificationsResponse)
+	err := c.cc.Invoke(ctx, NotificationService_BatchMarkNotifications_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) BatchDeleteNotifications(ctx context.Context, in *BatchDeleteNotificationsRequest, opts ...grpc.CallOption) (*BatchDeleteNotificationsResponse, error) {
+	out := new(BatchDeleteNotificationsResponse)
+	err := c.cc.Invoke(ctx, NotificationService_BatchDeleteNotifications_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) BatchCreateNotifications(ctx context.Context, in *BatchCreateNotificationsRequest, opts ...grpc.CallOption) (*BatchCreateNotificationsResponse, error) {
+	out := new(BatchCreateNotificationsResponse)
+	err := c.cc.Invoke(ctx, NotificationService_BatchCreateNotifications_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *notificationServiceClient) GetNotificationStats(ctx context.Context, in *GetNotificationStatsRequest, opts ...grpc.CallOption) (*GetNotificationStatsResponse, error) {
 	out := new(GetNotificationStatsResponse)
 	err := c.cc.Invoke(ctx, NotificationService_GetNotificationStats_FullMethodName, in, out, opts...)
@@ -162,6 +265,60 @@ func (c *notificationServiceClient) SendRealtimeNotification(ctx context.Context
 	return out, nil
 }
 
+func (c *notificationServiceClient) CreateAudience(ctx context.Context, in *CreateAudienceRequest, opts ...grpc.CallOption) (*CreateAudienceResponse, error) {
+	out := new(CreateAudienceResponse)
+	err := c.cc.Invoke(ctx, NotificationService_CreateAudience_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) GetAudience(ctx context.Context, in *GetAudienceRequest, opts ...grpc.CallOption) (*GetAudienceResponse, error) {
+	out := new(GetAudienceResponse)
+	err := c.cc.Invoke(ctx, NotificationService_GetAudience_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) ListAudiences(ctx context.Context, in *ListAudiencesRequest, opts ...grpc.CallOption) (*ListAudiencesResponse, error) {
+	out := new(ListAudiencesResponse)
+	err := c.cc.Invoke(ctx, NotificationService_ListAudiences_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) DeleteAudience(ctx context.Context, in *DeleteAudienceRequest, opts ...grpc.CallOption) (*DeleteAudienceResponse, error) {
+	out := new(DeleteAudienceResponse)
+	err := c.cc.Invoke(ctx, NotificationService_DeleteAudience_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) ListNotificationTypes(ctx context.Context, in *ListNotificationTypesRequest, opts ...grpc.CallOption) (*ListNotificationTypesResponse, error) {
+	out := new(ListNotificationTypesResponse)
+	err := c.cc.Invoke(ctx, NotificationService_ListNotificationTypes_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) RegisterNotificationType(ctx context.Context, in *RegisterNotificationTypeRequest, opts ...grpc.CallOption) (*RegisterNotificationTypeResponse, error) {
+	out := new(RegisterNotificationTypeResponse)
+	err := c.cc.Invoke(ctx, NotificationService_RegisterNotificationType_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // NotificationServiceServer is the server API for NotificationService service.
 // All implementations must embed UnimplementedNotificationServiceServer
 // for forward compatibility
@@ -173,16 +330,33 @@ type NotificationServiceServer interface {
 	DeleteNotification(context.Context, *DeleteNotificationRequest) (*DeleteNotificationResponse, error)
 	// List operations
 	ListNotifications(context.Context, *ListNotificationsRequest) (*ListNotificationsResponse, error)
+	SearchNotifications(context.Context, *SearchNotificationsRequest) (*SearchNotificationsResponse, error)
 	// Read/Unread operations
 	MarkNotificationAsRead(context.Context, *MarkNotificationAsReadRequest) (*MarkNotificationAsReadResponse, error)
 	MarkNotificationAsUnread(context.Context, *MarkNotificationAsUnreadRequest) (*MarkNotificationAsUnreadResponse, error)
 	MarkAllNotificationsAsRead(context.Context, *MarkAllNotificationsAsReadRequest) (*MarkAllNotificationsAsReadResponse, error)
+	// Archive/Snooze operations
+	ArchiveNotification(context.Context, *ArchiveNotificationRequest) (*ArchiveNotificationResponse, error)
+	UnarchiveNotification(context.Context, *UnarchiveNotificationRequest) (*UnarchiveNotificationResponse, error)
+	SnoozeNotification(context.Context, *SnoozeNotificationRequest) (*SnoozeNotificationResponse, error)
+	UnsnoozeNotification(context.Context, *UnsnoozeNotificationRequest) (*UnsnoozeNotificationResponse, error)
 	// Batch operations
 	DeleteReadNotifications(context.Context, *DeleteReadNotificationsRequest) (*DeleteReadNotificationsResponse, error)
+	BatchMarkNotifications(context.Context, *BatchMarkNotificationsRequest) (*BatchMarkNotificationsResponse, error)
+	BatchDeleteNotifications(context.Context, *BatchDeleteNotificationsRequest) (*BatchDeleteNotificationsResponse, error)
+	BatchCreateNotifications(context.Context, *BatchCreateNotificationsRequest) (*BatchCreateNotificationsResponse, error)
 	// Statistics
 	GetNotificationStats(context.Context, *GetNotificationStatsRequest) (*GetNotificationStatsResponse, error)
 	// Real-time notifications (WebSocket only)
 	SendRealtimeNotification(context.Context, *SendRealtimeNotificationRequest) (*SendRealtimeNotificationResponse, error)
+	// Saved audiences for targeting
+	CreateAudience(context.Context, *CreateAudienceRequest) (*CreateAudienceResponse, error)
+	GetAudience(context.Context, *GetAudienceRequest) (*GetAudienceResponse, error)
+	ListAudiences(context.Context, *ListAudiencesRequest) (*ListAudiencesResponse, error)
+	DeleteAudience(context.Context, *DeleteAudienceRequest) (*DeleteAudienceResponse, error)
+	// Notification type registry
+	ListNotificationTypes(context.Context, *ListNotificationTypesRequest) (*ListNotificationTypesResponse, error)
+	RegisterNotificationType(context.Context, *RegisterNotificationTypeRequest) (*RegisterNotificationTypeResponse, error)
 	mustEmbedUnimplementedNotificationServiceServer()
 }
 
@@ -205,6 +379,9 @@ func (UnimplementedNotificationServiceServer) DeleteNotification(context.Context
 func (UnimplementedNotificationServiceServer) ListNotifications(context.Context, *ListNotificationsRequest) (*ListNotificationsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListNotifications not implemented")
 }
+func (UnimplementedNotificationServiceServer) SearchNotifications(context.Context, *SearchNotificationsRequest) (*SearchNotificationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchNotifications not implemented")
+}
 func (UnimplementedNotificationServiceServer) MarkNotificationAsRead(context.Context, *MarkNotificationAsReadRequest) (*MarkNotificationAsReadResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method MarkNotificationAsRead not implemented")
 }
@@ -214,15 +391,54 @@ func (UnimplementedNotificationServiceServer) MarkNotificationAsUnread(context.C
 func (UnimplementedNotificationServiceServer) MarkAllNotificationsAsRead(context.Context, *MarkAllNotificationsAsReadRequest) (*MarkAllNotificationsAsReadResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method MarkAllNotificationsAsRead not implemented")
 }
+func (UnimplementedNotificationServiceServer) ArchiveNotification(context.Context, *ArchiveNotificationRequest) (*ArchiveNotificationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ArchiveNotification not implemented")
+}
+func (UnimplementedNotificationServiceServer) UnarchiveNotification(context.Context, *UnarchiveNotificationRequest) (*UnarchiveNotificationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnarchiveNotification not implemented")
+}
+func (UnimplementedNotificationServiceServer) SnoozeNotification(context.Context, *SnoozeNotificationRequest) (*SnoozeNotificationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SnoozeNotification not implemented")
+}
+func (UnimplementedNotificationServiceServer) UnsnoozeNotification(context.Context, *UnsnoozeNotificationRequest) (*UnsnoozeNotificationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnsnoozeNotification not implemented")
+}
 func (UnimplementedNotificationServiceServer) DeleteReadNotifications(context.Context, *DeleteReadNotificationsRequest) (*DeleteReadNotificationsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteReadNotifications not implemented")
 }
+func (UnimplementedNotificationServiceServer) BatchMarkNotifications(context.Context, *BatchMarkNotificationsRequest) (*BatchMarkNotificationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchMarkNotifications not implemented")
+}
+func (UnimplementedNotificationServiceServer) BatchDeleteNotifications(context.Context, *BatchDeleteNotificationsRequest) (*BatchDeleteNotificationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchDeleteNotifications not implemented")
+}
+func (UnimplementedNotificationServiceServer) BatchCreateNotifications(context.Context, *BatchCreateNotificationsRequest) (*BatchCreateNotificationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchCreateNotifications not implemented")
+}
 func (UnimplementedNotificationServiceServer) GetNotificationStats(context.Context, *GetNotificationStatsRequest) (*GetNotificationStatsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetNotificationStats not implemented")
 }
 func (UnimplementedNotificationServiceServer) SendRealtimeNotification(context.Context, *SendRealtimeNotificationRequest) (*SendRealtimeNotificationResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SendRealtimeNotification not implemented")
 }
+func (UnimplementedNotificationServiceServer) CreateAudience(context.Context, *CreateAudienceRequest) (*CreateAudienceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateAudience not implemented")
+}
+func (UnimplementedNotificationServiceServer) GetAudience(context.Context, *GetAudienceRequest) (*GetAudienceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAudience not implemented")
+}
+func (UnimplementedNotificationServiceServer) ListAudiences(context.Context, *ListAudiencesRequest) (*ListAudiencesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAudiences not implemented")
+}
+func (UnimplementedNotificationServiceServer) DeleteAudience(context.Context, *DeleteAudienceRequest) (*DeleteAudienceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAudience not implemented")
+}
+func (UnimplementedNotificationServiceServer) ListNotificationTypes(context.Context, *ListNotificationTypesRequest) (*ListNotificationTypesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListNotificationTypes not implemented")
+}
+func (UnimplementedNotificationServiceServer) RegisterNotificationType(context.Context, *RegisterNotificationTypeRequest) (*RegisterNotificationTypeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterNotificationType not implemented")
+}
 func (UnimplementedNotificationServiceServer) mustEmbedUnimplementedNotificationServiceServer() {}
 
 // UnsafeNotificationServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -326,6 +542,24 @@ func _NotificationService_ListNotifications_Handler(srv interface{}, ctx context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _NotificationService_SearchNotifications_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchNotificationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).SearchNotifications(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_SearchNotifications_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).SearchNotifications(ctx, req.(*SearchNotificationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _NotificationService_MarkNotificationAsRead_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(MarkNotificationAsReadRequest)
 	if err := dec(in); err != nil {
@@ -380,6 +614,78 @@ func _NotificationService_MarkAllNotificationsAsRead_Handler(srv interface{}, ct
 	return interceptor(ctx, in, info, handler)
 }
 
+func _NotificationService_ArchiveNotification_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ArchiveNotificationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).ArchiveNotification(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_ArchiveNotification_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).ArchiveNotification(ctx, req.(*ArchiveNotificationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_UnarchiveNotification_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnarchiveNotificationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).UnarchiveNotification(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_UnarchiveNotification_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).UnarchiveNotification(ctx, req.(*UnarchiveNotificationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_SnoozeNotification_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SnoozeNotificationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).SnoozeNotification(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_SnoozeNotification_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).SnoozeNotification(ctx, req.(*SnoozeNotificationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_UnsnoozeNotification_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnsnoozeNotificationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).UnsnoozeNotification(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_UnsnoozeNotification_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).UnsnoozeNotification(ctx, req.(*UnsnoozeNotificationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _NotificationService_DeleteReadNotifications_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(DeleteReadNotificationsRequest)
 	if err := dec(in); err != nil {
@@ -398,6 +704,60 @@ func _NotificationService_DeleteReadNotifications_Handler(srv interface{}, ctx c
 	return interceptor(ctx, in, info, handler)
 }
 
+func _NotificationService_BatchMarkNotifications_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchMarkNotificationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).BatchMarkNotifications(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_BatchMarkNotifications_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).BatchMarkNotifications(ctx, req.(*BatchMarkNotificationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_BatchDeleteNotifications_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchDeleteNotificationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).BatchDeleteNotifications(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_BatchDeleteNotifications_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).BatchDeleteNotifications(ctx, req.(*BatchDeleteNotificationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_BatchCreateNotifications_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchCreateNotificationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).BatchCreateNotifications(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_BatchCreateNotifications_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).BatchCreateNotifications(ctx, req.(*BatchCreateNotificationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _NotificationService_GetNotificationStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetNotificationStatsRequest)
 	if err := dec(in); err != nil {
@@ -434,6 +794,114 @@ func _NotificationService_SendRealtimeNotification_Handler(srv interface{}, ctx
 	return interceptor(ctx, in, info, handler)
 }
 
+func _NotificationService_CreateAudience_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateAudienceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).CreateAudience(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_CreateAudience_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).CreateAudience(ctx, req.(*CreateAudienceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_GetAudience_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAudienceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).GetAudience(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_GetAudience_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).GetAudience(ctx, req.(*GetAudienceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_ListAudiences_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAudiencesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).ListAudiences(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_ListAudiences_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).ListAudiences(ctx, req.(*ListAudiencesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_DeleteAudience_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAudienceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).DeleteAudience(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_DeleteAudience_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).DeleteAudience(ctx, req.(*DeleteAudienceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_ListNotificationTypes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListNotificationTypesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).ListNotificationTypes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_ListNotificationTypes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).ListNotificationTypes(ctx, req.(*ListNotificationTypesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_RegisterNotificationType_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterNotificationTypeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).RegisterNotificationType(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_RegisterNotificationType_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).RegisterNotificationType(ctx, req.(*RegisterNotificationTypeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // NotificationService_ServiceDesc is the grpc.ServiceDesc for NotificationService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -461,6 +929,10 @@ var NotificationService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListNotifications",
 			Handler:    _NotificationService_ListNotifications_Handler,
 		},
+		{
+			MethodName: "SearchNotifications",
+			Handler:    _NotificationService_SearchNotifications_Handler,
+		},
 		{
 			MethodName: "MarkNotificationAsRead",
 			Handler:    _NotificationService_MarkNotificationAsRead_Handler,
@@ -473,10 +945,38 @@ var NotificationService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "MarkAllNotificationsAsRead",
 			Handler:    _NotificationService_MarkAllNotificationsAsRead_Handler,
 		},
+		{
+			MethodName: "ArchiveNotification",
+			Handler:    _NotificationService_ArchiveNotification_Handler,
+		},
+		{
+			MethodName: "UnarchiveNotification",
+			Handler:    _NotificationService_UnarchiveNotification_Handler,
+		},
+		{
+			MethodName: "SnoozeNotification",
+			Handler:    _NotificationService_SnoozeNotification_Handler,
+		},
+		{
+			MethodName: "UnsnoozeNotification",
+			Handler:    _NotificationService_UnsnoozeNotification_Handler,
+		},
 		{
 			MethodName: "DeleteReadNotifications",
 			Handler:    _NotificationService_DeleteReadNotifications_Handler,
 		},
+		{
+			MethodName: "BatchMarkNotifications",
+			Handler:    _NotificationService_BatchMarkNotifications_Handler,
+		},
+		{
+			MethodName: "BatchDeleteNotifications",
+			Handler:    _NotificationService_BatchDeleteNotifications_Handler,
+		},
+		{
+			MethodName: "BatchCreateNotifications",
+			Handler:    _NotificationService_BatchCreateNotifications_Handler,
+		},
 		{
 			MethodName: "GetNotificationStats",
 			Handler:    _NotificationService_GetNotificationStats_Handler,
@@ -485,6 +985,30 @@ var NotificationService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SendRealtimeNotification",
 			Handler:    _NotificationService_SendRealtimeNotification_Handler,
 		},
+		{
+			MethodName: "CreateAudience",
+			Handler:    _NotificationService_CreateAudience_Handler,
+		},
+		{
+			MethodName: "GetAudience",
+			Handler:    _NotificationService_GetAudience_Handler,
+		},
+		{
+			MethodName: "ListAudiences",
+			Handler:    _NotificationService_ListAudiences_Handler,
+		},
+		{
+			MethodName: "DeleteAudience",
+			Handler:    _NotificationService_DeleteAudience_Handler,
+		},
+		{
+			MethodName: "ListNotificationTypes",
+			Handler:    _NotificationService_ListNotificationTypes_Handler,
+		},
+		{
+			MethodName: "RegisterNotificationType",
+			Handler:    _NotificationService_RegisterNotificationType_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "notification.proto",